@@ -1,9 +1,21 @@
 package cmd
 
 import (
+	"archive/zip"
 	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/marker"
+	"github.com/TsekNet/day1/internal/pages"
 )
 
 func TestVersionSubcommand(t *testing.T) {
@@ -30,7 +42,12 @@ func TestFlagDefaults(t *testing.T) {
 		wantDef string
 	}{
 		{"pages-dir", ""},
+		{"bundle", ""},
+		{"trusted-key", "[]"},
 		{"force", "false"},
+		{"restart", "false"},
+		{"tui", "false"},
+		{"locale", ""},
 		{"verbose", "false"},
 	}
 
@@ -68,3 +85,791 @@ func TestInvalidPagesDir(t *testing.T) {
 		t.Errorf("error = %q, want it to contain 'load pages'", err.Error())
 	}
 }
+
+func TestValidateSubcommand(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "day1.yml"), []byte("pages:\n  - missing.md\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := buildRootCmd()
+	var buf bytes.Buffer
+	root.SetOut(&buf)
+	root.SetArgs([]string{"validate", "--pages-dir", dir})
+
+	if err := root.Execute(); err == nil {
+		t.Fatal("expected error for invalid pages dir, got nil")
+	}
+	if !strings.Contains(buf.String(), "day1.yml:2:") {
+		t.Errorf("output missing file:line location: %s", buf.String())
+	}
+}
+
+func TestNeedsRerun(t *testing.T) {
+	tests := []struct {
+		name     string
+		st       marker.State
+		version  string
+		explicit bool
+		want     bool
+	}{
+		{"same version", marker.State{Version: "2"}, "2", true, false},
+		{"older version", marker.State{Version: "1"}, "2", true, true},
+		{"content hash changed", marker.State{Version: "aaaa"}, "bbbb", false, true},
+		{"legacy sentinel, hashed content", marker.State{}, "bbbb", false, false},
+		{"legacy sentinel, explicit version", marker.State{}, "1", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsRerun(tt.st, tt.version, tt.explicit); got != tt.want {
+				t.Errorf("needsRerun() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompletedSkipsLoad(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	for name, page := range map[string]string{
+		"welcome.md":    "# Welcome\n",
+		"welcome.de.md": "# Willkommen\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(page), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run := func(args ...string) string {
+		t.Helper()
+		root := buildRootCmd()
+		var buf bytes.Buffer
+		root.SetOut(&buf)
+		root.SetIn(strings.NewReader("\n\n"))
+		root.SetArgs(append([]string{"--tui", "--pages-dir", dir}, args...))
+		if err := root.Execute(); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return buf.String()
+	}
+
+	if out := run("--locale", "en"); !strings.Contains(out, "Onboarding complete.") {
+		t.Fatalf("first run:\n%s", out)
+	}
+	// Another language is the same content.
+	if out := run("--locale", "de"); out != "" {
+		t.Errorf("run in German after completing in English printed:\n%s", out)
+	}
+
+	// A completed machine doesn't load the pages, so a broken translation
+	// doesn't fail its logins.
+	if err := os.WriteFile(filepath.Join(dir, "welcome.de.md"), []byte("# {{ .Vars.missing }}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out := run("--locale", "de"); out != "" {
+		t.Errorf("run with a broken translation printed:\n%s", out)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "welcome.md"), []byte("# Welcome back\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out := run("--locale", "en"); !strings.Contains(out, "Welcome back") {
+		t.Errorf("run after the page changed:\n%s", out)
+	}
+}
+
+func TestOverlayPagesDir(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	base, company := t.TempDir(), t.TempDir()
+	for path, page := range map[string]string{
+		filepath.Join(base, "welcome.md"):    "---\norder: 1\n---\n# Base welcome\n",
+		filepath.Join(base, "tools.md"):      "---\norder: 2\n---\n# Base tools\n",
+		filepath.Join(company, "welcome.md"): "---\norder: 1\n---\n# Company welcome\n",
+		filepath.Join(company, "vpn.md"):     "---\norder: 3\n---\n# Company VPN\n",
+	} {
+		if err := os.WriteFile(path, []byte(page), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	root := buildRootCmd()
+	var buf bytes.Buffer
+	root.SetOut(&buf)
+	root.SetIn(strings.NewReader("\n\n\n\n"))
+	root.SetArgs([]string{"--tui", "--pages-dir", company + string(filepath.ListSeparator) + base})
+	if err := root.Execute(); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"Company welcome", "Base tools", "Company VPN"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Base welcome") {
+		t.Errorf("the base welcome page was not overridden:\n%s", out)
+	}
+}
+
+func TestChangedPages(t *testing.T) {
+	all := []pages.Page{
+		{Frontmatter: pages.Frontmatter{ID: "welcome"}, Markdown: "# Hi"},
+		{Frontmatter: pages.Frontmatter{ID: "vpn"}, Markdown: "# New VPN"},
+		{Frontmatter: pages.Frontmatter{ID: "policy"}, Markdown: "# Policy"},
+	}
+	completed := map[string]string{
+		"welcome": all[0].Hash(),
+		"vpn":     "outdated",
+	}
+
+	got := changedPages(all, completed)
+	if len(got) != 2 || got[0].ID() != "vpn" || got[1].ID() != "policy" {
+		t.Errorf("changedPages() = %v, want vpn and policy", got)
+	}
+	if got := changedPages(all, nil); len(got) != len(all) {
+		t.Errorf("no recorded hashes: got %d pages, want all %d", len(got), len(all))
+	}
+	unchanged := map[string]string{}
+	for _, p := range all {
+		unchanged[p.ID()] = p.Hash()
+	}
+	if got := changedPages(all, unchanged); len(got) != 0 {
+		t.Errorf("nothing changed: got %v, want no pages", got)
+	}
+}
+
+func TestHiddenPageEdit(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	other := "windows"
+	if runtime.GOOS == "windows" {
+		other = "darwin"
+	}
+	dir := t.TempDir()
+	write := func(name, page string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(page), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("welcome.md", "# Welcome\n")
+	write("other.md", "---\nplatform: "+other+"\n---\n# Other\n")
+	run := func() string {
+		t.Helper()
+		root := buildRootCmd()
+		var buf bytes.Buffer
+		root.SetOut(&buf)
+		root.SetIn(strings.NewReader("\n\n"))
+		root.SetArgs([]string{"--tui", "--pages-dir", dir})
+		if err := root.Execute(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	if out := run(); !strings.Contains(out, "Onboarding complete.") {
+		t.Fatalf("first run:\n%s", out)
+	}
+	write("other.md", "---\nplatform: "+other+"\n---\n# Other, edited\n")
+	if out := run(); out != "" {
+		t.Errorf("run after editing a page not shown here printed:\n%s", out)
+	}
+	st, _, err := marker.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version, _, _ := contentVersion(os.DirFS(dir)); st.Version != version {
+		t.Errorf("sentinel version = %q, want the new version %q", st.Version, version)
+	}
+}
+
+func TestStatusAndReset(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	page := "---\ntitle: Setup\n---\n# Setup\n\n- [ ] One {#one}\n- [ ] Two {#two}\n"
+	if err := os.WriteFile(filepath.Join(dir, "setup.md"), []byte(page), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := marker.WriteState(marker.State{Version: "3"}); err != nil {
+		t.Fatal(err)
+	}
+	a, _, err := newApp(os.DirFS(dir), dir, facts.Facts{OS: "linux"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.ToggleCheckItem("setup:two")
+	a.SetCurrentPage(0)
+	if err := a.MarkDismissed(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.MarkSnoozed(); err != nil {
+		t.Fatal(err)
+	}
+
+	status := func() statusReport {
+		t.Helper()
+		root := buildRootCmd()
+		var buf bytes.Buffer
+		root.SetOut(&buf)
+		root.SetArgs([]string{"status", "--pages-dir", dir, "--json"})
+		if err := root.Execute(); err != nil {
+			t.Fatalf("status: %v", err)
+		}
+		var r statusReport
+		if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+			t.Fatalf("status --json output: %v\n%s", err, buf.String())
+		}
+		return r
+	}
+
+	r := status()
+	if !r.Completed || r.Version != "3" || r.CompletedAt == nil {
+		t.Errorf("completion = %v %q %v, want completed version 3", r.Completed, r.Version, r.CompletedAt)
+	}
+	if r.LastPage != "setup" {
+		t.Errorf("LastPage = %q, want setup", r.LastPage)
+	}
+	if len(r.Pages) != 1 || r.Pages[0].Done != 1 || r.Pages[0].Total != 2 {
+		t.Errorf("Pages = %+v, want setup 1/2", r.Pages)
+	}
+	if len(r.Dismissals) != 2 || !r.Dismissals[0].Until.IsZero() || r.SnoozedUntil == nil {
+		t.Errorf("Dismissals = %+v, SnoozedUntil = %v; want a close, then a snooze in effect", r.Dismissals, r.SnoozedUntil)
+	}
+
+	root := buildRootCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetArgs([]string{"reset"})
+	if err := root.Execute(); err == nil {
+		t.Error("reset without a scope: expected error")
+	}
+
+	root = buildRootCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetArgs([]string{"reset", "--all"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("reset --all: %v", err)
+	}
+
+	r = status()
+	if r.Completed || r.LastPage != "" || r.Pages[0].Done != 0 || len(r.Dismissals) != 0 || r.SnoozedUntil != nil {
+		t.Errorf("after reset --all: %+v, want nothing saved", r)
+	}
+}
+
+func TestPackAndBundle(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "welcome.md"), []byte("# Welcome"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "onboarding.zip")
+
+	root := buildRootCmd()
+	var buf bytes.Buffer
+	root.SetOut(&buf)
+	root.SetArgs([]string{"pack", "--pages-dir", dir, "--out", out})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("pack: %v\n%s", err, buf.String())
+	}
+	fsys, _, cleanup, err := openPages(out)
+	if err != nil {
+		t.Fatalf("openPages: %v", err)
+	}
+	if _, err := verifyBundle(fsys, out); err != nil {
+		t.Errorf("verifyBundle: %v", err)
+	}
+	cleanup()
+
+	// Append a page to the archive so it no longer matches the manifest.
+	zr, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := filepath.Join(t.TempDir(), "tampered.zip")
+	f, err := os.Create(tampered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, zf := range zr.File {
+		if err := zw.Copy(zf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w, _ := zw.Create("phish.md")
+	w.Write([]byte("# Click here"))
+	zw.Close()
+	f.Close()
+	zr.Close()
+
+	root = buildRootCmd()
+	root.SetArgs([]string{"--bundle", tampered, "--force"})
+	err = root.Execute()
+	if err == nil || !strings.Contains(err.Error(), "phish.md is not in the manifest") {
+		t.Errorf("run with tampered bundle: error = %v, want manifest mismatch", err)
+	}
+
+	for _, name := range []string{"self.zip", "..self.zip"} {
+		root = buildRootCmd()
+		root.SetArgs([]string{"pack", "--pages-dir", dir, "--out", filepath.Join(dir, name)})
+		if err := root.Execute(); err == nil {
+			t.Errorf("pack into the pages dir as %s: expected error", name)
+		}
+	}
+}
+
+func TestSignAndTrustedKey(t *testing.T) {
+	keys := t.TempDir()
+	writeKey := func(name string) (privPath, pubPath string) {
+		pub, priv, err := ed25519.GenerateKey(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		privDER, _ := x509.MarshalPKCS8PrivateKey(priv)
+		pubDER, _ := x509.MarshalPKIXPublicKey(pub)
+		privPath = filepath.Join(keys, name+".pem")
+		pubPath = filepath.Join(keys, name+".pub")
+		os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0o600)
+		os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o644)
+		return privPath, pubPath
+	}
+	priv, _ := writeKey("signer")
+	_, otherPub := writeKey("other")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "welcome.md"), []byte("# Welcome"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := buildRootCmd()
+	var buf bytes.Buffer
+	root.SetOut(&buf)
+	root.SetArgs([]string{"sign", "--key", priv, "--pages-dir", dir})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if !strings.Contains(buf.String(), "public key: ") {
+		t.Errorf("sign output missing public key: %s", buf.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "manifest.sig")); err != nil {
+		t.Errorf("signature not written: %v", err)
+	}
+
+	root = buildRootCmd()
+	root.SetArgs([]string{"--pages-dir", dir, "--trusted-key", otherPub, "--force"})
+	err := root.Execute()
+	if err == nil || !strings.Contains(err.Error(), "not signed by a trusted key") {
+		t.Errorf("run with untrusted signer: error = %v", err)
+	}
+
+	root = buildRootCmd()
+	root.SetArgs([]string{"sign", "--key", priv})
+	if err := root.Execute(); err == nil {
+		t.Error("sign without --pages-dir or --bundle: expected error")
+	}
+}
+
+func TestUseTUI(t *testing.T) {
+	tests := []struct {
+		goos string
+		env  map[string]string
+		want bool
+	}{
+		{"linux", nil, true},
+		{"linux", map[string]string{"DISPLAY": ":0"}, false},
+		{"linux", map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, false},
+		{"darwin", nil, false},
+		{"windows", nil, false},
+	}
+	for _, tt := range tests {
+		getenv := func(k string) string { return tt.env[k] }
+		if got := useTUI(tt.goos, getenv); got != tt.want {
+			t.Errorf("useTUI(%q, %v) = %v, want %v", tt.goos, tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestResetChecklistWhen(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("DAY1_TEST_TEAM", "eng")
+
+	dir := t.TempDir()
+	for name, page := range map[string]string{
+		"sre.md": "---\nwhen:\n  env: {DAY1_TEST_TEAM: sre}\n---\n# SRE\n\n- [ ] Pager {#pager}\n",
+		"all.md": "# All\n\n- [ ] Badge {#badge}\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(page), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	state, err := marker.Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(state, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	saved := `{"sre:pager":true,"all:badge":true,"other:item":true}`
+	if err := os.WriteFile(filepath.Join(state, "checklist.json"), []byte(saved), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := buildRootCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetArgs([]string{"reset", "--checklist", "--pages-dir", dir})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(state, "checklist.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]bool
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	// The sre page isn't shown on this machine, so its items are kept.
+	if len(got) != 2 || !got["sre:pager"] || !got["other:item"] {
+		t.Errorf("checklist after reset = %v, want sre:pager and other:item", got)
+	}
+}
+
+func TestStatusWithoutContent(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "setup.md"), []byte("# Setup\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	a, _, err := newApp(os.DirFS(dir), dir, facts.Facts{OS: "linux"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetCurrentPage(0)
+	if err := a.MarkDismissed(); err != nil {
+		t.Fatal(err)
+	}
+	// A broken page keeps the content from loading.
+	if err := os.WriteFile(filepath.Join(dir, "setup.md"), []byte("---\ntitle: [\n---\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, args := range [][]string{{"--json"}, nil} {
+		root := buildRootCmd()
+		var buf bytes.Buffer
+		root.SetOut(&buf)
+		root.SetArgs(append([]string{"status", "--pages-dir", dir}, args...))
+		if err := root.Execute(); err != nil {
+			t.Fatalf("status %v: %v", args, err)
+		}
+		if args == nil {
+			if out := buf.String(); !strings.Contains(out, "last page:  setup (1 pages visited)") || !strings.Contains(out, "content:    not loaded:") {
+				t.Errorf("status output:\n%s", out)
+			}
+			continue
+		}
+		var r statusReport
+		if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+			t.Fatalf("status --json output: %v\n%s", err, buf.String())
+		}
+		if r.ContentError == "" || r.LastPage != "setup" || len(r.Dismissals) != 1 || r.Pages == nil {
+			t.Errorf("status = %+v, want the saved state and a content error", r)
+		}
+	}
+}
+
+func TestStatusLeavesState(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	state, err := marker.Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(state, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := `{"0:0":true}`
+	checklist := filepath.Join(state, "checklist.json")
+	if err := os.WriteFile(checklist, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "setup.md"), []byte("# Setup\n\n- [ ] One\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	root := buildRootCmd()
+	root.SetOut(&bytes.Buffer{})
+	root.SetArgs([]string{"status", "--pages-dir", dir})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if data, err := os.ReadFile(checklist); err != nil || string(data) != legacy {
+		t.Errorf("checklist.json = %s (%v), want it untouched", data, err)
+	}
+	if entries, _ := os.ReadDir(state); len(entries) != 1 {
+		t.Errorf("config dir has %d files, want only checklist.json", len(entries))
+	}
+}
+
+func TestSnoozeSkipsRun(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	page := "---\ntitle: Setup\n---\n# Setup\n"
+	if err := os.WriteFile(filepath.Join(dir, "setup.md"), []byte(page), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run := func(input string, args ...string) string {
+		t.Helper()
+		root := buildRootCmd()
+		var buf bytes.Buffer
+		root.SetOut(&buf)
+		root.SetIn(strings.NewReader(input))
+		root.SetArgs(append([]string{"--tui", "--pages-dir", dir}, args...))
+		if err := root.Execute(); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return buf.String()
+	}
+
+	if out := run("s\n"); !strings.Contains(out, "[s] Remind me later") || !strings.Contains(out, "Snoozed until") {
+		t.Errorf("snoozing:\n%s", out)
+	}
+	if out := run("\n\n"); out != "" {
+		t.Errorf("run while snoozed printed:\n%s", out)
+	}
+	if out := run("\n\n", "--force"); !strings.Contains(out, "Onboarding complete.") {
+		t.Errorf("--force while snoozed:\n%s", out)
+	}
+}
+
+func TestTUIFlag(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	page := "---\ntitle: Setup\n---\n# Setup\n\n- [ ] One {#one}\n"
+	if err := os.WriteFile(filepath.Join(dir, "setup.md"), []byte(page), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := buildRootCmd()
+	var buf bytes.Buffer
+	root.SetOut(&buf)
+	root.SetIn(strings.NewReader("1\n\n\n"))
+	root.SetArgs([]string{"--tui", "--pages-dir", dir})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("--tui: %v", err)
+	}
+	if !strings.Contains(buf.String(), "[x] 1. One") {
+		t.Errorf("checklist item not toggled:\n%s", buf.String())
+	}
+	if _, done, err := marker.Read(); !done || err != nil {
+		t.Errorf("marker.Read() = %v, %v; want completed", done, err)
+	}
+}
+
+func TestLoadContentLinks(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"day1.yml": "links:\n  schemes:\n    - scheme: slack\n",
+		"a.md":     "# A\n\n[Chat](slack://open)\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c, err := loadContent(os.DirFS(dir), dir, facts.Facts{OS: "linux"})
+	if err != nil {
+		t.Fatalf("loadContent: %v", err)
+	}
+	if !c.appConfig().Links.AllowedOn("slack://open", "linux") {
+		t.Error("slack: not allowed by the loaded policy")
+	}
+
+	bad := "links:\n  schemes:\n    - scheme: javascript\n"
+	if err := os.WriteFile(filepath.Join(dir, "day1.yml"), []byte(bad), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadContent(os.DirFS(dir), dir, facts.Facts{OS: "linux"}); err == nil || !strings.Contains(err.Error(), "links") {
+		t.Errorf("loadContent with invalid links: error = %v", err)
+	}
+}
+
+func TestPreviewLeavesState(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	state, err := marker.Dir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(state, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := `{"0:0":true}`
+	checklist := filepath.Join(state, "checklist.json")
+	if err := os.WriteFile(checklist, []byte(legacy), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for name, page := range map[string]string{
+		"mac.md": "---\nplatform: darwin\n---\n# Mac\n\n- [ ] One\n",
+		"all.md": "# All\n\n- [ ] Two\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(page), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Preview loads an App for each platform picked in the toolbar.
+	for _, p := range []string{"darwin", "linux", "windows"} {
+		if _, _, err := newApp(os.DirFS(dir), dir, previewFacts(facts.Current(), p)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if data, err := os.ReadFile(checklist); err != nil || string(data) != legacy {
+		t.Errorf("checklist.json = %s (%v), want it untouched", data, err)
+	}
+	if entries, _ := os.ReadDir(state); len(entries) != 1 {
+		t.Errorf("config dir has %d files, want only checklist.json", len(entries))
+	}
+}
+
+func TestListSimulate(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"apt.md":    "---\ntitle: APT\nwhen:\n  distro: debian\n  os_version: \">= 22.04\"\n---\n",
+		"vpn.md":    "---\ntitle: VPN\nwhen: {group: engineering, env: {TEAM: sre}}\n---\n",
+		"win.md":    "---\ntitle: Windows\nplatform: windows\n---\n",
+		"start.md":  "---\ntitle: Start\norder: 1\n---\nTeam {{ .Env.DAY1_TEAM }}\n",
+		"win.de.md": "---\ntitle: Fenster\n---\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantShown  []string
+		wantHidden []string
+		wantErr    bool
+	}{
+		{
+			name:       "ubuntu engineer",
+			args:       []string{"--os", "linux", "--distro", "debian", "--os-version", "24.04", "--group", "engineering", "--env", "TEAM=sre"},
+			wantShown:  []string{"start  Start", "apt    APT", "vpn    VPN"},
+			wantHidden: []string{"win    platform is linux, want windows"},
+		},
+		{
+			name:       "old release, wrong team",
+			args:       []string{"--os", "wsl", "--distro", "debian", "--os-version", "20.04", "--env", "TEAM=it"},
+			wantShown:  []string{"start  Start", "win    Windows"},
+			wantHidden: []string{"apt    os_version is 20.04, want >= 22.04", "vpn    not in group engineering"},
+		},
+		{
+			name:      "german",
+			args:      []string{"--os", "windows", "--locale", "de-AT"},
+			wantShown: []string{"locale:     de", "win    Fenster", "start  Start (untranslated)"},
+		},
+		{name: "unknown os", args: []string{"--os", "beos"}, wantErr: true},
+		{name: "bad env", args: []string{"--env", "TEAM"}, wantErr: true},
+		{name: "bad locale", args: []string{"--locale", "german!"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := buildRootCmd()
+			var buf bytes.Buffer
+			root.SetOut(&buf)
+			root.SetArgs(append([]string{"list", "--simulate", "--pages-dir", dir}, tt.args...))
+			err := root.Execute()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			out := buf.String()
+			_, hidden, _ := strings.Cut(out, "hidden (")
+			for _, want := range tt.wantShown {
+				if !strings.Contains(out, want) || strings.Contains(hidden, want) {
+					t.Errorf("output doesn't show %q:\n%s", want, out)
+				}
+			}
+			for _, want := range tt.wantHidden {
+				if !strings.Contains(hidden, want) {
+					t.Errorf("output doesn't hide %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestI18nExtractImport(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"day1.yml":   "title: Welcome\n",
+		"welcome.md": "# Welcome\n\nRead the [policy](https://x.example.com).\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	po := filepath.Join(t.TempDir(), "fr.po")
+
+	run := func(args ...string) (string, error) {
+		root := buildRootCmd()
+		var out, errOut bytes.Buffer
+		root.SetOut(&out)
+		root.SetErr(&errOut)
+		root.SetArgs(args)
+		err := root.Execute()
+		return out.String() + errOut.String(), err
+	}
+	out, err := run("i18n", "extract", "--pages-dir", dir, "--locale", "fr_FR.UTF-8", "--out", po)
+	if err != nil {
+		t.Fatalf("extract: %v", err)
+	}
+	if !strings.Contains(out, "4 units for fr-FR: 0 translated, 0 fuzzy, 4 untranslated") {
+		t.Errorf("extract output: %s", out)
+	}
+
+	data, err := os.ReadFile(po)
+	if err != nil {
+		t.Fatal(err)
+	}
+	translated := strings.NewReplacer(
+		"msgid \"Welcome\"\nmsgstr \"\"", "msgid \"Welcome\"\nmsgstr \"Bienvenue\"",
+		"policy](https://x.example.com).\"\nmsgstr \"\"", "policy](https://x.example.com).\"\nmsgstr \"Lisez la [politique](https://x.example.com).\"",
+	).Replace(string(data))
+	if err := os.WriteFile(po, []byte(translated), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err = run("i18n", "import", "--pages-dir", dir, po); err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if !strings.Contains(out, "imported 4 of 4 units for fr-FR") {
+		t.Errorf("import output: %s", out)
+	}
+	page, err := os.ReadFile(filepath.Join(dir, "welcome.fr-FR.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "---\ntitle: Bienvenue\n---\n# Bienvenue\n\nLisez la [politique](https://x.example.com).\n"; string(page) != want {
+		t.Errorf("welcome.fr-FR.md = %q, want %q", page, want)
+	}
+
+	if _, err := run("i18n", "extract", "--pages-dir", dir, "--locale", "en"); err == nil {
+		t.Error("extract into the pages' own language: want an error")
+	}
+	if _, err := run("i18n", "import", "--pages-dir", dir, "--format", "csv", po); err == nil {
+		t.Error("import --format csv: want an error")
+	}
+}
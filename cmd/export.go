@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+
+	"github.com/TsekNet/day1/internal/export"
+	"github.com/spf13/cobra"
+)
+
+func exportCmd() *cobra.Command {
+	var (
+		opts   export.Options
+		dir    string
+		asHTML bool
+	)
+	c := &cobra.Command{
+		Use:   "export",
+		Short: "Export the wizard content as a static website",
+		Long: `export renders every page to a self-contained static site with a table
+of contents, the brand and accent color from day1.yml and the optional
+final page. Referenced images are copied and links between .md pages are
+rewritten to the exported .html files.`,
+		Example: `  day1 export --html --pages-dir ./pages --out ./site
+  day1 export --html --pages-dir ./pages --out ./site --platform all`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if !asHTML {
+				return fmt.Errorf("no export format selected (use --html)")
+			}
+			fsys, label, cleanup, err := openPages(dir)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			opts.Pages = fsys
+			if err := export.HTML(opts); err != nil {
+				return fmt.Errorf("export: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "exported %s to %s\n", label, opts.OutDir)
+			return nil
+		},
+	}
+	f := c.Flags()
+	f.BoolVar(&asHTML, "html", false, "export as a static HTML site")
+	f.StringVar(&dir, "pages-dir", "", "directory or .zip archive containing .md pages and day1.yml")
+	f.StringVar(&opts.OutDir, "out", "", "output directory")
+	f.StringVar(&opts.Platform, "platform", runtime.GOOS, `platform to export (windows, darwin, linux, wsl, or "all")`)
+	c.MarkFlagRequired("pages-dir")
+	c.MarkFlagRequired("out")
+	return c
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/translate"
+	"github.com/spf13/cobra"
+)
+
+func i18nCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "i18n",
+		Short: "Move page text to and from XLIFF or PO files for translators",
+		Long: `i18n extract writes the text of a pages directory as translation units
+in XLIFF 1.2 or gettext PO, and i18n import writes translated pages and
+day1.<locale>.yml back from the translated file.
+
+Units are headings, paragraphs, list and checklist items and table
+cells, plus the title and strings of day1.yml. Markdown structure,
+links, template fields and frontmatter are kept from the originals.
+import records what each translation was made from in
+day1.<locale>.sum; commit it with the pages, and the next extract marks
+units whose source text changed since as fuzzy.`,
+	}
+	c.AddCommand(i18nExtractCmd(), i18nImportCmd())
+	return c
+}
+
+func i18nExtractCmd() *cobra.Command {
+	var dir, locale, out, format string
+	c := &cobra.Command{
+		Use:   "extract",
+		Short: "Write the units to translate as XLIFF or PO",
+		Example: `  day1 i18n extract --pages-dir ./pages --locale de --out de.xlf
+  day1 i18n extract --pages-dir ./pages --locale pt-BR --format po > pt-BR.po`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			tag, err := parseTargetLocale(locale)
+			if err != nil {
+				return err
+			}
+			format, err := i18nFormat(format, out)
+			if err != nil {
+				return err
+			}
+			fsys, _, cleanup, err := openPages(dir)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			cat, err := translate.Extract(fsys, tag)
+			if err != nil {
+				return fmt.Errorf("extract: %w", err)
+			}
+			for _, file := range cat.Unaligned {
+				fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s no longer matches its original; its text is not included\n", file)
+			}
+
+			w := cmd.OutOrStdout()
+			if out != "" {
+				f, err := os.Create(out)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			write := translate.WriteXLIFF
+			if format == "po" {
+				write = translate.WritePO
+			}
+			if err := write(w, cat); err != nil {
+				return fmt.Errorf("write %s: %w", format, err)
+			}
+			done, fuzzy, todo := cat.Counts()
+			fmt.Fprintf(cmd.ErrOrStderr(), "%d units for %s: %d translated, %d fuzzy, %d untranslated\n",
+				len(cat.Units), tag, done, fuzzy, todo)
+			return nil
+		},
+	}
+	f := c.Flags()
+	f.StringVar(&dir, "pages-dir", "", "directory or .zip archive containing .md pages and day1.yml")
+	f.StringVar(&locale, "locale", "", "language to translate into, like de or pt-BR")
+	f.StringVar(&out, "out", "", "file to write (default: stdout)")
+	f.StringVar(&format, "format", "", "xliff or po (default: from the --out extension, else xliff)")
+	c.MarkFlagRequired("pages-dir")
+	c.MarkFlagRequired("locale")
+	return c
+}
+
+func i18nImportCmd() *cobra.Command {
+	var dir, locale, format string
+	c := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Write translated pages from an XLIFF or PO file",
+		Long: `import reads a translated XLIFF or PO file written by i18n extract and
+writes a translation of every page, included file and final page that
+has a translated unit, next to the original as <name>.<locale>.md unless
+one exists elsewhere. The title and strings go to day1.<locale>.yml.
+Units without a translation keep the original's text.`,
+		Example: `  day1 i18n import --pages-dir ./pages de.xlf
+  day1 i18n import --pages-dir ./pages --locale pt-BR translated.po`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				return fmt.Errorf("--pages-dir %s is not a directory", dir)
+			}
+			format, err := i18nFormat(format, args[0])
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			read := translate.ReadXLIFF
+			if format == "po" {
+				read = translate.ReadPO
+			}
+			cat, err := read(f)
+			if err != nil {
+				return fmt.Errorf("%s: %w", args[0], err)
+			}
+			if locale != "" {
+				cat.Locale = locale
+			}
+			if cat.Locale, err = parseTargetLocale(cat.Locale); err != nil {
+				return fmt.Errorf("%s: %w", args[0], err)
+			}
+
+			written, err := translate.Import(dir, cat)
+			if err != nil {
+				return fmt.Errorf("import: %w", err)
+			}
+			w := cmd.OutOrStdout()
+			for _, file := range written {
+				fmt.Fprintf(w, "wrote %s\n", filepath.Join(dir, file))
+			}
+			done, fuzzy, todo := cat.Counts()
+			fmt.Fprintf(w, "imported %d of %d units for %s (%d fuzzy)\n", done+fuzzy, done+fuzzy+todo, cat.Locale, fuzzy)
+			return nil
+		},
+	}
+	fl := c.Flags()
+	fl.StringVar(&dir, "pages-dir", "", "directory containing .md pages and day1.yml")
+	fl.StringVar(&locale, "locale", "", "language of the translation (default: from the file)")
+	fl.StringVar(&format, "format", "", "xliff or po (default: from the file extension)")
+	c.MarkFlagRequired("pages-dir")
+	return c
+}
+
+// parseTargetLocale canonicalizes the language translated into.
+func parseTargetLocale(s string) (string, error) {
+	tag := facts.ParseLocale(s)
+	if tag == "" {
+		return "", fmt.Errorf("invalid locale %q (want a language tag like de or pt-BR)", s)
+	}
+	return tag, nil
+}
+
+// i18nFormat returns the format flag, or the format of file's extension.
+func i18nFormat(flag, file string) (string, error) {
+	switch strings.ToLower(flag) {
+	case "xliff", "xlf":
+		return "xliff", nil
+	case "po":
+		return "po", nil
+	case "":
+	default:
+		return "", fmt.Errorf("unknown --format %q (want xliff or po)", flag)
+	}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".po", ".pot":
+		return "po", nil
+	}
+	return "xliff", nil
+}
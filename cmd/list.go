@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/pages"
+	"github.com/TsekNet/day1/internal/platform"
+	"github.com/spf13/cobra"
+)
+
+func listCmd() *cobra.Command {
+	var (
+		dir       string
+		simulate  bool
+		overrides facts.Facts
+		groups    []string
+		env       []string
+		locale    string
+	)
+	c := &cobra.Command{
+		Use:   "list",
+		Short: "List the pages this machine is shown and why others are hidden",
+		Long: `list evaluates the platform and when: conditions of the content in
+--pages-dir (default: built-in) and prints the pages that would be shown,
+in order, followed by the pages that are hidden and the reason. Pages
+without a translation for the locale are marked untranslated.
+
+The facts of this machine are used, with any of --os, --arch, --distro,
+--os-version, --hostname, --group and --env overriding them. With
+--simulate only the flags are used, so authors can check what another
+machine would see; --os then defaults to this platform.`,
+		Example: `  day1 list --pages-dir /opt/day1/pages
+  day1 list --simulate --os linux --distro ubuntu --os-version 22.04 --group engineering
+  day1 list --os-version 13.6 --env TEAM=sre
+  day1 list --locale pt-BR`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			f := facts.Facts{OS: platform.Current(), Arch: runtime.GOARCH}
+			if !simulate {
+				f = facts.Current()
+			}
+			if err := applyFactFlags(&f, cmd, overrides, groups, env); err != nil {
+				return err
+			}
+			if err := setLocale(&f, locale); err != nil {
+				return err
+			}
+
+			fsys, label, cleanup, err := openPages(dir)
+			if err != nil {
+				return err
+			}
+			defer cleanup()
+			f.Locale = pages.ContentLocale(fsys, f.Locale)
+			shown, hidden, err := pages.Select(fsys, f)
+			if err != nil {
+				return fmt.Errorf("load pages: %w", err)
+			}
+			cfg, _ := pages.LoadConfig(fsys)
+			printList(cmd.OutOrStdout(), label, f, cfg.DefaultLocale(), shown, hidden)
+			return nil
+		},
+	}
+	fl := c.Flags()
+	fl.StringVar(&dir, "pages-dir", "", "directory or .zip archive containing .md pages and day1.yml (default: built-in)")
+	fl.BoolVar(&simulate, "simulate", false, "use only the facts given by flags instead of this machine's")
+	fl.StringVar(&overrides.OS, "os", "", "platform (windows, darwin, linux, wsl)")
+	fl.StringVar(&overrides.Arch, "arch", "", "CPU architecture, e.g. amd64 or arm64")
+	fl.StringVar(&overrides.Distro, "distro", "", "Linux distribution ID, e.g. ubuntu")
+	fl.StringVar(&overrides.OSVersion, "os-version", "", "OS version, e.g. 22.04, 14.5 or 10.0.22631")
+	fl.StringVar(&overrides.Hostname, "hostname", "", "host name")
+	fl.StringArrayVar(&groups, "group", nil, "group the user is in (repeatable)")
+	fl.StringArrayVar(&env, "env", nil, "environment variable as KEY=VALUE (repeatable)")
+	fl.StringVar(&locale, "locale", "", "language, like de or pt-BR (default: from LC_ALL, LC_MESSAGES or LANG)")
+	return c
+}
+
+// applyFactFlags copies the fact flags that were set onto f.
+func applyFactFlags(f *facts.Facts, cmd *cobra.Command, o facts.Facts, groups, env []string) error {
+	fl := cmd.Flags()
+	if fl.Changed("os") {
+		if !pages.IsPlatform(o.OS) {
+			return fmt.Errorf("unknown platform %q", o.OS)
+		}
+		f.OS = o.OS
+	}
+	if fl.Changed("arch") {
+		f.Arch = o.Arch
+	}
+	if fl.Changed("distro") {
+		f.Distro, f.DistroLike = strings.ToLower(o.Distro), nil
+	}
+	if fl.Changed("os-version") {
+		f.OSVersion = o.OSVersion
+	}
+	if fl.Changed("hostname") {
+		f.Hostname = strings.ToLower(o.Hostname)
+	}
+	if fl.Changed("group") {
+		f.Groups = groups
+	}
+	if fl.Changed("env") {
+		for _, kv := range env {
+			if k, _, ok := strings.Cut(kv, "="); !ok || k == "" {
+				return fmt.Errorf("invalid --env %q (want KEY=VALUE)", kv)
+			}
+		}
+		merged := facts.Environ(env)
+		for k, v := range f.Env {
+			if _, ok := merged[k]; !ok {
+				merged[k] = v
+			}
+		}
+		f.Env = merged
+	}
+	return nil
+}
+
+func printList(w io.Writer, label string, f facts.Facts, defaultLocale string, shown []pages.Page, hidden []pages.Hidden) {
+	fmt.Fprintf(w, "content:    %s\n", label)
+	fmt.Fprintf(w, "os:         %s/%s\n", f.OS, orDash(f.Arch))
+	if f.Distro != "" {
+		fmt.Fprintf(w, "distro:     %s\n", f.Distro)
+	}
+	fmt.Fprintf(w, "os version: %s\n", orDash(f.OSVersion))
+	fmt.Fprintf(w, "hostname:   %s\n", orDash(f.Hostname))
+	fmt.Fprintf(w, "groups:     %s\n", orDash(strings.Join(f.Groups, ", ")))
+	fmt.Fprintf(w, "locale:     %s\n", f.Locale)
+
+	width := 0
+	for _, p := range shown {
+		width = max(width, len(p.ID()))
+	}
+	for _, h := range hidden {
+		width = max(width, len(h.Page.ID()))
+	}
+	fmt.Fprintf(w, "shown (%d):\n", len(shown))
+	for _, p := range shown {
+		note := ""
+		if p.Locale == "" && f.Locale != defaultLocale {
+			note = " (untranslated)"
+		}
+		fmt.Fprintf(w, "  %-*s  %s%s\n", width, p.ID(), p.Frontmatter.Title, note)
+	}
+	if len(hidden) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "hidden (%d):\n", len(hidden))
+	for _, h := range hidden {
+		fmt.Fprintf(w, "  %-*s  %s\n", width, h.Page.ID(), h.Reason)
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TsekNet/day1/internal/bundle"
+	"github.com/TsekNet/day1/internal/pages"
+	"github.com/TsekNet/day1/internal/version"
+	"github.com/spf13/cobra"
+)
+
+func packCmd() *cobra.Command {
+	var dir, out string
+	c := &cobra.Command{
+		Use:   "pack",
+		Short: "Pack a pages directory into a single-file bundle",
+		Long: `pack validates a pages directory and zips it, with every image and
+asset, into one file for distribution. The bundle carries a manifest of
+SHA-256 hashes and the day1 version that built it; run it with
+day1 --bundle, which refuses to start if any file doesn't match.`,
+		Example: `  day1 pack --pages-dir ./pages --out onboarding.zip
+  day1 --bundle onboarding.zip`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			inside, err := within(dir, out)
+			if err != nil {
+				return err
+			}
+			if inside {
+				return fmt.Errorf("write the bundle outside %s", dir)
+			}
+			w := cmd.OutOrStdout()
+			if problems := pages.Validate(dir); len(problems) > 0 {
+				for _, p := range problems {
+					fmt.Fprintln(w, p)
+				}
+				return fmt.Errorf("%d problem(s) found in %s, not packing", len(problems), dir)
+			}
+
+			f, err := os.Create(out)
+			if err != nil {
+				return err
+			}
+			m, err := bundle.Pack(os.DirFS(dir), f, version.Version)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				os.Remove(out)
+				return fmt.Errorf("pack: %w", err)
+			}
+			fmt.Fprintf(w, "packed %d files from %s into %s\n", len(m.Files), dir, out)
+			return nil
+		},
+	}
+	f := c.Flags()
+	f.StringVar(&dir, "pages-dir", "", "directory containing .md pages and day1.yml")
+	f.StringVar(&out, "out", "", "bundle file to write (.zip)")
+	c.MarkFlagRequired("pages-dir")
+	c.MarkFlagRequired("out")
+	return c
+}
+
+// within reports whether path is dir or lies under it, comparing cleaned
+// absolute paths so that a file named like ..x.zip is still inside.
+func within(dir, path string) (bool, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false, err
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return false, err
+	}
+	prefix := strings.TrimSuffix(absDir, string(filepath.Separator)) + string(filepath.Separator)
+	return absPath == absDir || strings.HasPrefix(absPath, prefix), nil
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/TsekNet/day1/internal/app"
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/pages"
+	"github.com/TsekNet/day1/internal/preview"
+	"github.com/spf13/cobra"
+)
+
+func previewCmd() *cobra.Command {
+	var (
+		dir      string
+		addr     string
+		platform string
+		locale   string
+	)
+	c := &cobra.Command{
+		Use:   "preview",
+		Short: "Serve the wizard in a browser with live reload",
+		Long: `preview serves the wizard frontend and a pages directory over plain HTTP
+on localhost. The browser reloads whenever a .md file or day1.yml changes,
+and a toolbar switches the simulated platform so platform-filtered pages
+can be checked. --locale previews a translation. Checklist state and
+completion are simulated in the browser; nothing is written to the real
+day1 config directory.`,
+		Example: `  day1 preview --pages-dir ./pages
+  day1 preview --pages-dir ./pages --platform windows --addr localhost:9000
+  day1 preview --pages-dir ./pages --locale de`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if !pages.IsPlatform(platform) {
+				return fmt.Errorf("unknown platform %q", platform)
+			}
+			assets, err := fs.Sub(frontendAssets, "frontend")
+			if err != nil {
+				return fmt.Errorf("frontend assets: %w", err)
+			}
+			base := facts.Current()
+			if err := setLocale(&base, locale); err != nil {
+				return err
+			}
+			fsys := os.DirFS(dir)
+			if _, _, err := newApp(fsys, dir, previewFacts(base, platform)); err != nil {
+				return err
+			}
+
+			srv := preview.New(assets, dir, platform, func(p string) (*app.App, error) {
+				a, _, err := newApp(fsys, dir, previewFacts(base, p))
+				return a, err
+			})
+
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("listen: %w", err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "previewing %s at http://%s/\n", dir, ln.Addr())
+
+			stop := make(chan struct{})
+			defer close(stop)
+			go srv.Watch(500*time.Millisecond, stop)
+
+			return http.Serve(ln, srv.Handler())
+		},
+	}
+	f := c.Flags()
+	f.StringVar(&dir, "pages-dir", "", "directory containing .md pages and day1.yml")
+	f.StringVar(&addr, "addr", "localhost:8741", "address to listen on")
+	f.StringVar(&platform, "platform", runtime.GOOS, "initial simulated platform (windows, darwin, linux, wsl)")
+	f.StringVar(&locale, "locale", "", "language to preview, like de or pt-BR (default: from LC_ALL, LC_MESSAGES or LANG)")
+	c.MarkFlagRequired("pages-dir")
+	return c
+}
+
+// previewFacts returns the facts f as if they were of platform p, so
+// when: conditions other than os are evaluated as on this machine.
+func previewFacts(f facts.Facts, p string) facts.Facts {
+	f.OS = p
+	return f
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TsekNet/day1/internal/app"
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/marker"
+	"github.com/TsekNet/day1/internal/pages"
+	"github.com/spf13/cobra"
+)
+
+func resetCmd() *cobra.Command {
+	var (
+		dir                              string
+		sentinel, checklist, snooze, all bool
+	)
+	c := &cobra.Command{
+		Use:   "reset",
+		Short: "Reset saved onboarding state so the wizard shows again",
+		Long: `reset removes saved state from the day1 config directory. Pick what to
+reset: --marker removes the completion sentinel so the wizard shows on the
+next launch, --checklist clears checklist state, --snooze ends a snooze
+and forgets how often the wizard was put off, and --all removes all of
+them as well as the last viewed page.
+
+With --pages-dir, --checklist only clears the items of the pages the
+wizard shows on this machine.`,
+		Example: `  day1 reset --marker
+  day1 reset --checklist --pages-dir /opt/day1/pages
+  day1 reset --snooze
+  day1 reset --all`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if !sentinel && !checklist && !snooze && !all {
+				return fmt.Errorf("nothing to reset (use --marker, --checklist, --snooze or --all)")
+			}
+			w := cmd.OutOrStdout()
+
+			if sentinel || all {
+				if err := marker.Remove(); err != nil {
+					return fmt.Errorf("remove sentinel: %w", err)
+				}
+				fmt.Fprintln(w, "removed completion sentinel")
+			}
+			if checklist || all {
+				var loaded []pages.Page
+				if dir != "" && !all {
+					fsys, label, cleanup, err := openPages(dir)
+					if err != nil {
+						return err
+					}
+					defer cleanup()
+					// The pages the wizard shows on this machine, as in run.
+					c, err := loadContent(fsys, label, facts.Current())
+					if err != nil {
+						return err
+					}
+					loaded = c.pages
+				}
+				if err := app.ResetCheckState(loaded); err != nil {
+					return fmt.Errorf("reset checklist: %w", err)
+				}
+				if loaded != nil {
+					fmt.Fprintf(w, "cleared checklist state for %d pages in %s\n", len(loaded), dir)
+				} else {
+					fmt.Fprintln(w, "cleared checklist state")
+				}
+			}
+			if snooze || all {
+				if err := app.ResetSnooze(); err != nil {
+					return fmt.Errorf("reset snooze: %w", err)
+				}
+				fmt.Fprintln(w, "cleared snooze and dismissal history")
+			}
+			if all {
+				if err := app.ResetProgress(); err != nil {
+					return fmt.Errorf("reset progress: %w", err)
+				}
+				fmt.Fprintln(w, "cleared last viewed page")
+			}
+			return nil
+		},
+	}
+	f := c.Flags()
+	f.StringVar(&dir, "pages-dir", "", "only clear checklist state of the pages in this directory")
+	f.BoolVar(&sentinel, "marker", false, "remove the completion sentinel")
+	f.BoolVar(&checklist, "checklist", false, "clear checklist state")
+	f.BoolVar(&snooze, "snooze", false, "end a snooze and clear the dismissal history")
+	f.BoolVar(&all, "all", false, "remove the sentinel, checklist state, snooze and last viewed page")
+	return c
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"crypto/ed25519"
 	"embed"
 	"fmt"
 	"io/fs"
@@ -8,11 +9,19 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/TsekNet/day1/internal/app"
+	"github.com/TsekNet/day1/internal/bundle"
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/i18n"
 	"github.com/TsekNet/day1/internal/marker"
 	"github.com/TsekNet/day1/internal/pages"
+	"github.com/TsekNet/day1/internal/tui"
+	"github.com/TsekNet/day1/internal/urischeme"
+	"github.com/TsekNet/day1/internal/version"
 	"github.com/google/deck"
 	"github.com/spf13/cobra"
 	"github.com/wailsapp/wails/v2"
@@ -28,8 +37,13 @@ var (
 
 var (
 	flagPagesDir string
+	flagBundle   string
+	flagKeys     []string
 	flagForce    bool
+	flagRestart  bool
+	flagTUI      bool
 	flagVerbose  bool
+	flagLocale   string
 )
 
 func Execute(assets, pages embed.FS) error {
@@ -69,91 +83,166 @@ inside a frameless webview. Write pages in markdown, configure everything
 in day1.yml, and every new hire sees a polished first-run experience.
 
 Runs with built-in demo pages by default. Use --pages-dir to point at
-your own content.`,
+your own content. Without a display (or with --tui) the wizard runs in
+the terminal instead.`,
 		Example: `  day1                              # built-in demo pages
   day1 --pages-dir /opt/day1/pages
-  day1 --force                      # re-show even if completed`,
+  day1 --bundle /opt/day1/onboarding.zip
+  day1 --force                      # re-show even if completed or snoozed
+  day1 --restart                    # start from the first page again
+  day1 --tui                        # run in the terminal
+  day1 --locale pt-BR               # Brazilian Portuguese, falling back to pt`,
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE:          run,
 	}
 
 	f := root.Flags()
-	f.StringVar(&flagPagesDir, "pages-dir", "", "directory containing .md pages and day1.yml (default: built-in)")
-	f.BoolVar(&flagForce, "force", false, "show even if already completed")
+	f.StringVar(&flagPagesDir, "pages-dir", "", "directories or .zip archives containing .md pages and day1.yml, separated like $PATH to overlay them (default: built-in)")
+	f.StringVar(&flagBundle, "bundle", "", "verified .zip bundle built with day1 pack")
+	f.StringSliceVar(&flagKeys, "trusted-key", nil, "ed25519 public key file; only content signed by it is shown (repeatable)")
+	f.BoolVar(&flagForce, "force", false, "show even if already completed or snoozed")
+	f.BoolVar(&flagRestart, "restart", false, "start from the first page instead of where the user left off")
+	f.BoolVar(&flagTUI, "tui", false, "run in the terminal instead of a window (default on Linux without a display)")
 	f.BoolVarP(&flagVerbose, "verbose", "v", false, "verbose logging to stderr")
+	f.StringVar(&flagLocale, "locale", "", "language to show, like de or pt-BR (default: from LC_ALL, LC_MESSAGES or LANG)")
+	root.MarkFlagsMutuallyExclusive("pages-dir", "bundle")
 
 	root.AddCommand(versionCmd())
+	root.AddCommand(validateCmd())
+	root.AddCommand(previewCmd())
+	root.AddCommand(exportCmd())
+	root.AddCommand(packCmd())
+	root.AddCommand(signCmd())
+	root.AddCommand(statusCmd())
+	root.AddCommand(resetCmd())
+	root.AddCommand(listCmd())
+	root.AddCommand(i18nCmd())
 
 	return root
 }
 
 func run(cmd *cobra.Command, _ []string) error {
+	keys, err := trustedKeys(flagKeys)
+	if err != nil {
+		return err
+	}
+
+	// The sentinel is checked first, so a completed machine only reads
+	// day1.yml and the stored pages to compare versions at each login.
+	var completed *marker.State
 	if !flagForce {
-		done, err := marker.Exists()
+		st, done, err := marker.Read()
 		if err != nil {
 			deck.Warningf("marker check: %v", err)
 		}
-		if done {
-			deck.Info("already completed, exiting (use --force to override)")
+		if done && err == nil {
+			completed = &st
+		}
+	}
+
+	path := firstNonEmpty(flagBundle, flagPagesDir)
+	fsys, label, cleanup, err := openPages(path)
+	if err != nil {
+		if completed != nil {
+			deck.Warningf("%v; already completed, exiting", err)
 			return nil
 		}
+		return err
 	}
+	defer cleanup()
 
-	pagesDir := flagPagesDir
-	var cleanup func()
+	if completed != nil {
+		version, explicit, err := contentVersion(fsys)
+		if err != nil {
+			deck.Warningf("%s: %v; already completed, exiting", label, err)
+			return nil
+		}
+		if !needsRerun(*completed, version, explicit) {
+			deck.Info("already completed, exiting (use --force to override)")
+			return nil
+		}
+	}
 
-	if pagesDir == "" {
-		dir, cleanFn, err := extractEmbeddedPages()
+	var verified *bundle.Manifest
+	if flagBundle != "" {
+		m, err := verifyBundle(fsys, path)
+		if err != nil {
+			return err
+		}
+		verified = &m
+	}
+	// Built-in pages ship inside the binary and are as trusted as it is.
+	if len(keys) > 0 && path != "" {
+		m, err := bundle.VerifySigned(fsys, keys)
 		if err != nil {
-			return fmt.Errorf("extract embedded pages: %w", err)
+			return fmt.Errorf("verify %s: %w", label, err)
 		}
-		pagesDir = dir
-		cleanup = cleanFn
-		deck.Info("using built-in demo pages")
+		deck.Infof("verified signature of %s", label)
+		verified = &m
 	}
-	if cleanup != nil {
-		defer cleanup()
+	// Verified content is shown from the bytes that were checked, not
+	// read from disk again.
+	if verified != nil {
+		if fsys, err = bundle.Snapshot(fsys, *verified); err != nil {
+			return fmt.Errorf("verify %s: %w", label, err)
+		}
 	}
 
-	cfg, err := pages.LoadConfig(pagesDir)
-	if err != nil {
-		deck.Warningf("config: %v (using defaults)", err)
+	f := facts.Current()
+	if err := setLocale(&f, flagLocale); err != nil {
+		return err
 	}
-
-	title := firstNonEmpty(cfg.Title, "Day 1")
-	theme := firstNonEmpty(cfg.Theme, "auto")
-
-	loaded, err := pages.Load(pagesDir)
+	c, err := loadContent(fsys, label, f)
 	if err != nil {
-		return fmt.Errorf("load pages: %w", err)
+		return err
 	}
-	if len(loaded) == 0 {
-		return fmt.Errorf("no pages found in %s", pagesDir)
+	appCfg := c.appConfig()
+	app.MigrateCheckState(c.pages, c.facts)
+	shown := c.pages
+	if completed != nil {
+		shown = changedPages(c.pages, completed.Pages)
+		if len(shown) == 0 {
+			// Only pages this machine doesn't show changed.
+			completed.Version = appCfg.ContentVersion
+			if err := marker.WriteState(*completed); err != nil {
+				deck.Warningf("update sentinel: %v", err)
+			}
+			deck.Infof("no page shown here changed, recorded version %q and exiting", completed.Version)
+			return nil
+		}
+		appCfg.WhatsNew = true
+		deck.Infof("content changed since completion (version %q -> %q), showing %d of %d pages",
+			completed.Version, appCfg.ContentVersion, len(shown), len(c.pages))
 	}
 
-	deck.Infof("loaded %d pages from %s", len(loaded), pagesDir)
-
-	var finalMD string
-	if cfg.FinalPage != "" {
-		if filepath.IsAbs(cfg.FinalPage) || strings.Contains(cfg.FinalPage, "..") {
-			return fmt.Errorf("final_page must be a relative path without '..'")
+	if !flagForce && !appCfg.Mandatory {
+		if sn := app.LoadSnooze(); sn.Snoozed(time.Now()) {
+			deck.Infof("snoozed until %s, exiting (use --force to override)", sn.Until().Format(time.RFC3339))
+			return nil
 		}
-		data, err := os.ReadFile(filepath.Join(pagesDir, cfg.FinalPage))
-		if err != nil {
-			return fmt.Errorf("read final page: %w", err)
+	}
+
+	if flagForce || flagRestart {
+		if err := app.ResetProgress(); err != nil {
+			deck.Warningf("reset progress: %v", err)
 		}
-		finalMD = string(data)
 	}
 
-	a := app.New(loaded, app.Config{
-		HelpURL:     cfg.HelpURL,
-		FinalMD:     finalMD,
-		Theme:       theme,
-		AccentColor: cfg.AccentColor,
-		BrandName:   cfg.Brand.Name,
-		BrandLogo:   cfg.Brand.Logo,
-	})
+	a := app.New(shown, appCfg)
+	title := firstNonEmpty(c.cfg.Title, "Day 1")
+
+	if flagTUI || useTUI(runtime.GOOS, os.Getenv) {
+		deck.Info("running in terminal mode")
+		return tui.Run(a, tui.Options{
+			Title:   title,
+			Pages:   shown,
+			Facts:   c.facts,
+			FinalMD: c.finalMD,
+			Width:   terminalWidth(os.Getenv("COLUMNS")),
+			Color:   colorTerminal(os.Stdout, os.Getenv),
+		}, cmd.InOrStdin(), cmd.OutOrStdout())
+	}
 
 	if runtime.GOOS == "linux" {
 		if os.Getenv("XDG_SESSION_TYPE") == "wayland" || os.Getenv("WAYLAND_DISPLAY") != "" {
@@ -162,7 +251,7 @@ func run(cmd *cobra.Command, _ []string) error {
 		}
 	}
 
-	pagesHandler := http.StripPrefix("/pages/", http.FileServer(http.Dir(pagesDir)))
+	pagesHandler := http.StripPrefix("/pages/", http.FileServerFS(fsys))
 
 	err = wails.Run(&options.App{
 		Title:         title,
@@ -175,9 +264,10 @@ func run(cmd *cobra.Command, _ []string) error {
 			Assets:  frontendAssets,
 			Handler: pagesHandler,
 		},
-		OnStartup: a.Startup,
-		Bind:      []interface{}{a},
-		Windows:   &wopts.Options{IsZoomControlEnabled: false},
+		OnStartup:     a.Startup,
+		OnBeforeClose: a.BeforeClose,
+		Bind:          []interface{}{a},
+		Windows:       &wopts.Options{IsZoomControlEnabled: false},
 	})
 	if err != nil {
 		return fmt.Errorf("wails: %w", err)
@@ -185,35 +275,290 @@ func run(cmd *cobra.Command, _ []string) error {
 	return nil
 }
 
-// extractEmbeddedPages extracts to a temp dir. Caller must call cleanup.
-func extractEmbeddedPages() (string, func(), error) {
-	tmp, err := os.MkdirTemp("", "day1-pages-*")
+// useTUI reports whether there is no graphical session to open a window
+// in: Linux without an X11 or Wayland display, as over SSH.
+func useTUI(goos string, getenv func(string) string) bool {
+	return goos == "linux" && getenv("DISPLAY") == "" && getenv("WAYLAND_DISPLAY") == ""
+}
+
+// terminalWidth parses $COLUMNS, defaulting to 80 columns.
+func terminalWidth(columns string) int {
+	if n, err := strconv.Atoi(columns); err == nil && n > 0 {
+		return n
+	}
+	return 80
+}
+
+// colorTerminal reports whether f is a terminal that should get ANSI
+// styles. NO_COLOR (https://no-color.org) and TERM=dumb turn them off.
+func colorTerminal(f *os.File, getenv func(string) string) bool {
+	if getenv("NO_COLOR") != "" || getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// content is everything loaded from a pages directory for one machine.
+type content struct {
+	cfg     pages.Config
+	pages   []pages.Page
+	finalMD string
+	links   *urischeme.Policy
+	version string
+	snooze  time.Duration
+	facts   facts.Facts
+}
+
+// loadContent loads day1.yml, the pages shown on a machine with facts f
+// and the final page from fsys, translated for f.Locale where the content
+// has translations. label names the content in messages.
+func loadContent(fsys fs.FS, label string, f facts.Facts) (content, error) {
+	f.Locale = pages.ContentLocale(fsys, f.Locale)
+	cfg, err := pages.LoadLocalizedConfig(fsys, f.Locale)
+	if err != nil {
+		deck.Warningf("config: %v (using defaults)", err)
+	}
+
+	loaded, err := pages.LoadForFacts(fsys, f)
+	if err != nil {
+		return content{}, fmt.Errorf("load pages: %w", err)
+	}
+	if len(loaded) == 0 {
+		return content{}, fmt.Errorf("no pages found in %s", label)
+	}
+
+	deck.Infof("loaded %d pages from %s in %s", len(loaded), label, f.Locale)
+	warnUntranslated(loaded, f.Locale, cfg.DefaultLocale())
+
+	links, err := cfg.LinkPolicy()
+	if err != nil {
+		return content{}, fmt.Errorf("%s links: %w", label, err)
+	}
+	warnBlockedLinks(loaded, links, f)
+	snooze, err := cfg.Snooze.Interval()
+	if err != nil {
+		return content{}, fmt.Errorf("%s: %w", label, err)
+	}
+	version, _, err := contentVersion(fsys)
+	if err != nil {
+		return content{}, fmt.Errorf("%s: %w", label, err)
+	}
+
+	var finalMD string
+	if cfg.FinalPage != "" {
+		if filepath.IsAbs(cfg.FinalPage) || strings.Contains(cfg.FinalPage, "..") {
+			return content{}, fmt.Errorf("final_page must be a relative path without '..'")
+		}
+		file := pages.LocalizedFile(fsys, cfg.FinalPage, f.Locale)
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return content{}, fmt.Errorf("read final page: %w", err)
+		}
+		if finalMD, err = pages.ExpandIncludes(fsys, file, string(data), pages.WithFacts(f), pages.WithLocale(f.Locale)); err != nil {
+			return content{}, err
+		}
+		if finalMD, err = pages.ExpandTemplate(file, finalMD, pages.NewTemplateData(f, cfg)); err != nil {
+			return content{}, err
+		}
+	}
+	return content{cfg: cfg, pages: loaded, finalMD: finalMD, links: links, version: version, snooze: snooze, facts: f}, nil
+}
+
+// warnUntranslated logs the pages shown in the default language def
+// because they have no translation for locale.
+func warnUntranslated(loaded []pages.Page, locale, def string) {
+	if locale == def {
+		return
+	}
+	for _, p := range loaded {
+		if p.Locale == "" {
+			deck.Warningf("%s: no %s translation, showing it in %s", p.SourceFile, locale, def)
+		}
+	}
+}
+
+// setLocale sets the locale of f to flag, a language tag or POSIX
+// locale, unless it is empty.
+func setLocale(f *facts.Facts, flag string) error {
+	if flag == "" {
+		return nil
+	}
+	if f.Locale = facts.ParseLocale(flag); f.Locale == "" {
+		return fmt.Errorf("invalid --locale %q (want a language tag like de or pt-BR)", flag)
+	}
+	return nil
+}
+
+// warnBlockedLinks logs every link in loaded that OpenURL would refuse on
+// a machine with facts f, so authors notice a missing links: entry in
+// day1.yml. Links in ::: blocks hidden on this machine are skipped.
+func warnBlockedLinks(loaded []pages.Page, links *urischeme.Policy, f facts.Facts) {
+	for _, p := range loaded {
+		for _, link := range pages.Links(p.Markdown, pages.WithFacts(f)) {
+			if err := links.Check(link, f.OS); err != nil {
+				deck.Warningf("%s: link %q will be blocked: %v", p.SourceFile, link, err)
+			}
+		}
+	}
+}
+
+// appConfig maps the loaded content onto app.Config.
+func (c content) appConfig() app.Config {
+	hashes := make(map[string]string, len(c.pages))
+	for _, p := range c.pages {
+		hashes[p.ID()] = p.Hash()
+	}
+	return app.Config{
+		HelpURL:        c.cfg.HelpURL,
+		FinalMD:        c.finalMD,
+		Theme:          firstNonEmpty(c.cfg.Theme, "auto"),
+		AccentColor:    c.cfg.AccentColor,
+		BrandName:      c.cfg.Brand.Name,
+		BrandLogo:      c.cfg.Brand.Logo,
+		ContentVersion: c.version,
+		PageHashes:     hashes,
+		Links:          c.links,
+		Actions:        c.cfg.Actions,
+		Facts:          c.facts,
+		Locale:         c.facts.Locale,
+		SourceLocale:   c.cfg.DefaultLocale(),
+		Strings:        i18n.Strings(c.facts.Locale, c.cfg.Strings),
+		Mandatory:      c.cfg.Mandatory(),
+		SnoozeFor:      c.snooze,
+		MaxSnoozes:     c.cfg.Snooze.Max,
+	}
+}
+
+// newApp loads the content of fsys for facts f and wraps it in an
+// app.App that reads saved state without writing it, for preview and
+// status. The returned title is the window title.
+func newApp(fsys fs.FS, label string, f facts.Facts) (*app.App, string, error) {
+	c, err := loadContent(fsys, label, f)
 	if err != nil {
-		return "", nil, err
+		return nil, "", err
+	}
+	return app.New(c.pages, c.appConfig()), firstNonEmpty(c.cfg.Title, "Day 1"), nil
+}
+
+// contentVersion returns the version of the content in fsys:
+// content_version from day1.yml, and explicit set, or a hash of the pages
+// as stored. It reads nothing else, for the check at every login.
+func contentVersion(fsys fs.FS) (version string, explicit bool, err error) {
+	cfg, _ := pages.LoadConfig(fsys)
+	if cfg.ContentVersion != "" {
+		return cfg.ContentVersion, true, nil
+	}
+	version, err = pages.SourceHash(fsys)
+	return version, false, err
+}
+
+// needsRerun reports whether a completed sentinel is outdated. Sentinels
+// from before versioning only count as outdated once day1.yml sets an
+// explicit content_version, so upgrading day1 alone doesn't re-show the
+// wizard to everyone.
+func needsRerun(st marker.State, version string, explicit bool) bool {
+	if st.Version == "" && !explicit {
+		return false
+	}
+	return st.OlderThan(version)
+}
+
+// changedPages returns the pages whose hash differs from the one recorded
+// at completion, or all pages when nothing more specific is known. It
+// returns none when the content changed only in pages not in all, such
+// as pages for another platform.
+func changedPages(all []pages.Page, completed map[string]string) []pages.Page {
+	if len(completed) == 0 {
+		return all
 	}
+	var out []pages.Page
+	for _, p := range all {
+		if completed[p.ID()] != p.Hash() {
+			out = append(out, p)
+		}
+	}
+	return out
+}
 
-	err = fs.WalkDir(defaultPages, "testdata/pages", func(path string, d fs.DirEntry, err error) error {
+// openPages opens the pages directory or .zip archive at path, or the
+// built-in demo pages when path is empty. path may list several, separated
+// like $PATH, to overlay them: files in earlier ones override those of the
+// same name in later ones. The label names the content in messages.
+// Callers must call cleanup.
+func openPages(path string) (fsys fs.FS, label string, cleanup func(), err error) {
+	if path == "" {
+		sub, err := fs.Sub(defaultPages, "testdata/pages")
 		if err != nil {
-			return err
+			return nil, "", nil, fmt.Errorf("built-in pages: %w", err)
+		}
+		deck.Info("using built-in demo pages")
+		return sub, "built-in pages", func() {}, nil
+	}
+	paths := filepath.SplitList(path)
+	if len(paths) == 1 {
+		fsys, cleanup, err = pages.Open(path)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("load pages: %w", err)
 		}
-		rel, _ := filepath.Rel("testdata/pages", path)
-		target := filepath.Join(tmp, rel)
+		return fsys, path, cleanup, nil
+	}
 
-		if d.IsDir() {
-			return os.MkdirAll(target, 0o700)
+	var layers []fs.FS
+	var cleanups []func()
+	cleanup = func() {
+		for _, c := range cleanups {
+			c()
 		}
-		data, err := defaultPages.ReadFile(path)
+	}
+	for _, p := range paths {
+		layer, c, err := pages.Open(p)
 		if err != nil {
-			return err
+			cleanup()
+			return nil, "", nil, fmt.Errorf("load pages: %w", err)
 		}
-		return os.WriteFile(target, data, 0o600)
-	})
+		layers = append(layers, layer)
+		cleanups = append(cleanups, c)
+	}
+	deck.Infof("overlaying %d page sources", len(layers))
+	return pages.Overlay(layers...), path, cleanup, nil
+}
+
+// verifyBundle verifies every file of the bundle at path, built by day1
+// pack, against its manifest, and returns the manifest. Pages are loaded
+// from it only after.
+func verifyBundle(fsys fs.FS, path string) (bundle.Manifest, error) {
+	m, err := bundle.Verify(fsys)
 	if err != nil {
-		os.RemoveAll(tmp)
-		return "", nil, err
+		return bundle.Manifest{}, fmt.Errorf("bundle %s: %w", path, err)
 	}
+	deck.Infof("verified bundle %s (%d files, built with day1 %s)", path, len(m.Files), m.Day1Version)
+	return m, nil
+}
 
-	return tmp, func() { os.RemoveAll(tmp) }, nil
+// trustedKeys returns the key compiled in via ldflags, if any, and the
+// public keys in files.
+func trustedKeys(files []string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	if version.TrustedKey != "" {
+		key, err := bundle.ParsePublicKey([]byte(version.TrustedKey))
+		if err != nil {
+			return nil, fmt.Errorf("built-in trusted key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("trusted key: %w", err)
+		}
+		key, err := bundle.ParsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
 }
 
 func firstNonEmpty(values ...string) string {
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"os"
+
+	"github.com/TsekNet/day1/internal/bundle"
+	"github.com/TsekNet/day1/internal/version"
+	"github.com/spf13/cobra"
+)
+
+func signCmd() *cobra.Command {
+	var keyPath, dir, bundlePath string
+	c := &cobra.Command{
+		Use:   "sign",
+		Short: "Sign a pages directory or bundle with an ed25519 private key",
+		Long: `sign writes a detached signature (manifest.sig) over the content
+manifest. For a pages directory, manifest.json is regenerated first; for a
+bundle built with day1 pack, the bundle must still match its manifest and
+the signature is added to the archive.
+
+day1 started with --trusted-key, or built with a trusted key, refuses to
+show content that isn't signed by that key. Create a key pair with:
+
+  openssl genpkey -algorithm ed25519 -out day1-signing.pem
+  openssl pkey -in day1-signing.pem -pubout -out day1-signing.pub`,
+		Example: `  day1 sign --key day1-signing.pem --pages-dir ./pages
+  day1 sign --key day1-signing.pem --bundle onboarding.zip`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			data, err := os.ReadFile(keyPath)
+			if err != nil {
+				return err
+			}
+			key, err := bundle.ParsePrivateKey(data)
+			if err != nil {
+				return err
+			}
+
+			var m bundle.Manifest
+			target := dir
+			if bundlePath != "" {
+				target = bundlePath
+				m, err = bundle.SignArchive(bundlePath, key)
+			} else {
+				m, err = bundle.SignDir(dir, key, version.Version)
+			}
+			if err != nil {
+				return fmt.Errorf("sign %s: %w", target, err)
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "signed %d files in %s\npublic key: %s\n",
+				len(m.Files), target, bundle.EncodePublicKey(key.Public().(ed25519.PublicKey)))
+			return nil
+		},
+	}
+	f := c.Flags()
+	f.StringVar(&keyPath, "key", "", "PEM-encoded ed25519 private key")
+	f.StringVar(&dir, "pages-dir", "", "pages directory to sign")
+	f.StringVar(&bundlePath, "bundle", "", "bundle built with day1 pack to sign")
+	c.MarkFlagRequired("key")
+	c.MarkFlagsOneRequired("pages-dir", "bundle")
+	c.MarkFlagsMutuallyExclusive("pages-dir", "bundle")
+	return c
+}
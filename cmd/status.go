@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/TsekNet/day1/internal/app"
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/marker"
+	"github.com/google/deck"
+	"github.com/spf13/cobra"
+)
+
+// statusReport is the output of `day1 status`. Field names are part of the
+// --json contract used by MDM scripts.
+type statusReport struct {
+	Sentinel    string              `json:"sentinel"`
+	Completed   bool                `json:"completed"`
+	CompletedAt *time.Time          `json:"completed_at,omitempty"`
+	Version     string              `json:"version,omitempty"`
+	LastPage    string              `json:"last_page,omitempty"`
+	Visited     []string            `json:"visited"`
+	Pages       []app.PageChecklist `json:"pages"`
+	// Mandatory is set when day1.yml or the number of dismissals keeps
+	// the wizard from being put off.
+	Mandatory    bool            `json:"mandatory"`
+	SnoozedUntil *time.Time      `json:"snoozed_until,omitempty"`
+	Dismissals   []app.Dismissal `json:"dismissals"`
+	// ContentError is why the content couldn't be loaded. The saved state
+	// is still reported, without checklists.
+	ContentError string `json:"content_error,omitempty"`
+}
+
+func statusCmd() *cobra.Command {
+	var (
+		dir    string
+		asJSON bool
+	)
+	c := &cobra.Command{
+		Use:   "status",
+		Short: "Show whether onboarding was completed and how far the user got",
+		Long: `status reports whether the completion sentinel exists and when it was
+written, the last page viewed, checklist completion per page of the
+content in --pages-dir (default: built-in), and every time the wizard was
+closed or snoozed since it was last completed. Use --json for scripts.
+
+When the content can't be loaded the saved state is still reported,
+without checklists, with the error as a note.`,
+		Example: `  day1 status
+  day1 status --pages-dir /opt/day1/pages --json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			r, err := buildStatus(dir)
+			if err != nil {
+				return err
+			}
+			w := cmd.OutOrStdout()
+			if asJSON {
+				enc := json.NewEncoder(w)
+				enc.SetIndent("", "  ")
+				return enc.Encode(r)
+			}
+			printStatus(w, r)
+			return nil
+		},
+	}
+	f := c.Flags()
+	f.StringVar(&dir, "pages-dir", "", "directory or .zip archive containing .md pages and day1.yml (default: built-in)")
+	f.BoolVar(&asJSON, "json", false, "print the status as JSON")
+	return c
+}
+
+func buildStatus(dir string) (statusReport, error) {
+	var r statusReport
+	p, err := marker.Path()
+	if err != nil {
+		return r, err
+	}
+	r.Sentinel = p
+
+	st, done, err := marker.Read()
+	if err != nil {
+		return r, fmt.Errorf("read sentinel: %w", err)
+	}
+	if done {
+		r.Completed = true
+		r.Version = st.Version
+		if !st.CompletedAt.IsZero() {
+			r.CompletedAt = &st.CompletedAt
+		}
+	}
+
+	if a, err := statusApp(dir); err != nil {
+		deck.Warningf("status without content: %v", err)
+		r.ContentError = err.Error()
+		progress := app.LoadProgress()
+		r.LastPage = progress.PageID
+		r.Visited = progress.Visited
+		r.Pages = []app.PageChecklist{}
+	} else {
+		progress := a.GetProgress()
+		r.LastPage = progress.PageID
+		r.Visited = progress.Visited
+		r.Pages = a.ChecklistStatus()
+		r.Mandatory = a.GetMandatory()
+	}
+
+	sn := app.LoadSnooze()
+	r.Dismissals = sn.Dismissals
+	if r.Dismissals == nil {
+		r.Dismissals = []app.Dismissal{}
+	}
+	if until := sn.Until(); sn.Snoozed(time.Now()) {
+		r.SnoozedUntil = &until
+	}
+	return r, nil
+}
+
+// statusApp loads the content in dir for this machine.
+func statusApp(dir string) (*app.App, error) {
+	fsys, label, cleanup, err := openPages(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	a, _, err := newApp(fsys, label, facts.Current())
+	return a, err
+}
+
+func printStatus(w io.Writer, r statusReport) {
+	fmt.Fprintf(w, "sentinel:   %s\n", r.Sentinel)
+	switch {
+	case !r.Completed:
+		fmt.Fprintln(w, "completed:  no")
+	case r.CompletedAt != nil:
+		fmt.Fprintf(w, "completed:  %s\n", r.CompletedAt.Format(time.RFC3339))
+	default:
+		fmt.Fprintln(w, "completed:  yes")
+	}
+	if r.Version != "" {
+		fmt.Fprintf(w, "version:    %s\n", r.Version)
+	}
+	last := "-"
+	if r.LastPage != "" {
+		last = r.LastPage
+	}
+	if r.ContentError != "" {
+		fmt.Fprintf(w, "last page:  %s (%d pages visited)\n", last, len(r.Visited))
+		fmt.Fprintf(w, "content:    not loaded: %s\n", r.ContentError)
+	} else {
+		fmt.Fprintf(w, "last page:  %s (%d of %d pages visited)\n", last, len(r.Visited), len(r.Pages))
+		fmt.Fprintln(w, "checklist:")
+	}
+	width := 0
+	for _, p := range r.Pages {
+		width = max(width, len(p.ID))
+	}
+	for _, p := range r.Pages {
+		if p.Total == 0 {
+			fmt.Fprintf(w, "  %-*s  -\n", width, p.ID)
+			continue
+		}
+		fmt.Fprintf(w, "  %-*s  %d/%d\n", width, p.ID, p.Done, p.Total)
+	}
+
+	if r.Mandatory {
+		fmt.Fprintln(w, "mandatory:  yes")
+	}
+	if r.SnoozedUntil != nil {
+		fmt.Fprintf(w, "snoozed:    until %s\n", r.SnoozedUntil.Format(time.RFC3339))
+	}
+	if len(r.Dismissals) == 0 {
+		fmt.Fprintln(w, "dismissed:  never")
+		return
+	}
+	fmt.Fprintf(w, "dismissed:  %d times\n", len(r.Dismissals))
+	for _, d := range r.Dismissals {
+		if d.Until.IsZero() {
+			fmt.Fprintf(w, "  %s  closed\n", d.At.Format(time.RFC3339))
+			continue
+		}
+		fmt.Fprintf(w, "  %s  snoozed until %s\n", d.At.Format(time.RFC3339), d.Until.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/TsekNet/day1/internal/pages"
+	"github.com/spf13/cobra"
+)
+
+func validateCmd() *cobra.Command {
+	var dir string
+	c := &cobra.Command{
+		Use:   "validate",
+		Short: "Lint a pages directory before shipping it",
+		Long: `validate loads day1.yml and every page the way the wizard would, for
+every supported platform, and reports all problems with file:line
+locations. Exits non-zero when any problem is found, so it can gate CI.`,
+		Example: `  day1 validate --pages-dir ./pages`,
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			problems := pages.Validate(dir)
+			w := cmd.OutOrStdout()
+			for _, p := range problems {
+				fmt.Fprintln(w, p)
+			}
+			if len(problems) > 0 {
+				return fmt.Errorf("%d problem(s) found in %s", len(problems), dir)
+			}
+			fmt.Fprintf(w, "%s: ok\n", dir)
+			return nil
+		},
+	}
+	c.Flags().StringVar(&dir, "pages-dir", "", "directory containing .md pages and day1.yml")
+	c.MarkFlagRequired("pages-dir")
+	return c
+}
@@ -7,7 +7,14 @@
   var totalPages = 0;
   var onFinalPage = false;
   var checkState = {};
+  var visited = {};
   var CHECKBOX_SEL = 'input[type="checkbox"]';
+  // strings are the wizard's own strings, translated by the backend.
+  var strings = {};
+  // mandatory wizards can only be left by completing them.
+  var mandatory = false;
+  // snooze shows "Remind me later", which hides the wizard for a while.
+  var snooze = false;
 
   function findApp() {
     if (!window.go) return null;
@@ -17,6 +24,25 @@
     return null;
   }
 
+  // t returns the string for key with {name} placeholders filled in from
+  // vars, or the key itself when the backend has no such string.
+  function t(key, vars) {
+    var s = strings[key] || key;
+    for (var name in vars || {}) {
+      s = s.split("{" + name + "}").join(vars[name]);
+    }
+    return s;
+  }
+
+  function applyStrings(s) {
+    strings = s || {};
+    document.getElementById("whats-new").textContent = t("whats_new");
+    document.getElementById("help-link").textContent = t("help") + " \u2197";
+    document.getElementById("btn-close").textContent = t("close");
+    document.getElementById("btn-snooze").textContent = t("snooze");
+    document.getElementById("btn-next").textContent = t("next");
+  }
+
   function applyTheme(theme) {
     if (theme === "dark" || theme === "light") {
       document.documentElement.setAttribute("data-theme", theme);
@@ -33,6 +59,16 @@
 
     Backend.GetTheme().then(applyTheme);
 
+    Backend.GetLocale().then(function(locale) {
+      if (locale) document.documentElement.lang = locale;
+    });
+
+    // The layout follows the direction of the language: in rtl the
+    // stepper, footer and checklists run right to left.
+    Backend.GetDir().then(function(dir) {
+      if (dir) document.documentElement.dir = dir;
+    });
+
     Backend.GetAccentColor().then(function(color) {
       if (color && /^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$/.test(color)) {
         if (color.length === 4) {
@@ -78,18 +114,78 @@
       }
     });
 
-    Backend.GetCheckState().then(function(state) {
-      checkState = state || {};
-      Backend.GetPages().then(function(pages) {
-        allPages = pages || [];
-        totalPages = allPages.length;
-        buildProgress();
-        showPage(0);
-        Backend.Ready();
+    Backend.GetMandatory().then(function(m) {
+      mandatory = !!m;
+      if (mandatory) document.getElementById("btn-close").style.display = "none";
+    });
+
+    Backend.CanSnooze().then(function(s) {
+      snooze = !!s;
+      if (snooze && totalPages > 0 && !onFinalPage) document.getElementById("btn-snooze").style.display = "";
+    });
+
+    Backend.GetWhatsNew().then(function(whatsNew) {
+      if (whatsNew) {
+        document.getElementById("whats-new").style.display = "";
+      }
+    });
+
+    if (window.runtime && window.runtime.EventsOn) {
+      window.runtime.EventsOn("notice", showNotice);
+      // day1: links are handled by the backend, which tells us what to show.
+      window.runtime.EventsOn("navigate", function(index) {
+        if (index < 0) {
+          showFinalPage();
+        } else if (index < totalPages) {
+          showPage(index);
+        }
+      });
+      window.runtime.EventsOn("check", function(state) {
+        checkState = state || {};
+        if (!onFinalPage) showPage(currentIndex);
+      });
+    }
+
+    Backend.GetStrings().then(function(s) {
+      applyStrings(s);
+      Backend.GetCheckState().then(function(state) {
+        checkState = state || {};
+        Backend.GetPages().then(function(pages) {
+          allPages = pages || [];
+          totalPages = allPages.length;
+          buildProgress();
+          Backend.GetProgress().then(function(progress) {
+            var start = 0;
+            if (progress) {
+              (progress.visited || []).forEach(function(id) { visited[id] = true; });
+              if (progress.index > 0 && progress.index < totalPages) start = progress.index;
+            }
+            // Don't resume past a page whose required items were added since.
+            for (var i = 0; i < start; i++) {
+              if (requiredLeft(i)) {
+                start = i;
+                break;
+              }
+            }
+            showPage(start);
+            Backend.Ready();
+          });
+        });
       });
     });
   }
 
+  // showNotice displays a message from the backend for a few seconds, e.g.
+  // where to find a settings panel it couldn't open.
+  var noticeTimer = null;
+  function showNotice(message) {
+    var el = document.getElementById("notice");
+    el.textContent = message;
+    el.style.display = "";
+    clearTimeout(noticeTimer);
+    noticeTimer = setTimeout(function() { el.style.display = "none"; }, 6000);
+  }
+
   function buildProgress() {
     var container = document.getElementById("progress");
     container.innerHTML = "";
@@ -113,7 +209,8 @@
 
       var label = document.createElement("span");
       label.className = "step-label";
-      label.textContent = allPages[i].title || "Step " + (i + 1);
+      label.textContent = allPages[i].title || t("step", { n: i + 1 });
+      label.dir = "auto";
       step.appendChild(label);
 
       container.appendChild(step);
@@ -124,21 +221,48 @@
     var step = e.currentTarget;
     var index = parseInt(step.getAttribute("data-step-index"), 10);
     if (isNaN(index) || index < 0 || index >= totalPages) return;
+    if (blocked(index)) return;
     showPage(index);
   }
 
+  // requiredLeft reports whether page i has required checklist items that
+  // aren't checked yet.
+  function requiredLeft(i) {
+    var keys = (allPages[i] && allPages[i].required) || [];
+    for (var k = 0; k < keys.length; k++) {
+      if (!checkState[keys[k]]) return true;
+    }
+    return false;
+  }
+
+  // blocked reports whether a page before index, or index === totalPages
+  // for the final page, still has required items left, and says so. The
+  // backend refuses to complete in that case too.
+  function blocked(index) {
+    for (var i = 0; i < index && i < totalPages; i++) {
+      if (requiredLeft(i)) {
+        showNotice(t("required_left"));
+        return true;
+      }
+    }
+    return false;
+  }
+
   function updateProgress() {
     var steps = document.querySelectorAll(".step");
     var lines = document.querySelectorAll(".step-line");
 
+    var locked = false;
     for (var i = 0; i < steps.length; i++) {
       steps[i].classList.remove("active", "completed");
+      steps[i].classList.toggle("locked", locked);
+      locked = locked || requiredLeft(i);
       if (onFinalPage) {
         steps[i].classList.add("completed");
-      } else if (i < currentIndex) {
-        steps[i].classList.add("completed");
       } else if (i === currentIndex) {
         steps[i].classList.add("active");
+      } else if (i < currentIndex || visited[allPages[i].id]) {
+        steps[i].classList.add("completed");
       }
     }
 
@@ -153,19 +277,24 @@
   function showPage(index) {
     currentIndex = index;
     onFinalPage = false;
+    visited[allPages[index].id] = true;
+    Backend.SetCurrentPage(index);
 
     Backend.GetPageHTML(index).then(function(html) {
       var content = document.getElementById("content");
       content.className = "content";
+      content.dir = allPages[index].dir || "";
       content.innerHTML = html;
       enhanceChecklist(content, index);
 
       var indicator = document.getElementById("page-indicator");
-      indicator.textContent = (index + 1) + " of " + totalPages;
+      indicator.textContent = t("progress", { n: index + 1, total: totalPages });
 
       var btnNext = document.getElementById("btn-next");
-      btnNext.textContent = (index === totalPages - 1) ? "Finish" : "Next";
-      document.getElementById("btn-close").style.display = "";
+      btnNext.textContent = t(index === totalPages - 1 ? "finish" : "next");
+      btnNext.disabled = requiredLeft(index);
+      document.getElementById("btn-close").style.display = mandatory ? "none" : "";
+      document.getElementById("btn-snooze").style.display = snooze ? "" : "none";
 
       updateProgress();
     });
@@ -180,7 +309,8 @@
       var cb = li.querySelector(CHECKBOX_SEL);
       if (!cb || cb.closest("li") !== li) continue;
 
-      var key = pageIndex + ":" + checkCount;
+      var key = checkKey(pageIndex, cb);
+      if (!key) continue;
       checkCount++;
 
       var checked = !!checkState[key];
@@ -188,6 +318,7 @@
       cb.checked = checked;
       li.classList.add("check-item");
       li.classList.toggle("checked", checked);
+      var required = (allPages[pageIndex].required || []).indexOf(key) >= 0;
 
       var wrap = document.createElement("span");
       wrap.className = "check-item-text";
@@ -203,6 +334,12 @@
         var plain = document.createTextNode(links[j].textContent);
         links[j].parentNode.replaceChild(plain, links[j]);
       }
+      if (required) {
+        var badge = document.createElement("span");
+        badge.className = "required-badge";
+        badge.textContent = t("required");
+        li.appendChild(badge);
+      }
       for (var j = 0; j < hrefs.length; j++) {
         (function(info) {
           if (!info.href) return;
@@ -225,6 +362,8 @@
             checkState[itemKey] = checked;
             item.classList.toggle("checked", checked);
             updateCheckProgress(container, pageIndex);
+            document.getElementById("btn-next").disabled = requiredLeft(pageIndex);
+            updateProgress();
           });
         });
       })(li, cb, key);
@@ -235,13 +374,21 @@
     }
   }
 
+  // checkKey returns the stable "pageID:itemID" key of a checkbox, using the
+  // item ID the backend renders into data-check-id.
+  function checkKey(pageIndex, cb) {
+    var itemID = cb.getAttribute("data-check-id");
+    if (!itemID || !allPages[pageIndex]) return "";
+    return allPages[pageIndex].id + ":" + itemID;
+  }
+
   function updateCheckProgress(container, pageIndex) {
-    var items = container.querySelectorAll("li");
+    var boxes = container.querySelectorAll(CHECKBOX_SEL);
     var total = 0, done = 0;
-    for (var i = 0; i < items.length; i++) {
-      if (!items[i].querySelector(CHECKBOX_SEL)) continue;
+    for (var i = 0; i < boxes.length; i++) {
+      var key = checkKey(pageIndex, boxes[i]);
+      if (!key) continue;
       total++;
-      var key = pageIndex + ":" + (total - 1);
       if (checkState[key]) done++;
     }
     if (total === 0) return;
@@ -269,7 +416,7 @@
     }
     var pct = Math.round((done / total) * 100);
     bar.querySelector(".check-progress-fill").style.width = pct + "%";
-    bar.querySelector(".check-progress-label").textContent = done + " of " + total;
+    bar.querySelector(".check-progress-label").textContent = t("progress", { n: done, total: total });
   }
 
   function showFinalPage() {
@@ -278,6 +425,7 @@
 
     Backend.GetFinalHTML().then(function(html) {
       var content = document.getElementById("content");
+      content.removeAttribute("dir");
       if (html) {
         content.className = "content";
         content.innerHTML = html;
@@ -287,13 +435,16 @@
           '<div class="final-check">' +
             '<svg viewBox="0 0 24 24"><polyline points="20 6 9 17 4 12"></polyline></svg>' +
           '</div>' +
-          '<h1>You\'re all set!</h1>' +
-          '<p>You\'re ready to go. Close this window to get started.</p>';
+          '<h1></h1><p></p>';
+        content.querySelector("h1").textContent = t("done_title");
+        content.querySelector("p").textContent = t("done_text");
       }
 
       document.getElementById("page-indicator").textContent = "";
       document.getElementById("btn-close").style.display = "none";
-      document.getElementById("btn-next").textContent = "Close";
+      document.getElementById("btn-snooze").style.display = "none";
+      document.getElementById("btn-next").textContent = t("close");
+      document.getElementById("btn-next").disabled = false;
     });
   }
 
@@ -302,6 +453,7 @@
       Backend.Complete();
       return;
     }
+    if (blocked(currentIndex + 1)) return;
     if (currentIndex < totalPages - 1) {
       showPage(currentIndex + 1);
     } else {
@@ -311,21 +463,57 @@
 
   document.getElementById("btn-next").addEventListener("click", advance);
 
+  // Links in page content open through the backend, which applies the link
+  // policy and handles day1: links. In-page anchors scroll as usual.
+  document.getElementById("content").addEventListener("click", function(e) {
+    var link = e.target.closest("a[href]");
+    if (!link) return;
+    var href = link.getAttribute("href");
+    if (href.charAt(0) === "#") return;
+    e.preventDefault();
+    Backend.OpenURL(href);
+  });
+
   document.getElementById("btn-close").addEventListener("click", function() {
     Backend.Dismiss();
   });
 
+  document.getElementById("btn-snooze").addEventListener("click", function() {
+    Backend.Snooze();
+  });
+
+  // back returns to the previous page, if there is one.
+  function back() {
+    if (!onFinalPage && currentIndex > 0) {
+      showPage(currentIndex - 1);
+    }
+  }
+
+  // Enter and Backspace move forward and back in reading order. The arrow
+  // keys follow the layout: the one pointing at the next step advances,
+  // which is the left arrow in right-to-left languages.
   document.addEventListener("keydown", function(e) {
+    var rtl = document.documentElement.dir === "rtl";
     if (e.key === "Enter") {
       e.preventDefault();
       advance();
     } else if (e.key === "Backspace") {
       e.preventDefault();
-      if (!onFinalPage && currentIndex > 0) {
-        showPage(currentIndex - 1);
+      back();
+    } else if (e.key === "ArrowRight" || e.key === "ArrowLeft") {
+      if (e.altKey || e.ctrlKey || e.metaKey || e.shiftKey) return;
+      e.preventDefault();
+      if ((e.key === "ArrowRight") !== rtl) {
+        if (!onFinalPage) advance();
+      } else {
+        back();
       }
     } else if (e.key === "Escape") {
-      Backend.Dismiss();
+      if (mandatory) {
+        showNotice(t("mandatory"));
+      } else {
+        Backend.Dismiss();
+      }
     }
   });
 
@@ -2,32 +2,60 @@
 // This file is automatically generated. DO NOT EDIT
 import {app} from '../models';
 
+export function CanSnooze():Promise<boolean>;
+
+export function ChecklistStatus():Promise<Array<app.PageChecklist>>;
+
 export function Complete():Promise<void>;
 
 export function Dismiss():Promise<void>;
 
+export function FirstIncomplete():Promise<number>;
+
 export function GetAccentColor():Promise<string>;
 
 export function GetBrand():Promise<app.BrandInfo>;
 
 export function GetCheckState():Promise<Record<string, boolean>>;
 
+export function GetDir():Promise<string>;
+
 export function GetFinalHTML():Promise<string>;
 
 export function GetHelpURL():Promise<string>;
 
+export function GetLocale():Promise<string>;
+
+export function GetMandatory():Promise<boolean>;
+
 export function GetPageHTML(arg1:number):Promise<string>;
 
 export function GetPages():Promise<Array<app.PageInfo>>;
 
+export function GetProgress():Promise<app.Progress>;
+
+export function GetStrings():Promise<Record<string, string>>;
+
 export function GetTheme():Promise<string>;
 
 export function GetUsername():Promise<string>;
 
+export function GetWhatsNew():Promise<boolean>;
+
+export function MarkComplete():Promise<void>;
+
+export function MarkDismissed():Promise<void>;
+
+export function MarkSnoozed():Promise<any>;
+
 export function OpenHelp():Promise<void>;
 
 export function OpenURL(arg1:string):Promise<void>;
 
 export function Ready():Promise<void>;
 
+export function SetCurrentPage(arg1:number):Promise<void>;
+
+export function Snooze():Promise<void>;
+
 export function ToggleCheckItem(arg1:string):Promise<boolean>;
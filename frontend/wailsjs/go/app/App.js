@@ -2,6 +2,14 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function CanSnooze() {
+  return window['go']['app']['App']['CanSnooze']();
+}
+
+export function ChecklistStatus() {
+  return window['go']['app']['App']['ChecklistStatus']();
+}
+
 export function Complete() {
   return window['go']['app']['App']['Complete']();
 }
@@ -10,6 +18,10 @@ export function Dismiss() {
   return window['go']['app']['App']['Dismiss']();
 }
 
+export function FirstIncomplete() {
+  return window['go']['app']['App']['FirstIncomplete']();
+}
+
 export function GetAccentColor() {
   return window['go']['app']['App']['GetAccentColor']();
 }
@@ -22,6 +34,10 @@ export function GetCheckState() {
   return window['go']['app']['App']['GetCheckState']();
 }
 
+export function GetDir() {
+  return window['go']['app']['App']['GetDir']();
+}
+
 export function GetFinalHTML() {
   return window['go']['app']['App']['GetFinalHTML']();
 }
@@ -30,6 +46,14 @@ export function GetHelpURL() {
   return window['go']['app']['App']['GetHelpURL']();
 }
 
+export function GetLocale() {
+  return window['go']['app']['App']['GetLocale']();
+}
+
+export function GetMandatory() {
+  return window['go']['app']['App']['GetMandatory']();
+}
+
 export function GetPageHTML(arg1) {
   return window['go']['app']['App']['GetPageHTML'](arg1);
 }
@@ -38,6 +62,14 @@ export function GetPages() {
   return window['go']['app']['App']['GetPages']();
 }
 
+export function GetProgress() {
+  return window['go']['app']['App']['GetProgress']();
+}
+
+export function GetStrings() {
+  return window['go']['app']['App']['GetStrings']();
+}
+
 export function GetTheme() {
   return window['go']['app']['App']['GetTheme']();
 }
@@ -46,6 +78,22 @@ export function GetUsername() {
   return window['go']['app']['App']['GetUsername']();
 }
 
+export function GetWhatsNew() {
+  return window['go']['app']['App']['GetWhatsNew']();
+}
+
+export function MarkComplete() {
+  return window['go']['app']['App']['MarkComplete']();
+}
+
+export function MarkDismissed() {
+  return window['go']['app']['App']['MarkDismissed']();
+}
+
+export function MarkSnoozed() {
+  return window['go']['app']['App']['MarkSnoozed']();
+}
+
 export function OpenHelp() {
   return window['go']['app']['App']['OpenHelp']();
 }
@@ -58,6 +106,14 @@ export function Ready() {
   return window['go']['app']['App']['Ready']();
 }
 
+export function SetCurrentPage(arg1) {
+  return window['go']['app']['App']['SetCurrentPage'](arg1);
+}
+
+export function Snooze() {
+  return window['go']['app']['App']['Snooze']();
+}
+
 export function ToggleCheckItem(arg1) {
   return window['go']['app']['App']['ToggleCheckItem'](arg1);
 }
@@ -14,9 +14,30 @@ export namespace app {
 	        this.logo = source["logo"];
 	    }
 	}
+	export class PageChecklist {
+	    id: string;
+	    title: string;
+	    done: number;
+	    total: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PageChecklist(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.title = source["title"];
+	        this.done = source["done"];
+	        this.total = source["total"];
+	    }
+	}
 	export class PageInfo {
+	    id: string;
 	    title: string;
 	    index: number;
+	    dir: string;
+	    required?: string[];
 	
 	    static createFrom(source: any = {}) {
 	        return new PageInfo(source);
@@ -24,8 +45,27 @@ export namespace app {
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
 	        this.title = source["title"];
 	        this.index = source["index"];
+	        this.dir = source["dir"];
+	        this.required = source["required"];
+	    }
+	}
+	export class Progress {
+	    index: number;
+	    page_id: string;
+	    visited: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Progress(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.index = source["index"];
+	        this.page_id = source["page_id"];
+	        this.visited = source["visited"];
 	    }
 	}
 
@@ -3,24 +3,36 @@ package app
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
-	"runtime"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/i18n"
 	"github.com/TsekNet/day1/internal/marker"
 	"github.com/TsekNet/day1/internal/pages"
+	"github.com/TsekNet/day1/internal/platform"
 	"github.com/TsekNet/day1/internal/urischeme"
 	"github.com/google/deck"
-	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 type PageInfo struct {
+	ID    string `json:"id"`
 	Title string `json:"title"`
 	Index int    `json:"index"`
+	// Dir is the direction the page is written in: ltr, rtl or auto.
+	Dir string `json:"dir"`
+	// Required are the check keys of the page's required checklist
+	// items, which must be checked before moving past it.
+	Required []string `json:"required,omitempty"`
 }
 
 type BrandInfo struct {
@@ -35,22 +47,95 @@ type Config struct {
 	AccentColor string
 	BrandName   string
 	BrandLogo   string
+	// ContentVersion and PageHashes are recorded in the sentinel on
+	// completion. PageHashes defaults to the hashes of the shown pages.
+	ContentVersion string
+	PageHashes     map[string]string
+	// WhatsNew marks a re-run that shows only pages changed since the
+	// user last completed the wizard.
+	WhatsNew bool
+	// Host is the window system. Defaults to the Wails runtime.
+	Host Host
+	// Links decides which URLs OpenURL may open. Defaults to the built-in
+	// urischeme policy.
+	Links *urischeme.Policy
+	// Actions are run by day1:action/<name> links.
+	Actions map[string]pages.Action
+	// Facts decide which ::: directive blocks of a page are shown.
+	// Defaults to this machine.
+	Facts facts.Facts
+	// Locale is the language the content is shown in. Defaults to
+	// pages.DefaultLocale.
+	Locale string
+	// SourceLocale is the language of pages shown untranslated. Defaults
+	// to pages.DefaultLocale.
+	SourceLocale string
+	// Dir is the direction of the wizard's layout, ltr or rtl. Defaults
+	// to the direction of Locale.
+	Dir string
+	// Strings are the wizard's own strings. Defaults to the built-in
+	// translation for Locale.
+	Strings map[string]string
+	// Mandatory refuses Dismiss and closing the window until the wizard
+	// is completed.
+	Mandatory bool
+	// SnoozeFor is how long Snooze hides the wizard. 0 turns snoozing off.
+	SnoozeFor time.Duration
+	// MaxSnoozes makes the wizard mandatory once it was snoozed or
+	// closed this many times. 0 means no limit.
+	MaxSnoozes int
 }
 
 type App struct {
-	ctx        context.Context
+	host       Host
 	pages      []pages.Page
 	cfg        Config
 	brand      BrandInfo
 	rendered   []string
+	dirs       []string
+	required   [][]string
+	checkKeys  map[string]bool
 	checkState map[string]bool
 	checkMu    sync.Mutex
+	progress   savedProgress
+	progressMu sync.Mutex
+	snooze     Snooze
+	snoozeMu   sync.Mutex
+	completed  atomic.Bool
 }
 
+// New creates the App for loaded. It reads saved state but never writes
+// it, so status and preview can use it; see MigrateCheckState.
 func New(loaded []pages.Page, cfg Config) *App {
+	if cfg.Facts.OS == "" {
+		cfg.Facts = facts.Current()
+	}
+	if cfg.Locale == "" {
+		cfg.Locale = pages.DefaultLocale
+	}
+	if cfg.Strings == nil {
+		cfg.Strings = i18n.Strings(cfg.Locale, nil)
+	}
+	if cfg.SourceLocale == "" {
+		cfg.SourceLocale = pages.DefaultLocale
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = i18n.Dir(cfg.Locale)
+	}
 	rendered := make([]string, len(loaded))
+	dirs := make([]string, len(loaded))
+	required := make([][]string, len(loaded))
+	checkKeys := map[string]bool{}
 	for i, p := range loaded {
-		html, err := pages.RenderHTML(p.Markdown, "/pages")
+		for _, item := range pages.Checklist(p.Markdown, pages.WithFacts(cfg.Facts)) {
+			key := CheckKey(p.ID(), item.ID)
+			checkKeys[key] = true
+			if item.Required || p.Frontmatter.Required {
+				required[i] = append(required[i], key)
+			}
+		}
+		dirs[i] = p.Dir(cfg.SourceLocale)
+		html, err := pages.RenderHTML(p.Markdown, "/pages", pages.WithSource(p.SourceFile), pages.WithFacts(cfg.Facts), pages.WithDir(dirs[i]))
 		if err != nil {
 			deck.Errorf("render page %s: %v", p.SourceFile, err)
 			rendered[i] = "<p>Error rendering page.</p>"
@@ -62,21 +147,51 @@ func New(loaded []pages.Page, cfg Config) *App {
 	if cfg.BrandLogo != "" {
 		logoURL = "/pages/" + cfg.BrandLogo
 	}
+	if cfg.PageHashes == nil {
+		cfg.PageHashes = make(map[string]string, len(loaded))
+		for _, p := range loaded {
+			cfg.PageHashes[p.ID()] = p.Hash()
+		}
+	}
+	host := cfg.Host
+	if host == nil {
+		host = &wailsHost{}
+	}
+	if cfg.Links == nil {
+		cfg.Links = urischeme.Default()
+	}
+	state := loadCheckState()
+	snooze := LoadSnooze()
+	if n := len(snooze.Dismissals); !cfg.Mandatory && cfg.MaxSnoozes > 0 && n >= cfg.MaxSnoozes {
+		deck.Infof("wizard was put off %d times, it is now mandatory", n)
+		cfg.Mandatory = true
+	}
 	return &App{
+		host:       host,
 		pages:      loaded,
 		cfg:        cfg,
 		brand:      BrandInfo{Name: cfg.BrandName, Logo: logoURL},
 		rendered:   rendered,
-		checkState: loadCheckState(),
+		dirs:       dirs,
+		required:   required,
+		checkKeys:  checkKeys,
+		checkState: state,
+		progress:   loadProgress(),
+		snooze:     snooze,
 	}
 }
 
-func (a *App) Startup(ctx context.Context) { a.ctx = ctx }
+// Startup receives the Wails context, which the default host needs.
+func (a *App) Startup(ctx context.Context) {
+	if h, ok := a.host.(*wailsHost); ok {
+		h.ctx = ctx
+	}
+}
 
 func (a *App) GetPages() []PageInfo {
 	info := make([]PageInfo, len(a.pages))
 	for i, p := range a.pages {
-		info[i] = PageInfo{Title: p.Frontmatter.Title, Index: i}
+		info[i] = PageInfo{ID: p.ID(), Title: p.Frontmatter.Title, Index: i, Dir: a.dirs[i], Required: a.required[i]}
 	}
 	return info
 }
@@ -92,7 +207,7 @@ func (a *App) GetFinalHTML() string {
 	if a.cfg.FinalMD == "" {
 		return ""
 	}
-	html, err := pages.RenderHTML(a.cfg.FinalMD, "/pages")
+	html, err := pages.RenderHTML(a.cfg.FinalMD, "/pages", pages.WithFacts(a.cfg.Facts), pages.WithDir(a.cfg.Dir))
 	if err != nil {
 		deck.Errorf("render final page: %v", err)
 		return ""
@@ -101,8 +216,24 @@ func (a *App) GetFinalHTML() string {
 }
 
 func (a *App) GetHelpURL() string     { return a.cfg.HelpURL }
-func (a *App) GetAccentColor() string  { return a.cfg.AccentColor }
-func (a *App) GetBrand() BrandInfo     { return a.brand }
+func (a *App) GetAccentColor() string { return a.cfg.AccentColor }
+func (a *App) GetBrand() BrandInfo    { return a.brand }
+func (a *App) GetWhatsNew() bool      { return a.cfg.WhatsNew }
+
+// GetLocale returns the language tag of the content, for the lang
+// attribute of the page.
+func (a *App) GetLocale() string { return a.cfg.Locale }
+
+// GetDir returns the direction of the wizard's layout, "ltr" or "rtl",
+// for the dir attribute of the page. Pages carry their own in PageInfo.
+func (a *App) GetDir() string { return a.cfg.Dir }
+
+// GetMandatory reports whether the wizard can only be left by completing
+// it, so the frontend hides Close.
+func (a *App) GetMandatory() bool { return a.cfg.Mandatory }
+
+// GetStrings returns the wizard's own strings by key, such as "next".
+func (a *App) GetStrings() map[string]string { return a.cfg.Strings }
 
 // GetTheme resolves "auto" on WSL by reading the Windows registry, since
 // WebKit2GTK can't detect prefers-color-scheme from the Windows host.
@@ -110,7 +241,7 @@ func (a *App) GetTheme() string {
 	if a.cfg.Theme != "auto" {
 		return a.cfg.Theme
 	}
-	if runtime.GOOS != "linux" || !isWSL() {
+	if !platform.IsWSL() {
 		return "auto"
 	}
 	if wslDarkMode() {
@@ -120,22 +251,87 @@ func (a *App) GetTheme() string {
 }
 
 func (a *App) Ready() {
-	wailsRuntime.WindowShow(a.ctx)
-	wailsRuntime.WindowCenter(a.ctx)
+	a.host.WindowShow()
+	a.host.WindowCenter()
 }
 
+// Complete writes the sentinel and quits. While required checklist items
+// are unchecked it shows the first page that has some instead.
 func (a *App) Complete() {
-	if err := marker.Write(); err != nil {
+	if err := a.MarkComplete(); err != nil {
+		deck.Warningf("complete refused: %v", err)
+		a.host.Emit(NavigateEvent, a.FirstIncomplete())
+		a.host.Emit(NoticeEvent, a.cfg.Strings["required_left"])
+		return
+	}
+	a.host.Quit()
+}
+
+// ErrRequired is returned by MarkComplete while required checklist items
+// are unchecked.
+var ErrRequired = errors.New("required checklist items are unchecked")
+
+// MarkComplete writes the sentinel and clears saved progress and snooze
+// history without quitting, for front ends that manage their own
+// lifecycle such as the terminal UI. It refuses with ErrRequired while
+// required checklist items are unchecked.
+func (a *App) MarkComplete() error {
+	if i := a.FirstIncomplete(); i >= 0 {
+		return fmt.Errorf("%w on page %s", ErrRequired, a.pages[i].ID())
+	}
+	a.completed.Store(true)
+	st := marker.State{Version: a.cfg.ContentVersion, Pages: a.cfg.PageHashes}
+	if err := marker.WriteState(st); err != nil {
 		deck.Errorf("write marker: %v", err)
 	} else {
 		deck.Info("onboarding completed, sentinel written")
 	}
-	wailsRuntime.Quit(a.ctx)
+	if err := ResetProgress(); err != nil {
+		deck.Warningf("reset progress: %v", err)
+	}
+	if err := ResetSnooze(); err != nil {
+		deck.Warningf("reset snooze: %v", err)
+	}
+	return nil
+}
+
+// FirstIncomplete returns the index of the first page with required
+// checklist items left unchecked, or -1 when there is none. The wizard
+// can't move past that page.
+func (a *App) FirstIncomplete() int {
+	a.checkMu.Lock()
+	defer a.checkMu.Unlock()
+	for i, keys := range a.required {
+		for _, key := range keys {
+			if !a.checkState[key] {
+				return i
+			}
+		}
+	}
+	return -1
 }
 
+// Dismiss quits without completing and records the dismissal, unless
+// the wizard is mandatory.
 func (a *App) Dismiss() {
+	if err := a.MarkDismissed(); err != nil {
+		deck.Warningf("dismiss refused: %v", err)
+		a.host.Emit(NoticeEvent, a.cfg.Strings["mandatory"])
+		return
+	}
 	deck.Info("wizard dismissed without completing")
-	wailsRuntime.Quit(a.ctx)
+	a.host.Quit()
+}
+
+// BeforeClose is the Wails OnBeforeClose hook. It keeps a mandatory
+// wizard's window open until the wizard is completed.
+func (a *App) BeforeClose(context.Context) (prevent bool) {
+	if !a.cfg.Mandatory || a.completed.Load() {
+		return false
+	}
+	deck.Warning("close refused: the wizard is mandatory")
+	a.host.Emit(NoticeEvent, a.cfg.Strings["mandatory"])
+	return true
 }
 
 func (a *App) OpenHelp() {
@@ -146,7 +342,7 @@ func (a *App) OpenHelp() {
 }
 
 func (a *App) OpenURL(rawURL string) {
-	if !urischeme.Allowed(rawURL) {
+	if !a.cfg.Links.Allowed(rawURL) {
 		truncated := rawURL
 		if len(truncated) > 100 {
 			truncated = truncated[:100] + "..."
@@ -154,7 +350,21 @@ func (a *App) OpenURL(rawURL string) {
 		deck.Warningf("blocked URL: %s", truncated)
 		return
 	}
-	if err := openBrowser(a.ctx, rawURL); err != nil {
+	if strings.HasPrefix(strings.ToLower(rawURL), urischeme.InternalPrefix) {
+		a.openInternal(rawURL)
+		return
+	}
+	err := a.host.OpenURL(rawURL)
+	var unknown *urischeme.UnknownDesktopError
+	switch {
+	case errors.As(err, &unknown):
+		deck.Warningf("no settings app for desktop %q: %v", unknown.Desktop, err)
+		msg := a.cfg.Strings["settings"]
+		if title := unknown.PanelTitle(); title != "" {
+			msg = strings.Replace(a.cfg.Strings["settings_panel"], "%s", title, 1)
+		}
+		a.host.Emit(NoticeEvent, msg)
+	case err != nil:
 		deck.Errorf("open browser: %v", err)
 	}
 }
@@ -169,13 +379,11 @@ func (a *App) GetCheckState() map[string]bool {
 	return out
 }
 
-// Keys are positional ("pageIndex:checkIndex"). Reordering or inserting
-// checkboxes in markdown shifts existing mappings. Acceptable for onboarding
-// content that rarely changes after deployment.
-var validCheckKey = regexp.MustCompile(`^\d+:\d+$`)
-
+// Keys are "pageID:itemID", so saved state follows a checklist item when
+// pages are reordered or other items are added. Only keys of items in the
+// loaded pages are accepted.
 func (a *App) ToggleCheckItem(key string) bool {
-	if !validCheckKey.MatchString(key) {
+	if !a.checkKeys[key] {
 		deck.Warningf("invalid check key: %q", key)
 		return false
 	}
@@ -186,29 +394,56 @@ func (a *App) ToggleCheckItem(key string) bool {
 	return a.checkState[key]
 }
 
-// openBrowser uses rundll32 on WSL to avoid cmd.exe metacharacter injection.
-func openBrowser(ctx context.Context, rawURL string) error {
-	if runtime.GOOS == "linux" && isWSL() {
-		return exec.Command("rundll32.exe", "url.dll,FileProtocolHandler", rawURL).Start()
-	}
-	wailsRuntime.BrowserOpenURL(ctx, rawURL)
-	return nil
+// PageChecklist is the checklist completion of one page.
+type PageChecklist struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
 }
 
-var (
-	wslOnce   sync.Once
-	wslCached bool
-)
+// ChecklistStatus returns checklist completion for every loaded page,
+// including pages without a checklist.
+func (a *App) ChecklistStatus() []PageChecklist {
+	a.checkMu.Lock()
+	defer a.checkMu.Unlock()
+	out := make([]PageChecklist, len(a.pages))
+	for i, p := range a.pages {
+		out[i] = PageChecklist{ID: p.ID(), Title: p.Frontmatter.Title}
+		for _, item := range pages.Checklist(p.Markdown, pages.WithFacts(a.cfg.Facts)) {
+			out[i].Total++
+			if a.checkState[CheckKey(p.ID(), item.ID)] {
+				out[i].Done++
+			}
+		}
+	}
+	return out
+}
 
-func isWSL() bool {
-	wslOnce.Do(func() {
-		data, err := os.ReadFile("/proc/version")
-		if err != nil {
-			return
+// ResetCheckState clears saved checklist state. With no pages the whole
+// file is removed; otherwise only the items of the given pages are cleared.
+func ResetCheckState(loaded []pages.Page) error {
+	p := checklistPath()
+	if p == "" {
+		return nil
+	}
+	if len(loaded) == 0 {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	state := loadCheckState()
+	for _, pg := range loaded {
+		prefix := CheckKey(pg.ID(), "")
+		for key := range state {
+			if strings.HasPrefix(key, prefix) {
+				delete(state, key)
+			}
 		}
-		wslCached = strings.Contains(strings.ToLower(string(data)), "microsoft")
-	})
-	return wslCached
+	}
+	saveCheckState(state)
+	return nil
 }
 
 func wslDarkMode() bool {
@@ -223,12 +458,67 @@ func wslDarkMode() bool {
 
 const checklistFile = "checklist.json"
 
-func checklistPath() string {
+// CheckKey returns the ToggleCheckItem key of a checklist item.
+func CheckKey(pageID, itemID string) string { return pageID + ":" + itemID }
+
+// positionalKey matches keys saved before checklist items had stable IDs.
+var positionalKey = regexp.MustCompile(`^(\d+):(\d+)$`)
+
+// MigrateCheckState rewrites checklist state saved with positional keys
+// to stable item IDs and saves it. all must be every page the content
+// shows on this machine, in order, rather than the pages of a re-run,
+// since positions refer to the full list. New never writes state, so
+// call this once before it.
+func MigrateCheckState(all []pages.Page, f facts.Facts) {
+	state := loadCheckState()
+	checklists := make([][]pages.ChecklistItem, len(all))
+	for i, p := range all {
+		checklists[i] = pages.Checklist(p.Markdown, pages.WithFacts(f))
+	}
+	if migrateCheckState(state, all, checklists) {
+		saveCheckState(state)
+	}
+}
+
+// migrateCheckState rewrites positional "pageIndex:checkIndex" keys to
+// "pageID:itemID" by matching them against the current page order. Keys
+// that no longer map to an item are dropped. Reports whether state changed.
+func migrateCheckState(state map[string]bool, loaded []pages.Page, checklists [][]pages.ChecklistItem) bool {
+	changed := false
+	for key, checked := range state {
+		m := positionalKey.FindStringSubmatch(key)
+		if m == nil {
+			continue
+		}
+		delete(state, key)
+		changed = true
+
+		pi, _ := strconv.Atoi(m[1])
+		ci, _ := strconv.Atoi(m[2])
+		if pi >= len(loaded) || ci >= len(checklists[pi]) {
+			continue
+		}
+		newKey := CheckKey(loaded[pi].ID(), checklists[pi][ci].ID)
+		if _, exists := state[newKey]; !exists {
+			state[newKey] = checked
+		}
+	}
+	if changed {
+		deck.Info("migrated positional checklist state to stable item IDs")
+	}
+	return changed
+}
+
+func checklistPath() string { return statePath(checklistFile) }
+
+// statePath returns the path of a state file next to the sentinel, or ""
+// if the config dir can't be determined.
+func statePath(name string) string {
 	dir, err := marker.Dir()
 	if err != nil {
 		return ""
 	}
-	return filepath.Join(dir, checklistFile)
+	return filepath.Join(dir, name)
 }
 
 func loadCheckState() map[string]bool {
@@ -249,26 +539,30 @@ func loadCheckState() map[string]bool {
 }
 
 func saveCheckState(state map[string]bool) {
-	p := checklistPath()
+	writeStateFile(checklistPath(), state)
+}
+
+// writeStateFile marshals v to p atomically via a temp file and rename.
+func writeStateFile(p string, v any) {
 	if p == "" {
 		return
 	}
-	dir := filepath.Dir(p)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		deck.Errorf("mkdir for checklist: %v", err)
+	name := filepath.Base(p)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		deck.Errorf("mkdir for %s: %v", name, err)
 		return
 	}
-	data, err := json.Marshal(state)
+	data, err := json.Marshal(v)
 	if err != nil {
-		deck.Errorf("marshal checklist: %v", err)
+		deck.Errorf("marshal %s: %v", name, err)
 		return
 	}
 	tmp := p + ".tmp"
 	if err := os.WriteFile(tmp, data, 0o600); err != nil {
-		deck.Errorf("write checklist tmp: %v", err)
+		deck.Errorf("write %s tmp: %v", name, err)
 		return
 	}
 	if err := os.Rename(tmp, p); err != nil {
-		deck.Errorf("rename checklist: %v", err)
+		deck.Errorf("rename %s: %v", name, err)
 	}
 }
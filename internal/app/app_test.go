@@ -2,18 +2,22 @@ package app
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/TsekNet/day1/internal/facts"
 	"github.com/TsekNet/day1/internal/pages"
 )
 
 func testPages(n int) []pages.Page {
 	pp := make([]pages.Page, n)
 	for i := range pp {
+		letter := string(rune('a' + i))
 		pp[i] = pages.Page{
-			Frontmatter: pages.Frontmatter{Title: "Page " + string(rune('A'+i))},
-			Markdown:    "# Page " + string(rune('A'+i)),
-			SourceFile:  "page.md",
+			Frontmatter: pages.Frontmatter{Title: "Page " + strings.ToUpper(letter)},
+			Markdown:    "# Page " + strings.ToUpper(letter) + "\n\n- [ ] First {#first}\n- [ ] Second {#second}\n",
+			SourceFile:  "page-" + letter + ".md",
 		}
 	}
 	return pp
@@ -82,6 +86,47 @@ func TestGetHelpURL(t *testing.T) {
 	}
 }
 
+func TestGetStrings(t *testing.T) {
+	t.Parallel()
+	a := testApp(1, Config{})
+	if got := a.GetLocale(); got != "en" {
+		t.Errorf("GetLocale() = %q, want en", got)
+	}
+	if got := a.GetStrings()["next"]; got != "Next" {
+		t.Errorf("GetStrings()[next] = %q, want Next", got)
+	}
+	a = testApp(1, Config{Locale: "de", Strings: map[string]string{"next": "Los"}})
+	if got := a.GetStrings()["next"]; got != "Los" {
+		t.Errorf("GetStrings()[next] = %q, want Los", got)
+	}
+}
+
+func TestGetDir(t *testing.T) {
+	t.Parallel()
+	if got := testApp(1, Config{}).GetDir(); got != "ltr" {
+		t.Errorf("GetDir() = %q, want ltr", got)
+	}
+
+	pp := testPages(3)
+	pp[0].Locale = "he"
+	pp[1].Frontmatter.Dir = "auto"
+	pp[2].Markdown = "Install שלום"
+	a := New(pp, Config{Locale: "he"})
+	if got := a.GetDir(); got != "rtl" {
+		t.Errorf("GetDir() = %q, want rtl", got)
+	}
+	var got []string
+	for _, p := range a.GetPages() {
+		got = append(got, p.Dir)
+	}
+	if strings.Join(got, " ") != "rtl auto ltr" {
+		t.Errorf("GetPages() dirs = %q, want [rtl auto ltr]", got)
+	}
+	if html := a.GetPageHTML(2); !strings.Contains(html, `<p dir="auto">`) {
+		t.Errorf("GetPageHTML(2) = %q, want a dir=\"auto\" paragraph", html)
+	}
+}
+
 func TestOpenURLBlocked(t *testing.T) {
 	a := testApp(1, Config{})
 	blocked := []string{
@@ -118,12 +163,12 @@ func TestGetCheckStateEmpty(t *testing.T) {
 func TestGetCheckStateReturnsCopy(t *testing.T) {
 	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 	a := testApp(1, Config{})
-	a.ToggleCheckItem("0:0")
+	a.ToggleCheckItem("page-a:first")
 
 	m := a.GetCheckState()
-	m["0:0"] = false
+	m["page-a:first"] = false
 
-	if !a.GetCheckState()["0:0"] {
+	if !a.GetCheckState()["page-a:first"] {
 		t.Error("mutating returned map should not affect internal state")
 	}
 }
@@ -132,10 +177,10 @@ func TestToggleCheckItem(t *testing.T) {
 	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
 	a := testApp(1, Config{})
 
-	if got := a.ToggleCheckItem("0:0"); !got {
+	if got := a.ToggleCheckItem("page-a:first"); !got {
 		t.Error("first toggle: want true, got false")
 	}
-	if got := a.ToggleCheckItem("0:0"); got {
+	if got := a.ToggleCheckItem("page-a:first"); got {
 		t.Error("second toggle: want false, got true")
 	}
 }
@@ -145,11 +190,11 @@ func TestToggleCheckItemPersistence(t *testing.T) {
 	t.Setenv("XDG_CONFIG_HOME", dir)
 
 	a := testApp(1, Config{})
-	a.ToggleCheckItem("0:0")
+	a.ToggleCheckItem("page-a:first")
 
 	os.Setenv("XDG_CONFIG_HOME", dir)
 	a2 := testApp(1, Config{})
-	if !a2.GetCheckState()["0:0"] {
+	if !a2.GetCheckState()["page-a:first"] {
 		t.Error("state not persisted across app restarts")
 	}
 }
@@ -162,7 +207,165 @@ func TestToggleCheckItemInvalidKey(t *testing.T) {
 	if got := a.ToggleCheckItem(""); got {
 		t.Error("empty key should return false")
 	}
-	if got := a.ToggleCheckItem("abc:def"); got {
-		t.Error("non-numeric key should return false")
+	if got := a.ToggleCheckItem("0:0"); got {
+		t.Error("positional key should return false")
+	}
+	if got := a.ToggleCheckItem("page-a:missing"); got {
+		t.Error("unknown item should return false")
+	}
+}
+
+func TestCheckKeysSurviveReordering(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	a := testApp(2, Config{})
+	a.ToggleCheckItem("page-b:second")
+
+	pp := testPages(2)
+	pp[0], pp[1] = pp[1], pp[0]
+	pp[0].Markdown = "# Page B\n\n- [ ] New item\n- [ ] First {#first}\n- [ ] Second {#second}\n"
+	a2 := New(pp, Config{})
+
+	state := a2.GetCheckState()
+	if !state["page-b:second"] {
+		t.Errorf("state after reorder = %v, want page-b:second checked", state)
+	}
+}
+
+func TestMigratePositionalCheckState(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	path := filepath.Join(dir, "day1", checklistFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := `{"0:1":true,"1:0":true,"1:0x":true,"7:0":true}`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	MigrateCheckState(testPages(2), facts.Current())
+	a := testApp(2, Config{})
+	want := map[string]bool{"page-a:second": true, "page-b:first": true, "1:0x": true}
+	got := a.GetCheckState()
+	if len(got) != len(want) {
+		t.Fatalf("state = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("state[%q] = %v, want %v", k, got[k], v)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"0:1"`) {
+		t.Errorf("migrated state not saved: %s", data)
+	}
+}
+
+func TestNewDoesNotMigrateSubset(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+
+	path := filepath.Join(dir, "day1", checklistFile)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := `{"0:1":true}`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A re-run shows only page-b; "0:1" still means page-a's second item.
+	all := testPages(2)
+	New(all[1:], Config{})
+	if data, err := os.ReadFile(path); err != nil || string(data) != legacy {
+		t.Fatalf("New rewrote saved state to %s (%v)", data, err)
+	}
+
+	MigrateCheckState(all, facts.Current())
+	state := New(all[1:], Config{}).GetCheckState()
+	if !state["page-a:second"] || state["page-b:second"] || len(state) != 1 {
+		t.Errorf("state = %v, want only page-a:second", state)
+	}
+}
+
+func TestProgressResumesByPageID(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	a := testApp(3, Config{})
+	if got := a.GetProgress(); got.Index != 0 || got.PageID != "" || len(got.Visited) != 0 {
+		t.Fatalf("fresh progress = %+v, want zero", got)
+	}
+	a.SetCurrentPage(0)
+	a.SetCurrentPage(2)
+	a.SetCurrentPage(2)
+	a.SetCurrentPage(9)
+
+	// Drop page-a and reorder: page-c is now first.
+	pp := testPages(3)
+	pp = []pages.Page{pp[2], pp[1]}
+	got := New(pp, Config{}).GetProgress()
+	if got.Index != 0 || got.PageID != "page-c" {
+		t.Errorf("resumed at %d (%q), want 0 (page-c)", got.Index, got.PageID)
+	}
+	if len(got.Visited) != 1 || got.Visited[0] != "page-c" {
+		t.Errorf("Visited = %v, want [page-c]", got.Visited)
+	}
+
+	got = testApp(3, Config{}).GetProgress()
+	if got.Index != 2 || len(got.Visited) != 2 {
+		t.Errorf("original order: got %+v, want index 2 with 2 visited", got)
+	}
+}
+
+func TestProgressRemovedPage(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	testApp(3, Config{}).SetCurrentPage(2)
+	if got := testApp(2, Config{}).GetProgress(); got.Index != 0 || got.PageID != "" {
+		t.Errorf("progress on removed page = %+v, want first page", got)
+	}
+}
+
+func TestResetProgress(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	if err := ResetProgress(); err != nil {
+		t.Fatalf("ResetProgress without saved progress: %v", err)
+	}
+	testApp(3, Config{}).SetCurrentPage(1)
+	if err := ResetProgress(); err != nil {
+		t.Fatalf("ResetProgress: %v", err)
+	}
+	if got := testApp(3, Config{}).GetProgress(); got.Index != 0 || len(got.Visited) != 0 {
+		t.Errorf("progress after reset = %+v, want zero", got)
+	}
+}
+
+func TestResetCheckStateScoped(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	a := testApp(2, Config{})
+	a.ToggleCheckItem("page-a:first")
+	a.ToggleCheckItem("page-b:first")
+
+	if err := ResetCheckState(testPages(1)); err != nil {
+		t.Fatalf("ResetCheckState: %v", err)
+	}
+	got := testApp(2, Config{}).ChecklistStatus()
+	if got[0].Done != 0 || got[1].Done != 1 || got[1].Total != 2 {
+		t.Errorf("after scoped reset: %+v, want page-a 0/2, page-b 1/2", got)
+	}
+
+	if err := ResetCheckState(nil); err != nil {
+		t.Fatalf("ResetCheckState(nil): %v", err)
+	}
+	if state := testApp(2, Config{}).GetCheckState(); len(state) != 0 {
+		t.Errorf("after full reset: %v, want empty", state)
 	}
 }
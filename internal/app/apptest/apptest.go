@@ -0,0 +1,286 @@
+// Package apptest provides an in-memory app.Host and a driver that walks
+// an app.App through the wizard the way the frontend does, so completion,
+// quitting and link clicks can be tested without a window.
+package apptest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/TsekNet/day1/internal/app"
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/marker"
+	"github.com/TsekNet/day1/internal/pages"
+)
+
+// Event is a call to Host.Emit.
+type Event struct {
+	Name string
+	Data []any
+}
+
+// Host is an app.Host that records every call.
+type Host struct {
+	mu        sync.Mutex
+	shown     bool
+	centered  bool
+	quit      bool
+	urls      []string
+	events    []Event
+	clipboard string
+
+	// OpenErr is returned from OpenURL when set.
+	OpenErr error
+}
+
+var _ app.Host = (*Host)(nil)
+
+func (h *Host) WindowShow() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.shown = true
+}
+
+func (h *Host) WindowCenter() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.centered = true
+}
+
+func (h *Host) Quit() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.quit = true
+}
+
+func (h *Host) OpenURL(rawURL string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.OpenErr != nil {
+		return h.OpenErr
+	}
+	h.urls = append(h.urls, rawURL)
+	return nil
+}
+
+func (h *Host) Emit(event string, data ...any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.events = append(h.events, Event{Name: event, Data: data})
+}
+
+func (h *Host) ClipboardSetText(text string) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clipboard = text
+	return nil
+}
+
+// Shown reports whether the window was shown and centered.
+func (h *Host) Shown() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.shown && h.centered
+}
+
+// Quitted reports whether the app asked to quit.
+func (h *Host) Quitted() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.quit
+}
+
+// URLs returns the URLs opened so far, in order.
+func (h *Host) URLs() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.urls...)
+}
+
+// Events returns the events emitted so far, in order.
+func (h *Host) Events() []Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Event(nil), h.events...)
+}
+
+// Clipboard returns the last text copied to the clipboard.
+func (h *Host) Clipboard() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.clipboard
+}
+
+// Driver steps through an App like the frontend: it calls Ready, resumes
+// on the saved page, records each page shown as the current page and
+// completes from the final page.
+type Driver struct {
+	t     testing.TB
+	App   *app.App
+	Host  *Host
+	Pages []app.PageInfo
+	pages []pages.Page
+	facts facts.Facts
+	index int
+	final bool
+}
+
+// Load loads the pages in dir for platform and starts a Driver on them.
+// Saved state goes to a temporary config directory.
+func Load(t testing.TB, dir, platform string) *Driver {
+	t.Helper()
+	fsys := os.DirFS(dir)
+	cfg, err := pages.LoadConfig(fsys)
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	loaded, err := pages.LoadForPlatform(fsys, platform)
+	if err != nil {
+		t.Fatalf("load pages: %v", err)
+	}
+	if len(loaded) == 0 {
+		t.Fatalf("no %s pages in %s", platform, dir)
+	}
+	links, err := cfg.LinkPolicy()
+	if err != nil {
+		t.Fatalf("links: %v", err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	return New(t, loaded, app.Config{HelpURL: cfg.HelpURL, ContentVersion: cfg.ContentVersion, Links: links, Actions: cfg.Actions, Facts: facts.Facts{OS: platform}})
+}
+
+// New starts a Driver on an App for loaded. cfg.Host is replaced by a
+// fresh fake Host, and cfg.Facts defaults to this machine as in app.New.
+func New(t testing.TB, loaded []pages.Page, cfg app.Config) *Driver {
+	t.Helper()
+	h := &Host{}
+	cfg.Host = h
+	if cfg.Facts.OS == "" {
+		cfg.Facts = facts.Current()
+	}
+	a := app.New(loaded, cfg)
+	a.Ready()
+	d := &Driver{t: t, App: a, Host: h, Pages: a.GetPages(), pages: loaded, facts: cfg.Facts}
+	d.show(a.GetProgress().Index)
+	return d
+}
+
+func (d *Driver) show(i int) {
+	d.index, d.final = i, false
+	d.App.SetCurrentPage(i)
+	if d.App.GetPageHTML(i) == "" {
+		d.t.Errorf("page %d (%s) rendered empty", i, d.Pages[i].ID)
+	}
+}
+
+// Page returns the page being shown.
+func (d *Driver) Page() app.PageInfo { return d.Pages[d.index] }
+
+// Final reports whether the final page is shown.
+func (d *Driver) Final() bool { return d.final }
+
+// Next advances to the next page, or to the final page from the last one.
+// Like the frontend, it stays put while a page up to the current one has
+// unchecked required items, and reports whether it moved.
+func (d *Driver) Next() bool {
+	d.t.Helper()
+	if first := d.App.FirstIncomplete(); first >= 0 && first <= d.index {
+		return false
+	}
+	switch {
+	case d.final:
+		d.t.Fatal("Next on the final page")
+	case d.index == len(d.Pages)-1:
+		d.final = true
+		d.App.GetFinalHTML()
+	default:
+		d.show(d.index + 1)
+	}
+	return true
+}
+
+// Back returns to the previous page.
+func (d *Driver) Back() {
+	switch {
+	case d.final:
+		d.show(d.index)
+	case d.index > 0:
+		d.show(d.index - 1)
+	}
+}
+
+// Items returns the checklist items of the current page.
+func (d *Driver) Items() []pages.ChecklistItem {
+	return pages.Checklist(d.pages[d.index].Markdown, pages.WithFacts(d.facts))
+}
+
+// Check toggles checklist item itemID on the current page and fails the
+// test if the key is rejected.
+func (d *Driver) Check(itemID string) {
+	d.t.Helper()
+	key := app.CheckKey(d.Page().ID, itemID)
+	before := d.App.GetCheckState()[key]
+	if d.App.ToggleCheckItem(key) == before {
+		d.t.Fatalf("toggle %s was rejected", key)
+	}
+}
+
+// Click opens rawURL as a link on the current page would, and follows
+// the page change when it is a day1: link to another page.
+func (d *Driver) Click(rawURL string) {
+	seen := len(d.Host.Events())
+	d.App.OpenURL(rawURL)
+	for _, e := range d.Host.Events()[seen:] {
+		if e.Name != app.NavigateEvent {
+			continue
+		}
+		if i := e.Data[0].(int); i == app.FinalIndex {
+			d.final = true
+			d.App.GetFinalHTML()
+		} else {
+			d.show(i)
+		}
+	}
+}
+
+// Finish advances through the remaining pages and completes the wizard.
+// When required items stop it on the way, it completes from there, which
+// the app refuses.
+func (d *Driver) Finish() {
+	d.t.Helper()
+	for !d.final && d.Next() {
+	}
+	d.App.Complete()
+}
+
+// Completed returns the sentinel state and fails the test when it is
+// missing.
+func (d *Driver) Completed() marker.State {
+	d.t.Helper()
+	st, done, err := marker.Read()
+	if err != nil || !done {
+		d.t.Fatalf("sentinel: done=%v err=%v", done, err)
+	}
+	return st
+}
+
+// SavedChecklist reads checklist.json as the next launch would.
+func (d *Driver) SavedChecklist() map[string]bool {
+	d.t.Helper()
+	dir, err := marker.Dir()
+	if err != nil {
+		d.t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "checklist.json"))
+	if err != nil {
+		d.t.Fatalf("read checklist: %v", err)
+	}
+	var state map[string]bool
+	if err := json.Unmarshal(data, &state); err != nil {
+		d.t.Fatalf("parse checklist: %v", err)
+	}
+	return state
+}
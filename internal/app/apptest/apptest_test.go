@@ -0,0 +1,312 @@
+package apptest
+
+import (
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TsekNet/day1/internal/app"
+	"github.com/TsekNet/day1/internal/marker"
+	"github.com/TsekNet/day1/internal/urischeme"
+)
+
+const demoPages = "../../../testdata/pages"
+
+func TestWalkToComplete(t *testing.T) {
+	d := Load(t, demoPages, "linux")
+	if !d.Host.Shown() {
+		t.Error("Ready did not show and center the window")
+	}
+	if got := d.Page().ID; got != "welcome" {
+		t.Fatalf("first page = %q, want welcome", got)
+	}
+
+	d.Next()
+	items := d.Items()
+	if len(items) == 0 {
+		t.Fatalf("page %s has no checklist", d.Page().ID)
+	}
+	for _, item := range items {
+		d.Check(item.ID)
+	}
+	d.Click("https://outlook.office.com")
+	d.Click("javascript:alert(1)")
+	d.App.OpenHelp()
+	d.Finish()
+
+	if !d.Host.Quitted() {
+		t.Error("Complete did not quit")
+	}
+	if st := d.Completed(); len(st.Pages) != len(d.Pages) {
+		t.Errorf("sentinel records %d pages, want %d", len(st.Pages), len(d.Pages))
+	}
+	saved := d.SavedChecklist()
+	for _, item := range items {
+		if key := "getting-started:" + item.ID; !saved[key] {
+			t.Errorf("checklist.json missing %s: %v", key, saved)
+		}
+	}
+	want := []string{"https://outlook.office.com", "https://wiki.example.com/onboarding"}
+	if got := d.Host.URLs(); !slices.Equal(got, want) {
+		t.Errorf("opened URLs = %v, want %v", got, want)
+	}
+}
+
+func TestDismissResumes(t *testing.T) {
+	d := Load(t, demoPages, "linux")
+	d.Next()
+	d.Next()
+	page := d.Page().ID
+	d.App.Dismiss()
+
+	if !d.Host.Quitted() {
+		t.Error("Dismiss did not quit")
+	}
+	if done, _ := marker.Exists(); done {
+		t.Error("Dismiss wrote the sentinel")
+	}
+
+	// Same config dir: the next launch reopens where the user left off.
+	next := New(t, d.pages, app.Config{})
+	if got := next.Page().ID; got != page {
+		t.Errorf("resumed on %q, want %q", got, page)
+	}
+	next.Back()
+	next.Finish()
+	next.Completed()
+}
+
+func TestOpenURLError(t *testing.T) {
+	d := Load(t, demoPages, "linux")
+	d.Host.OpenErr = errors.New("no browser")
+	d.Click("https://example.com")
+	if got := d.Host.URLs(); len(got) != 0 {
+		t.Errorf("URLs = %v, want none after a failed open", got)
+	}
+}
+
+func TestUnknownDesktopNotice(t *testing.T) {
+	tests := []struct {
+		name   string
+		locale string
+		panel  string
+		want   string
+	}{
+		{"panel", "", "network", "Open your system settings and go to Network."},
+		{"wifi", "", "wifi", "Open your system settings and go to Wi-Fi."},
+		{"settings app", "", "", "Open your system settings."},
+		{"translated", "de", "network", "Öffnen Sie Ihre Systemeinstellungen und gehen Sie zu Network."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Load(t, demoPages, "linux")
+			d = New(t, d.pages, app.Config{Locale: tt.locale, Facts: d.facts})
+			d.Host.OpenErr = &urischeme.UnknownDesktopError{Panel: tt.panel}
+			d.Click("x-linux-settings:" + tt.panel)
+			events := d.Host.Events()
+			if len(events) != 1 || events[0].Name != app.NoticeEvent {
+				t.Fatalf("events = %v, want one %s event", events, app.NoticeEvent)
+			}
+			if msg := events[0].Data[0]; msg != tt.want {
+				t.Errorf("notice = %q, want %q", msg, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfiguredLinks(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"day1.yml": "links:\n  schemes:\n    - scheme: slack\n  allow_hosts: [\"*.corp.example\"]\n",
+		"a.md":     "# A\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := Load(t, dir, "linux")
+	d.Click("slack://open")
+	d.Click("https://wiki.corp.example/kb")
+	d.Click("https://example.com")
+	d.Click("zoommtg://zoom.us/join")
+
+	want := []string{"slack://open", "https://wiki.corp.example/kb"}
+	if got := d.Host.URLs(); !slices.Equal(got, want) {
+		t.Errorf("opened URLs = %v, want %v", got, want)
+	}
+}
+
+func TestInternalLinks(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"day1.yml": "pages: [a.md, b.md]\nactions:\n  wifi:\n    copy: CorpWiFi\n  portal:\n    url: https://portal.example.com\n  loop:\n    url: day1:action/wifi\n",
+		"a.md":     "# A\n\nSee [B](b.md#vpn).\n\n- [ ] One {#one}\n",
+		"b.md":     "# B\n\n- [ ] Two {#two}\n- [ ] Three {#three}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := Load(t, dir, "linux")
+	if html := d.App.GetPageHTML(0); !strings.Contains(html, `href="day1:page/b#vpn"`) {
+		t.Errorf("relative link not rewritten:\n%s", html)
+	}
+
+	d.Click("day1:page/b")
+	if d.Page().ID != "b" {
+		t.Fatalf("page = %s, want b", d.Page().ID)
+	}
+	d.Click("day1:check/a/one")
+	d.Click("day1:check/all")
+	d.Click("day1:check/missing")
+	want := map[string]bool{"a:one": true, "b:two": true, "b:three": true}
+	if got := d.SavedChecklist(); !maps.Equal(got, want) {
+		t.Errorf("checklist = %v, want %v", got, want)
+	}
+
+	d.Click("day1:action/loop")
+	if got := d.Host.Clipboard(); got != "" {
+		t.Errorf("action ran another action: clipboard = %q", got)
+	}
+	d.Click("day1:action/wifi")
+	if got := d.Host.Clipboard(); got != "CorpWiFi" {
+		t.Errorf("clipboard = %q, want CorpWiFi", got)
+	}
+	if events := d.Host.Events(); events[len(events)-1].Name != app.NoticeEvent || events[len(events)-1].Data[0] != "Copied to clipboard." {
+		t.Errorf("last event = %+v, want the copied notice", events[len(events)-1])
+	}
+	d.Click("day1:action/portal")
+	if got := d.Host.URLs(); !slices.Equal(got, []string{"https://portal.example.com"}) {
+		t.Errorf("URLs = %v", got)
+	}
+
+	d.Click("day1:final")
+	if !d.Final() {
+		t.Error("day1:final did not show the final page")
+	}
+}
+
+func TestMandatory(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"day1.yml":    "mode: mandatory\npages: [policy.md, training.md, done.md]\n",
+		"policy.md":   "# Policy\n\n- [ ] Read the policy {#aup required}\n- [ ] Say hi {#hi}\n",
+		"training.md": "---\nrequired: true\n---\n# Training\n\n- [ ] Take the course {#course}\n",
+		"done.md":     "# Done\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	d := Load(t, dir, "linux")
+	d = New(t, d.pages, app.Config{Mandatory: true, Facts: d.facts})
+	if got := d.Pages[0].Required; !slices.Equal(got, []string{"policy:aup"}) {
+		t.Errorf("policy required = %v, want [policy:aup]", got)
+	}
+	if got := d.Pages[1].Required; !slices.Equal(got, []string{"training:course"}) {
+		t.Errorf("training required = %v, want [training:course]", got)
+	}
+
+	d.App.Dismiss()
+	if d.Host.Quitted() {
+		t.Fatal("mandatory wizard was dismissed")
+	}
+	if !d.App.BeforeClose(t.Context()) {
+		t.Error("BeforeClose let a mandatory wizard close")
+	}
+	if d.Next() {
+		t.Error("Next moved past unchecked required items")
+	}
+	d.Click("day1:page/done")
+	d.Click("day1:final")
+	if d.Page().ID != "policy" || d.Final() {
+		t.Errorf("links moved past unchecked required items to %s (final %v)", d.Page().ID, d.Final())
+	}
+
+	d.Check("aup")
+	if !d.Next() || d.Page().ID != "training" {
+		t.Fatalf("Next after checking the policy = %s, want training", d.Page().ID)
+	}
+	d.Finish()
+	if d.Host.Quitted() {
+		t.Fatal("Complete quit with unchecked required items")
+	}
+	if done, _ := marker.Exists(); done {
+		t.Fatal("Complete wrote the sentinel with unchecked required items")
+	}
+	if err := d.App.MarkComplete(); !errors.Is(err, app.ErrRequired) {
+		t.Errorf("MarkComplete = %v, want ErrRequired", err)
+	}
+	events := d.Host.Events()
+	if last := events[len(events)-2]; last.Name != app.NavigateEvent || last.Data[0] != 1 {
+		t.Errorf("refused Complete emitted %+v, want navigate to page 1", last)
+	}
+
+	d.Check("course")
+	d.Finish()
+	if !d.Host.Quitted() {
+		t.Error("Complete did not quit")
+	}
+	d.Completed()
+	if d.App.BeforeClose(t.Context()) {
+		t.Error("BeforeClose kept a completed wizard open")
+	}
+}
+
+func TestSnoozeEscalates(t *testing.T) {
+	d := Load(t, demoPages, "linux")
+	cfg := app.Config{SnoozeFor: time.Hour, MaxSnoozes: 2, Facts: d.facts}
+	d = New(t, d.pages, cfg)
+	if !d.App.CanSnooze() {
+		t.Fatal("CanSnooze = false, want true")
+	}
+	before := time.Now()
+	d.App.Snooze()
+	if !d.Host.Quitted() {
+		t.Error("Snooze did not quit")
+	}
+	sn := app.LoadSnooze()
+	if !sn.Snoozed(before) || sn.Snoozed(before.Add(2*time.Hour)) {
+		t.Errorf("snoozed until %v, want about an hour from %v", sn.Until(), before)
+	}
+
+	// The second launch is closed; closing ends the snooze but counts.
+	d = New(t, d.pages, cfg)
+	d.App.Dismiss()
+	if !d.Host.Quitted() {
+		t.Error("Dismiss did not quit")
+	}
+	sn = app.LoadSnooze()
+	if len(sn.Dismissals) != 2 || sn.Snoozed(time.Now()) {
+		t.Errorf("after closing: %+v, want 2 dismissals and no snooze", sn)
+	}
+
+	// Put off twice, the wizard is mandatory.
+	d = New(t, d.pages, cfg)
+	if !d.App.GetMandatory() || d.App.CanSnooze() {
+		t.Fatalf("GetMandatory, CanSnooze = %v, %v after 2 dismissals; want true, false", d.App.GetMandatory(), d.App.CanSnooze())
+	}
+	d.App.Snooze()
+	d.App.Dismiss()
+	if d.Host.Quitted() {
+		t.Fatal("mandatory wizard was put off")
+	}
+	if _, err := d.App.MarkSnoozed(); !errors.Is(err, app.ErrMandatory) {
+		t.Errorf("MarkSnoozed = %v, want ErrMandatory", err)
+	}
+
+	// Completing clears the history, so a later re-run starts over.
+	d.Finish()
+	d.Completed()
+	if sn := app.LoadSnooze(); len(sn.Dismissals) != 0 {
+		t.Errorf("after completing: %+v, want no dismissals", sn)
+	}
+}
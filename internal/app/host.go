@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/TsekNet/day1/internal/platform"
+	"github.com/TsekNet/day1/internal/urischeme"
+	wailsRuntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// Host is the window system App runs in. The default is the Wails runtime;
+// tests use the in-memory fake in internal/app/apptest.
+type Host interface {
+	WindowShow()
+	WindowCenter()
+	Quit()
+	OpenURL(rawURL string) error
+	Emit(event string, data ...any)
+	ClipboardSetText(text string) error
+}
+
+// NoticeEvent is emitted with a message for the user when a link can't be
+// opened the way it asks, e.g. a Linux settings panel on an unknown desktop.
+const NoticeEvent = "notice"
+
+// wailsHost forwards to the Wails runtime. Its context is only valid once
+// Wails calls App.Startup.
+type wailsHost struct {
+	ctx context.Context
+}
+
+func (h *wailsHost) WindowShow()   { wailsRuntime.WindowShow(h.ctx) }
+func (h *wailsHost) WindowCenter() { wailsRuntime.WindowCenter(h.ctx) }
+func (h *wailsHost) Quit()         { wailsRuntime.Quit(h.ctx) }
+
+// OpenURL uses rundll32 on WSL to avoid cmd.exe metacharacter injection.
+// Linux settings links start the desktop's settings app directly.
+func (h *wailsHost) OpenURL(rawURL string) error {
+	if strings.HasPrefix(strings.ToLower(rawURL), urischeme.LinuxSettingsPrefix) {
+		argv, err := urischeme.LinuxSettingsCommand(rawURL, os.Getenv("XDG_CURRENT_DESKTOP"))
+		if err != nil {
+			return err
+		}
+		return exec.Command(argv[0], argv[1:]...).Start()
+	}
+	if platform.IsWSL() {
+		return exec.Command("rundll32.exe", "url.dll,FileProtocolHandler", rawURL).Start()
+	}
+	wailsRuntime.BrowserOpenURL(h.ctx, rawURL)
+	return nil
+}
+
+func (h *wailsHost) Emit(event string, data ...any) {
+	wailsRuntime.EventsEmit(h.ctx, event, data...)
+}
+
+func (h *wailsHost) ClipboardSetText(text string) error {
+	return wailsRuntime.ClipboardSetText(h.ctx, text)
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"github.com/TsekNet/day1/internal/pages"
+	"github.com/TsekNet/day1/internal/urischeme"
+	"github.com/google/deck"
+)
+
+// Events emitted when a day1: link changes what the wizard shows.
+const (
+	// NavigateEvent carries the index of the page to show, or FinalIndex.
+	NavigateEvent = "navigate"
+	// CheckEvent carries the checklist state as GetCheckState returns it.
+	CheckEvent = "check"
+)
+
+// FinalIndex is the NavigateEvent index of the final page.
+const FinalIndex = -1
+
+// openInternal handles a day1: link without leaving the app.
+func (a *App) openInternal(rawURL string) {
+	link, err := urischeme.ParseInternal(rawURL)
+	if err != nil {
+		deck.Warningf("day1 link %q: %v", rawURL, err)
+		return
+	}
+	switch link.Op {
+	case urischeme.OpPage:
+		i := a.pageIndex(link.Page)
+		if i < 0 {
+			deck.Warningf("day1 link %q: no page %q", rawURL, link.Page)
+			return
+		}
+		if a.blocks(i) {
+			return
+		}
+		a.host.Emit(NavigateEvent, i)
+	case urischeme.OpFinal:
+		if a.blocks(len(a.pages)) {
+			return
+		}
+		a.host.Emit(NavigateEvent, FinalIndex)
+	case urischeme.OpCheck:
+		a.checkLink(rawURL, link)
+	case urischeme.OpAction:
+		a.runAction(link.Name)
+	}
+}
+
+// pageIndex returns the index of the page id names, the current page when
+// id is empty, or -1.
+func (a *App) pageIndex(id string) int {
+	if id == "" {
+		return a.GetProgress().Index
+	}
+	for i, p := range a.pages {
+		if p.HasID(id) {
+			return i
+		}
+	}
+	return -1
+}
+
+// blocks reports whether a page with unchecked required items comes
+// before page i, the final page for len(a.pages), and tells the user so.
+func (a *App) blocks(i int) bool {
+	first := a.FirstIncomplete()
+	if first < 0 || i <= first {
+		return false
+	}
+	deck.Warningf("navigation to page %d refused: required items on page %s are unchecked", i, a.pages[first].ID())
+	a.host.Emit(NoticeEvent, a.cfg.Strings["required_left"])
+	return true
+}
+
+// checkLink toggles the item of a day1:check link, or checks every item
+// of the page for CheckAll, and sends the new state to the frontend.
+func (a *App) checkLink(rawURL string, link urischeme.Internal) {
+	i := a.pageIndex(link.Page)
+	if i < 0 {
+		deck.Warningf("day1 link %q: no page %q", rawURL, link.Page)
+		return
+	}
+	id := a.pages[i].ID()
+	a.checkMu.Lock()
+	if link.Item == urischeme.CheckAll {
+		for _, item := range pages.Checklist(a.pages[i].Markdown, pages.WithFacts(a.cfg.Facts)) {
+			a.checkState[CheckKey(id, item.ID)] = true
+		}
+	} else {
+		key := CheckKey(id, link.Item)
+		if !a.checkKeys[key] {
+			a.checkMu.Unlock()
+			deck.Warningf("day1 link %q: no checklist item %q on page %s", rawURL, link.Item, id)
+			return
+		}
+		a.checkState[key] = !a.checkState[key]
+	}
+	saveCheckState(a.checkState)
+	a.checkMu.Unlock()
+	a.host.Emit(CheckEvent, a.GetCheckState())
+}
+
+// runAction runs an action from day1.yml. Actions can't start other
+// actions, so a misconfigured pair can't loop.
+func (a *App) runAction(name string) {
+	action, ok := a.cfg.Actions[name]
+	if !ok {
+		deck.Warningf("unknown action %q", name)
+		return
+	}
+	if action.Copy != "" {
+		if err := a.host.ClipboardSetText(action.Copy); err != nil {
+			deck.Errorf("copy to clipboard: %v", err)
+			return
+		}
+		a.host.Emit(NoticeEvent, a.cfg.Strings["copied"])
+		return
+	}
+	if link, err := urischeme.ParseInternal(action.URL); err == nil && link.Op == urischeme.OpAction {
+		deck.Warningf("action %q can't run another action", name)
+		return
+	}
+	a.OpenURL(action.URL)
+}
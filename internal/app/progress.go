@@ -0,0 +1,114 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/google/deck"
+)
+
+const progressFile = "progress.json"
+
+// Progress is where the user left off. Pages are tracked by ID so content
+// edits that add, remove or reorder pages never resume on the wrong page.
+type Progress struct {
+	// Index is the position of PageID in the loaded pages, or 0 when the
+	// page is no longer shown.
+	Index   int      `json:"index"`
+	PageID  string   `json:"page_id"`
+	Visited []string `json:"visited"`
+}
+
+// savedProgress is the on-disk form of Progress.
+type savedProgress struct {
+	PageID  string   `json:"page_id"`
+	Visited []string `json:"visited,omitempty"`
+}
+
+// GetProgress returns the page to reopen on and the pages already seen.
+// Visited only lists pages that are still loaded.
+func (a *App) GetProgress() Progress {
+	a.progressMu.Lock()
+	defer a.progressMu.Unlock()
+	p := Progress{Visited: []string{}}
+	for i, pg := range a.pages {
+		id := pg.ID()
+		if id == a.progress.PageID {
+			p.Index, p.PageID = i, id
+		}
+		for _, v := range a.progress.Visited {
+			if v == id {
+				p.Visited = append(p.Visited, id)
+				break
+			}
+		}
+	}
+	return p
+}
+
+// SetCurrentPage records index as the last viewed page and marks it
+// visited. Out-of-range indexes are ignored.
+func (a *App) SetCurrentPage(index int) {
+	if index < 0 || index >= len(a.pages) {
+		return
+	}
+	id := a.pages[index].ID()
+	a.progressMu.Lock()
+	defer a.progressMu.Unlock()
+	a.progress.PageID = id
+	seen := false
+	for _, v := range a.progress.Visited {
+		if v == id {
+			seen = true
+			break
+		}
+	}
+	if !seen {
+		a.progress.Visited = append(a.progress.Visited, id)
+	}
+	writeStateFile(statePath(progressFile), a.progress)
+}
+
+// ResetProgress deletes the saved progress so the next run starts on the
+// first page. A missing file is not an error.
+func ResetProgress() error {
+	p := statePath(progressFile)
+	if p == "" {
+		return nil
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// LoadProgress returns the saved progress without checking it against
+// any content, for reporting when the content can't be loaded. Index is
+// always 0.
+func LoadProgress() Progress {
+	sp := loadProgress()
+	p := Progress{PageID: sp.PageID, Visited: sp.Visited}
+	if p.Visited == nil {
+		p.Visited = []string{}
+	}
+	return p
+}
+
+func loadProgress() savedProgress {
+	p := statePath(progressFile)
+	if p == "" {
+		return savedProgress{}
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return savedProgress{}
+	}
+	var sp savedProgress
+	if err := json.Unmarshal(data, &sp); err != nil {
+		deck.Warningf("corrupt progress state: %v", err)
+		return savedProgress{}
+	}
+	return sp
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/google/deck"
+)
+
+const snoozeFile = "snooze.json"
+
+// ErrMandatory is returned when the user tries to put off a mandatory
+// wizard.
+var ErrMandatory = errors.New("the wizard is mandatory")
+
+// Dismissal is one time the user put the wizard off.
+type Dismissal struct {
+	At time.Time `json:"at"`
+	// Until is when a "Remind me later" snooze ends. It is zero when the
+	// wizard was closed.
+	Until time.Time `json:"until,omitzero"`
+}
+
+// Snooze is the saved history of the wizard being put off since it was
+// last completed.
+type Snooze struct {
+	Dismissals []Dismissal `json:"dismissals"`
+}
+
+// Until returns when the last snooze ends, or the zero time.
+func (s Snooze) Until() time.Time {
+	if n := len(s.Dismissals); n > 0 {
+		return s.Dismissals[n-1].Until
+	}
+	return time.Time{}
+}
+
+// Snoozed reports whether the wizard should stay hidden at now.
+func (s Snooze) Snoozed(now time.Time) bool { return now.Before(s.Until()) }
+
+// CanSnooze reports whether "Remind me later" is offered: snoozing is
+// turned on and the wizard is not mandatory.
+func (a *App) CanSnooze() bool { return !a.cfg.Mandatory && a.cfg.SnoozeFor > 0 }
+
+// Snooze hides the wizard for the configured time and quits. A
+// mandatory wizard tells the user it can't be put off instead.
+func (a *App) Snooze() {
+	until, err := a.MarkSnoozed()
+	if err != nil {
+		deck.Warningf("snooze refused: %v", err)
+		a.host.Emit(NoticeEvent, a.cfg.Strings["mandatory"])
+		return
+	}
+	deck.Infof("wizard snoozed until %s", until.Format(time.RFC3339))
+	a.host.Quit()
+}
+
+// MarkSnoozed records a snooze without quitting, for front ends that
+// manage their own lifecycle, and returns when it ends.
+func (a *App) MarkSnoozed() (until time.Time, err error) {
+	if !a.CanSnooze() {
+		return time.Time{}, ErrMandatory
+	}
+	now := time.Now().UTC().Truncate(time.Second)
+	until = now.Add(a.cfg.SnoozeFor)
+	a.recordDismissal(Dismissal{At: now, Until: until})
+	return until, nil
+}
+
+// MarkDismissed records that the user closed the wizard without
+// completing it, or returns ErrMandatory.
+func (a *App) MarkDismissed() error {
+	if a.cfg.Mandatory {
+		return ErrMandatory
+	}
+	a.recordDismissal(Dismissal{At: time.Now().UTC().Truncate(time.Second)})
+	return nil
+}
+
+func (a *App) recordDismissal(d Dismissal) {
+	a.snoozeMu.Lock()
+	defer a.snoozeMu.Unlock()
+	a.snooze.Dismissals = append(a.snooze.Dismissals, d)
+	writeStateFile(statePath(snoozeFile), a.snooze)
+}
+
+// LoadSnooze returns the saved snooze history. A missing or corrupt file
+// reads as no history.
+func LoadSnooze() Snooze {
+	p := statePath(snoozeFile)
+	if p == "" {
+		return Snooze{}
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return Snooze{}
+	}
+	var s Snooze
+	if err := json.Unmarshal(data, &s); err != nil {
+		deck.Warningf("corrupt snooze state: %v", err)
+		return Snooze{}
+	}
+	return s
+}
+
+// ResetSnooze deletes the snooze history, ending any snooze. A missing
+// file is not an error.
+func ResetSnooze() error {
+	p := statePath(snoozeFile)
+	if p == "" {
+		return nil
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
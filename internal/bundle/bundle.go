@@ -0,0 +1,265 @@
+// Package bundle packs a pages directory into a single .zip archive with a
+// manifest of SHA-256 hashes, and verifies content against that manifest
+// before it is shown.
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestName is the manifest's path inside a bundle.
+const ManifestName = "manifest.json"
+
+// Manifest lists every content file of a bundle with its SHA-256 hash.
+type Manifest struct {
+	// Day1Version is the day1 version that built the bundle.
+	Day1Version string    `json:"day1_version"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Files maps slash-separated paths to hex SHA-256 hashes.
+	Files map[string]string `json:"files"`
+}
+
+// skip reports whether a file is left out of bundles and manifests: the
+// manifest, its signature and hidden files such as .git or .DS_Store.
+func skip(name string) bool {
+	return name == ManifestName || name == SignatureName || hidden(name)
+}
+
+// hidden reports whether name or one of its directories starts with a dot.
+func hidden(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") && part != "." {
+			return true
+		}
+	}
+	return false
+}
+
+// hiddenFiles returns the hidden files and directories of fsys, which
+// BuildManifest leaves out.
+func hiddenFiles(fsys fs.FS) ([]string, error) {
+	var names []string
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !hidden(name) {
+			return nil
+		}
+		names = append(names, name)
+		if d.IsDir() {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list content: %w", err)
+	}
+	return names, nil
+}
+
+// BuildManifest hashes every content file in fsys.
+func BuildManifest(fsys fs.FS, day1Version string) (Manifest, error) {
+	m := Manifest{
+		Day1Version: day1Version,
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+		Files:       map[string]string{},
+	}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if skip(name) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		sum, err := hashFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		m.Files[name] = sum
+		return nil
+	})
+	if err != nil {
+		return Manifest{}, fmt.Errorf("hash content: %w", err)
+	}
+	return m, nil
+}
+
+// Pack writes the content of fsys and its manifest to w as a zip archive.
+func Pack(fsys fs.FS, w io.Writer, day1Version string) (Manifest, error) {
+	m, err := BuildManifest(fsys, day1Version)
+	if err != nil {
+		return Manifest{}, err
+	}
+	zw := zip.NewWriter(w)
+	for _, name := range m.Names() {
+		if err := addFile(zw, fsys, name); err != nil {
+			return Manifest{}, err
+		}
+	}
+	data, err := m.marshal()
+	if err != nil {
+		return Manifest{}, err
+	}
+	fw, err := zw.Create(ManifestName)
+	if err != nil {
+		return Manifest{}, err
+	}
+	if _, err := fw.Write(data); err != nil {
+		return Manifest{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return Manifest{}, fmt.Errorf("write archive: %w", err)
+	}
+	return m, nil
+}
+
+// Names returns the manifest's file paths in sorted order.
+func (m Manifest) Names() []string {
+	names := make([]string, 0, len(m.Files))
+	for name := range m.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (m Manifest) marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal manifest: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// ReadManifest reads the manifest at the root of fsys.
+func ReadManifest(fsys fs.FS) (Manifest, error) {
+	data, err := fs.ReadFile(fsys, ManifestName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Manifest{}, fmt.Errorf("no %s found (build bundles with day1 pack)", ManifestName)
+	}
+	if err != nil {
+		return Manifest{}, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Manifest{}, fmt.Errorf("parse %s: %w", ManifestName, err)
+	}
+	return m, nil
+}
+
+// Verify checks that the content of fsys matches its manifest exactly:
+// every listed file exists with the recorded hash and no unlisted files
+// were added. Hidden files are never listed, so any found is an error.
+func Verify(fsys fs.FS) (Manifest, error) {
+	m, err := ReadManifest(fsys)
+	if err != nil {
+		return Manifest{}, err
+	}
+	actual, err := BuildManifest(fsys, "")
+	if err != nil {
+		return Manifest{}, err
+	}
+	extra, err := hiddenFiles(fsys)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	var problems []string
+	for _, name := range m.Names() {
+		got, ok := actual.Files[name]
+		switch {
+		case !ok:
+			problems = append(problems, name+" is missing")
+		case got != m.Files[name]:
+			problems = append(problems, name+" was modified")
+		}
+	}
+	for _, name := range actual.Names() {
+		if _, ok := m.Files[name]; !ok {
+			problems = append(problems, name+" is not in the manifest")
+		}
+	}
+	for _, name := range extra {
+		problems = append(problems, name+" is hidden and not in the manifest")
+	}
+	if len(problems) > 0 {
+		return Manifest{}, fmt.Errorf("content does not match %s: %s", ManifestName, strings.Join(problems, "; "))
+	}
+	return m, nil
+}
+
+// Snapshot reads the files listed in m from fsys into memory, checking
+// each against its hash as it is read, and returns them as an fs.FS.
+// Loading content from the snapshot of a verified fsys means a file
+// changed on disk after Verify is never shown.
+func Snapshot(fsys fs.FS, m Manifest) (fs.FS, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range m.Names() {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != m.Files[name] {
+			return nil, fmt.Errorf("%s changed after it was verified", name)
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+}
+
+func hashFile(fsys fs.FS, name string) (string, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("read %s: %w", name, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func addFile(zw *zip.Writer, fsys fs.FS, name string) error {
+	in, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("pack %s: %w", name, err)
+	}
+	return nil
+}
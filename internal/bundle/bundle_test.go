@@ -0,0 +1,270 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/pem"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func testContent() fstest.MapFS {
+	return fstest.MapFS{
+		"day1.yml":          {Data: []byte("title: Test\n")},
+		"welcome.md":        {Data: []byte("# Welcome")},
+		"assets/logo.png":   {Data: []byte("png")},
+		".git/config":       {Data: []byte("[core]")},
+		"assets/.DS_Store":  {Data: []byte("junk")},
+		"notes/.hidden.md":  {Data: []byte("# Hidden")},
+		"nested/deep/a.txt": {Data: []byte("a")},
+	}
+}
+
+func TestPackRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	m, err := Pack(testContent(), &buf, "1.2.3")
+	if err != nil {
+		t.Fatalf("Pack: %v", err)
+	}
+	want := []string{"assets/logo.png", "day1.yml", "nested/deep/a.txt", "welcome.md"}
+	if got := strings.Join(m.Names(), ","); got != strings.Join(want, ",") {
+		t.Errorf("manifest files = %s, want %v", got, want)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("read archive: %v", err)
+	}
+	got, err := Verify(zr)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got.Day1Version != "1.2.3" || len(got.Files) != len(want) {
+		t.Errorf("Verify() = %+v, want version 1.2.3 and %d files", got, len(want))
+	}
+	if data, _ := fs.ReadFile(zr, "welcome.md"); string(data) != "# Welcome" {
+		t.Errorf("welcome.md = %q", data)
+	}
+}
+
+func TestVerifyMismatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		tamper  func(fstest.MapFS)
+		wantErr string
+	}{
+		{"modified", func(m fstest.MapFS) { m["welcome.md"] = &fstest.MapFile{Data: []byte("# Click here")} }, "welcome.md was modified"},
+		{"removed", func(m fstest.MapFS) { delete(m, "day1.yml") }, "day1.yml is missing"},
+		{"added", func(m fstest.MapFS) { m["extra.md"] = &fstest.MapFile{Data: []byte("# Extra")} }, "extra.md is not in the manifest"},
+		{"hidden file", func(m fstest.MapFS) { m[".extra.md"] = &fstest.MapFile{Data: []byte("# Extra")} }, ".extra.md is hidden"},
+		{"hidden dir", func(m fstest.MapFS) { m[".git/config"] = &fstest.MapFile{Data: []byte("[core]")} }, ".git is hidden"},
+		{"no manifest", func(m fstest.MapFS) { delete(m, ManifestName) }, "no manifest.json"},
+		{"corrupt manifest", func(m fstest.MapFS) { m[ManifestName] = &fstest.MapFile{Data: []byte("{")} }, "parse manifest.json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var buf bytes.Buffer
+			if _, err := Pack(testContent(), &buf, "dev"); err != nil {
+				t.Fatal(err)
+			}
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Verify the packed files, which leave out the hidden ones.
+			fsys := fstest.MapFS{}
+			for _, zf := range zr.File {
+				data, err := fs.ReadFile(zr, zf.Name)
+				if err != nil {
+					t.Fatal(err)
+				}
+				fsys[zf.Name] = &fstest.MapFile{Data: data}
+			}
+			if _, err := Verify(fsys); err != nil {
+				t.Fatalf("untampered content: %v", err)
+			}
+
+			tt.tamper(fsys)
+			_, err = Verify(fsys)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Verify() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	fsys := testContent()
+	m, err := BuildManifest(fsys, "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := Snapshot(fsys, m)
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	if err := fstest.TestFS(snap, m.Names()...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Stat(snap, "notes/.hidden.md"); err == nil {
+		t.Error("snapshot has a file that isn't in the manifest")
+	}
+	if data, _ := fs.ReadFile(snap, "welcome.md"); string(data) != "# Welcome" {
+		t.Errorf("welcome.md = %q", data)
+	}
+
+	// A file changed between Verify and loading is caught.
+	fsys["welcome.md"] = &fstest.MapFile{Data: []byte("# Click here")}
+	if _, err := Snapshot(fsys, m); err == nil || !strings.Contains(err.Error(), "welcome.md changed") {
+		t.Errorf("changed file: error = %v", err)
+	}
+}
+
+func testKey(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv
+}
+
+func TestParseKeys(t *testing.T) {
+	t.Parallel()
+	pub, priv := testKey(t)
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotPriv, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil || !gotPriv.Equal(priv) {
+		t.Errorf("ParsePrivateKey: err = %v, equal = %v", err, gotPriv.Equal(priv))
+	}
+
+	der, err = x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		"pem":    pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}),
+		"base64": []byte(EncodePublicKey(pub) + "\n"),
+	} {
+		got, err := ParsePublicKey(data)
+		if err != nil || !got.Equal(pub) {
+			t.Errorf("ParsePublicKey(%s): err = %v", name, err)
+		}
+	}
+
+	for _, bad := range []string{"", "not a key", "c2hvcnQ="} {
+		if _, err := ParsePublicKey([]byte(bad)); err == nil {
+			t.Errorf("ParsePublicKey(%q): expected error", bad)
+		}
+	}
+	if _, err := ParsePrivateKey([]byte("no pem")); err == nil {
+		t.Error("ParsePrivateKey without PEM: expected error")
+	}
+}
+
+func TestSignDir(t *testing.T) {
+	t.Parallel()
+	pub, priv := testKey(t)
+	other, _ := testKey(t)
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "welcome.md"), []byte("# Welcome"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifySigned(os.DirFS(dir), []ed25519.PublicKey{pub}); err == nil {
+		t.Error("unsigned dir: expected error")
+	}
+	if _, err := SignDir(dir, priv, "dev"); err != nil {
+		t.Fatalf("SignDir: %v", err)
+	}
+	if _, err := VerifySigned(os.DirFS(dir), []ed25519.PublicKey{other, pub}); err != nil {
+		t.Errorf("VerifySigned: %v", err)
+	}
+	if _, err := VerifySigned(os.DirFS(dir), []ed25519.PublicKey{other}); err == nil || !strings.Contains(err.Error(), "trusted key") {
+		t.Errorf("untrusted key: error = %v", err)
+	}
+
+	// Hidden files aren't in the manifest, so they can't ride along.
+	hiddenPage := filepath.Join(dir, ".extra.md")
+	if err := os.WriteFile(hiddenPage, []byte("# Click here"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifySigned(os.DirFS(dir), []ed25519.PublicKey{pub}); err == nil || !strings.Contains(err.Error(), ".extra.md is hidden") {
+		t.Errorf("hidden file: error = %v", err)
+	}
+	if err := os.Remove(hiddenPage); err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-hashing a tampered page isn't enough: the manifest is signed.
+	if err := os.WriteFile(filepath.Join(dir, "welcome.md"), []byte("# Click here"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifySigned(os.DirFS(dir), []ed25519.PublicKey{pub}); err == nil {
+		t.Error("tampered page: expected error")
+	}
+	m, _ := BuildManifest(os.DirFS(dir), "dev")
+	data, _ := m.marshal()
+	if err := os.WriteFile(filepath.Join(dir, ManifestName), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifySigned(os.DirFS(dir), []ed25519.PublicKey{pub}); err == nil {
+		t.Error("tampered manifest: expected error")
+	}
+}
+
+func TestSignArchive(t *testing.T) {
+	t.Parallel()
+	pub, priv := testKey(t)
+
+	path := filepath.Join(t.TempDir(), "onboarding.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Pack(testContent(), f, "dev"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	for i := 0; i < 2; i++ { // re-signing replaces the signature
+		if _, err := SignArchive(path, priv); err != nil {
+			t.Fatalf("SignArchive: %v", err)
+		}
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	if _, err := VerifySigned(zr, []ed25519.PublicKey{pub}); err != nil {
+		t.Errorf("VerifySigned: %v", err)
+	}
+	sigs := 0
+	for _, zf := range zr.File {
+		if zf.Name == SignatureName {
+			sigs++
+		}
+	}
+	if sigs != 1 {
+		t.Errorf("archive has %d signatures, want 1", sigs)
+	}
+}
@@ -0,0 +1,178 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SignatureName is the path of the detached manifest signature, next to
+// the manifest in a bundle or pages directory.
+const SignatureName = "manifest.sig"
+
+// ParsePrivateKey parses a PEM-encoded PKCS#8 ed25519 private key, as
+// written by `openssl genpkey -algorithm ed25519`.
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key: no PEM block found")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("private key: %w", err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key: %T is not an ed25519 key", key)
+	}
+	return priv, nil
+}
+
+// ParsePublicKey parses a PEM-encoded PKIX ed25519 public key, or a raw
+// 32-byte key in base64 as compiled in via ldflags.
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("public key: %w", err)
+		}
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf("public key: %T is not an ed25519 key", key)
+		}
+		return pub, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, errors.New("public key: want PEM or base64 of a 32-byte ed25519 key")
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// EncodePublicKey returns pub as base64, the format ParsePublicKey and
+// the ldflags key accept.
+func EncodePublicKey(pub ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(pub)
+}
+
+// signManifest returns the signature file content for manifest bytes.
+func signManifest(manifest []byte, key ed25519.PrivateKey) []byte {
+	sig := ed25519.Sign(key, manifest)
+	return []byte(base64.StdEncoding.EncodeToString(sig) + "\n")
+}
+
+// VerifySignature checks the detached signature in fsys against its
+// manifest and reports an error unless one of keys made it.
+func VerifySignature(fsys fs.FS, keys []ed25519.PublicKey) error {
+	manifest, err := fs.ReadFile(fsys, ManifestName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("unsigned content: no %s found (sign it with day1 sign)", ManifestName)
+	}
+	if err != nil {
+		return err
+	}
+	data, err := fs.ReadFile(fsys, SignatureName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("unsigned content: no %s found (sign it with day1 sign)", SignatureName)
+	}
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", SignatureName, err)
+	}
+	for _, key := range keys {
+		if ed25519.Verify(key, manifest, sig) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not signed by a trusted key", ManifestName)
+}
+
+// VerifySigned checks the signature with VerifySignature, then that every
+// file matches the signed manifest.
+func VerifySigned(fsys fs.FS, keys []ed25519.PublicKey) (Manifest, error) {
+	if err := VerifySignature(fsys, keys); err != nil {
+		return Manifest{}, err
+	}
+	return Verify(fsys)
+}
+
+// SignDir writes a fresh manifest of dir and its signature into dir.
+func SignDir(dir string, key ed25519.PrivateKey, day1Version string) (Manifest, error) {
+	m, err := BuildManifest(os.DirFS(dir), day1Version)
+	if err != nil {
+		return Manifest{}, err
+	}
+	data, err := m.marshal()
+	if err != nil {
+		return Manifest{}, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestName), data, 0o644); err != nil {
+		return Manifest{}, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, SignatureName), signManifest(data, key), 0o644); err != nil {
+		return Manifest{}, err
+	}
+	return m, nil
+}
+
+// SignArchive signs the manifest of a bundle built by Pack, after checking
+// that the bundle still matches it, and rewrites the archive with the
+// signature added. An existing signature is replaced.
+func SignArchive(path string, key ed25519.PrivateKey) (Manifest, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("open bundle: %w", err)
+	}
+	defer zr.Close()
+	m, err := Verify(zr)
+	if err != nil {
+		return Manifest{}, err
+	}
+	manifest, err := fs.ReadFile(zr, ManifestName)
+	if err != nil {
+		return Manifest{}, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range zr.File {
+		if f.Name == SignatureName {
+			continue
+		}
+		if err := zw.Copy(f); err != nil {
+			return Manifest{}, err
+		}
+	}
+	w, err := zw.Create(SignatureName)
+	if err != nil {
+		return Manifest{}, err
+	}
+	if _, err := w.Write(signManifest(manifest, key)); err != nil {
+		return Manifest{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return Manifest{}, err
+	}
+	zr.Close()
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return Manifest{}, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return Manifest{}, err
+	}
+	return m, nil
+}
@@ -0,0 +1,340 @@
+// Package export writes the onboarding content as a self-contained static
+// website, for publishing on an intranet alongside the wizard.
+package export
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/TsekNet/day1/internal/pages"
+	"github.com/TsekNet/day1/internal/urischeme"
+)
+
+//go:embed site.html.tmpl
+var siteTmpl string
+
+var (
+	tmpl      = template.Must(template.New("site").Parse(siteTmpl))
+	imgSrcRe  = regexp.MustCompile(`<img\s[^>]*?src="([^"]+)"`)
+	day1Re    = regexp.MustCompile(`\shref="(day1:[^"#]*)(#[^"]*)?"`)
+	accentRe  = regexp.MustCompile(`^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$`)
+	indexName = "index.html"
+	finalName = "final.html"
+)
+
+// Options controls an export.
+type Options struct {
+	// Pages is the content to export, rooted at day1.yml.
+	Pages  fs.FS
+	OutDir string
+	// Platform is a GOOS value, or "all" to export the union of every
+	// platform's pages with every ::: directive block kept.
+	Platform string
+}
+
+type entry struct {
+	Title    string
+	Href     string
+	Platform string
+	Current  bool
+}
+
+type pageData struct {
+	SiteTitle string
+	Brand     pages.Brand
+	LogoHref  string
+	Accent    string
+	Title     string
+	Platform  string
+	Dir       string
+	Body      template.HTML
+	TOC       []entry
+	Prev      *entry
+	Next      *entry
+	Root      string
+}
+
+type page struct {
+	src   pages.Page
+	out   string // slash-separated path relative to OutDir
+	title string
+}
+
+// HTML renders every page and the optional final page to OutDir, copies
+// the images they reference, and writes index.html with a table of
+// contents. Links between .md pages and day1: page links are rewritten to
+// the exported .html files.
+func HTML(opts Options) error {
+	cfg, err := pages.LoadConfig(opts.Pages)
+	if err != nil {
+		return err
+	}
+	loaded, err := loadPages(opts.Pages, opts.Platform)
+	if err != nil {
+		return err
+	}
+	if len(loaded) == 0 {
+		return fmt.Errorf("no pages found for platform %s", opts.Platform)
+	}
+	// The site is the same for everyone, so templates show placeholders.
+	data := pages.PlaceholderData(cfg, opts.Platform)
+	if err := pages.ExpandTemplates(loaded, data); err != nil {
+		return err
+	}
+
+	site := make([]page, 0, len(loaded)+1)
+	for _, p := range loaded {
+		site = append(site, page{src: p, out: outName(p.SourceFile), title: p.Frontmatter.Title})
+	}
+	if cfg.FinalPage != "" {
+		if filepath.IsAbs(cfg.FinalPage) || strings.Contains(cfg.FinalPage, "..") {
+			return fmt.Errorf("final_page must be a relative path without '..'")
+		}
+		raw, err := fs.ReadFile(opts.Pages, cfg.FinalPage)
+		if err != nil {
+			return fmt.Errorf("read final page: %w", err)
+		}
+		fm, body, err := pages.ParseFrontmatter(string(raw), cfg.FinalPage)
+		if err != nil {
+			return err
+		}
+		var include []pages.RenderOption
+		if opts.Platform != "all" {
+			include = append(include, pages.WithPlatform(opts.Platform))
+		}
+		if body, err = pages.ExpandIncludes(opts.Pages, cfg.FinalPage, body, include...); err != nil {
+			return err
+		}
+		if body, err = pages.ExpandTemplate(cfg.FinalPage, body, data); err != nil {
+			return err
+		}
+		title := fm.Title
+		if title == "" {
+			title = "All Done"
+		}
+		site = append(site, page{
+			src:   pages.Page{Frontmatter: fm, Markdown: body, SourceFile: cfg.FinalPage},
+			out:   finalName,
+			title: title,
+		})
+	}
+
+	if err := os.MkdirAll(opts.OutDir, 0o755); err != nil {
+		return fmt.Errorf("create output dir: %w", err)
+	}
+
+	ex := &exporter{opts: opts, cfg: cfg, copied: map[string]bool{}}
+	if cfg.Brand.Logo != "" {
+		if err := ex.copyAsset(cfg.Brand.Logo); err != nil {
+			return err
+		}
+	}
+	for i := range site {
+		if err := ex.writePage(site, i); err != nil {
+			return err
+		}
+	}
+	return ex.writeIndex(site)
+}
+
+type exporter struct {
+	opts   Options
+	cfg    pages.Config
+	copied map[string]bool
+}
+
+func (ex *exporter) writePage(site []page, i int) error {
+	p := site[i]
+	root := relRoot(p.out)
+
+	prefix := ""
+	if root != "" {
+		prefix = strings.TrimSuffix(root, "/")
+	}
+	dir := p.src.Dir(ex.cfg.DefaultLocale())
+	render := []pages.RenderOption{pages.WithSource(p.src.SourceFile), pages.WithDir(dir)}
+	if ex.opts.Platform != "all" {
+		render = append(render, pages.WithPlatform(ex.opts.Platform))
+	}
+	body, err := pages.RenderHTML(p.src.Markdown, prefix, render...)
+	if err != nil {
+		return fmt.Errorf("render %s: %w", p.src.SourceFile, err)
+	}
+	for _, m := range imgSrcRe.FindAllStringSubmatch(body, -1) {
+		src := strings.TrimPrefix(m[1], root)
+		if isExternal(src) {
+			continue
+		}
+		if err := ex.copyAsset(src); err != nil {
+			return err
+		}
+	}
+	body = rewriteLinks(body, site, root)
+
+	data := ex.data(site, root)
+	data.Title = p.title
+	data.Body = template.HTML(body)
+	data.Dir = dir
+	if pl := p.src.Frontmatter.Platform; pl != "" && pl != "all" {
+		data.Platform = pl
+	}
+	for j := range data.TOC {
+		data.TOC[j].Current = j == i
+	}
+	if i > 0 {
+		data.Prev = &data.TOC[i-1]
+	}
+	if i < len(site)-1 {
+		data.Next = &data.TOC[i+1]
+	}
+	return ex.render(p.out, data)
+}
+
+// rewriteLinks points day1:page and day1:final links at the exported
+// files. Links that only work in the wizard, such as checklist and action
+// links or links to pages not exported, lose their href.
+func rewriteLinks(body string, site []page, root string) string {
+	return day1Re.ReplaceAllStringFunc(body, func(m string) string {
+		g := day1Re.FindStringSubmatch(m)
+		link, err := urischeme.ParseInternal(html.UnescapeString(g[1]))
+		if err != nil {
+			return ""
+		}
+		for _, p := range site {
+			switch {
+			case link.Op == urischeme.OpPage && p.out != finalName && p.src.HasID(link.Page),
+				link.Op == urischeme.OpFinal && p.out == finalName:
+				return ` href="` + root + p.out + g[2] + `"`
+			}
+		}
+		return ""
+	})
+}
+
+// writeIndex writes the landing page: the table of contents with a link to
+// the first page.
+func (ex *exporter) writeIndex(site []page) error {
+	data := ex.data(site, "")
+	data.Title = ex.siteTitle()
+	data.Next = &data.TOC[0]
+	return ex.render(indexName, data)
+}
+
+func (ex *exporter) data(site []page, root string) pageData {
+	toc := make([]entry, len(site))
+	for i, p := range site {
+		toc[i] = entry{Title: p.title, Href: root + p.out}
+		if pl := p.src.Frontmatter.Platform; pl != "" && pl != "all" {
+			toc[i].Platform = pl
+		}
+	}
+	d := pageData{
+		SiteTitle: ex.siteTitle(),
+		Brand:     ex.cfg.Brand,
+		TOC:       toc,
+		Root:      root,
+		Accent:    "#188038",
+	}
+	if accentRe.MatchString(ex.cfg.AccentColor) {
+		d.Accent = ex.cfg.AccentColor
+	}
+	if ex.cfg.Brand.Logo != "" {
+		d.LogoHref = root + ex.cfg.Brand.Logo
+	}
+	return d
+}
+
+func (ex *exporter) siteTitle() string {
+	if ex.cfg.Title != "" {
+		return ex.cfg.Title
+	}
+	return "Day 1"
+}
+
+func (ex *exporter) render(name string, data pageData) error {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return fmt.Errorf("render %s: %w", name, err)
+	}
+	target := filepath.Join(ex.opts.OutDir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(target, buf.Bytes(), 0o644)
+}
+
+// copyAsset copies src (relative to the pages dir) to the same relative
+// path under the output dir.
+func (ex *exporter) copyAsset(src string) error {
+	if u, err := url.PathUnescape(src); err == nil {
+		src = u
+	}
+	src = path.Clean(src)
+	if ex.copied[src] {
+		return nil
+	}
+	if strings.HasPrefix(src, "..") || path.IsAbs(src) {
+		return fmt.Errorf("asset %q must be a relative path without '..'", src)
+	}
+	ex.copied[src] = true
+
+	in, err := ex.opts.Pages.Open(src)
+	if err != nil {
+		return fmt.Errorf("copy asset: %w", err)
+	}
+	defer in.Close()
+
+	target := filepath.Join(ex.opts.OutDir, filepath.FromSlash(src))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("copy asset %s: %w", src, err)
+	}
+	return out.Close()
+}
+
+// loadPages loads pages for one platform, or every platform's pages when
+// platform is "all".
+func loadPages(fsys fs.FS, platform string) ([]pages.Page, error) {
+	if platform != "all" && !pages.IsPlatform(platform) {
+		return nil, fmt.Errorf("unknown platform %q", platform)
+	}
+	return pages.LoadForPlatform(fsys, platform)
+}
+
+// outName maps "sub/page.md" to "sub/page.html".
+func outName(source string) string {
+	return strings.TrimSuffix(filepath.ToSlash(source), ".md") + ".html"
+}
+
+// relRoot returns the relative path from an output file back to OutDir,
+// e.g. "" for "a.html" and "../" for "sub/a.html".
+func relRoot(out string) string {
+	return strings.Repeat("../", strings.Count(out, "/"))
+}
+
+func isExternal(src string) bool {
+	for _, p := range []string{"http://", "https://", "//", "/", "data:"} {
+		if strings.HasPrefix(src, p) {
+			return true
+		}
+	}
+	return false
+}
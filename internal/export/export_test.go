@@ -0,0 +1,161 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readOut(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func TestHTML(t *testing.T) {
+	t.Parallel()
+
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{
+		"day1.yml":       "title: Onboarding\nbrand:\n  name: Acme\n  logo: img/logo.png\naccent_color: \"#ff0000\"\nfinal_page: done.md\npages:\n  - welcome.md\n  - mac.md\n  - win.md\n  - guides/vpn.md\n",
+		"welcome.md":     "---\ntitle: Welcome\n---\n# Hi\n\nSee [VPN](guides/vpn.md#setup) and [docs](https://example.com/a.md).\n\n![pic](img/pic.png)\n",
+		"mac.md":         "---\ntitle: Mac\nplatform: darwin\n---\n# Mac\n",
+		"win.md":         "---\ntitle: Windows\nplatform: windows\ndir: rtl\n---\n# Win\n",
+		"guides/vpn.md":  "# VPN\n\n![pic](img/pic.png) back to [welcome](../welcome.md), [finish](day1:final) or [check all](day1:check/all)\n",
+		"done.md":        "---\ntitle: Finished\n---\n# Done\n",
+		"img/pic.png":    "png",
+		"img/logo.png":   "logo",
+		"img/unused.png": "unused",
+	})
+
+	tests := []struct {
+		name        string
+		platform    string
+		wantFiles   []string
+		absentFiles []string
+		contains    map[string][]string
+	}{
+		{
+			name:        "single platform",
+			platform:    "darwin",
+			wantFiles:   []string{"index.html", "welcome.html", "mac.html", "guides/vpn.html", "final.html", "img/pic.png", "img/logo.png"},
+			absentFiles: []string{"win.html", "img/unused.png"},
+			contains: map[string][]string{
+				"index.html":      {"Onboarding", "Acme", "--accent: #ff0000", `href="welcome.html"`, `href="final.html"`},
+				"welcome.html":    {`<main dir="ltr">`, `href="guides/vpn.html#setup"`, `href="https://example.com/a.md"`, `src="img/pic.png"`, `href="mac.html"`},
+				"guides/vpn.html": {`src="../img/pic.png"`, `href="../welcome.html"`, `href="../index.html"`, `href="../final.html"`, `<a>check all</a>`},
+				"final.html":      {"Finished", "<h1>Done</h1>"},
+			},
+		},
+		{
+			name:      "all platforms",
+			platform:  "all",
+			wantFiles: []string{"welcome.html", "mac.html", "win.html"},
+			contains: map[string][]string{
+				"index.html": {`<span class="platform">windows</span>`, `<span class="platform">darwin</span>`},
+				"win.html":   {"windows only", `<main dir="rtl">`},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			out := t.TempDir()
+			if err := HTML(Options{Pages: os.DirFS(src), OutDir: out, Platform: tt.platform}); err != nil {
+				t.Fatalf("HTML: %v", err)
+			}
+			for _, name := range tt.wantFiles {
+				if _, err := os.Stat(filepath.Join(out, filepath.FromSlash(name))); err != nil {
+					t.Errorf("missing %s", name)
+				}
+			}
+			for _, name := range tt.absentFiles {
+				if _, err := os.Stat(filepath.Join(out, filepath.FromSlash(name))); err == nil {
+					t.Errorf("%s should not be exported", name)
+				}
+			}
+			for name, wants := range tt.contains {
+				got := readOut(t, out, name)
+				for _, want := range wants {
+					if !strings.Contains(got, want) {
+						t.Errorf("%s missing %q", name, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestHTMLTemplates(t *testing.T) {
+	t.Parallel()
+
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{
+		"day1.yml":   "vars:\n  start: March 3\nfinal_page: done.md\n",
+		"welcome.md": "# Welcome, {{ .FirstName }}\n\nLog in to `{{ .Hostname }}` on {{ .Vars.start }}, team {{ .Env.DAY1_TEAM }}.\n{{ if eq .OS \"darwin\" }}Mac{{ end }}\n",
+		"done.md":    "# Bye {{ .User }}\n",
+	})
+	out := t.TempDir()
+	if err := HTML(Options{Pages: os.DirFS(src), OutDir: out, Platform: "darwin"}); err != nil {
+		t.Fatalf("HTML: %v", err)
+	}
+	for name, wants := range map[string][]string{
+		"welcome.html": {"Welcome, FIRST_NAME", "<code>HOSTNAME</code>", "March 3", "team DAY1_TEAM", "Mac"},
+		"final.html":   {"Bye USER"},
+	} {
+		got := readOut(t, out, name)
+		for _, want := range wants {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s missing %q", name, want)
+			}
+		}
+		if strings.Contains(got, "{{") {
+			t.Errorf("%s has an unexpanded template:\n%s", name, got)
+		}
+	}
+}
+
+func TestHTMLErrors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		files    map[string]string
+		platform string
+	}{
+		{"unknown platform", map[string]string{"a.md": "# A"}, "beos"},
+		{"no pages", map[string]string{"a.md": "---\nplatform: darwin\n---\n"}, "linux"},
+		{"missing image", map[string]string{"a.md": "![x](nope.png)"}, "linux"},
+		{"unknown var", map[string]string{"a.md": "{{ .Vars.desk }}"}, "linux"},
+		{"final page traversal", map[string]string{"a.md": "# A", "day1.yml": "final_page: ../x.md\n"}, "linux"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			src := t.TempDir()
+			writeTree(t, src, tt.files)
+			if err := HTML(Options{Pages: os.DirFS(src), OutDir: t.TempDir(), Platform: tt.platform}); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
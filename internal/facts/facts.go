@@ -0,0 +1,198 @@
+// Package facts describes the machine day1 runs on: its platform, CPU
+// architecture, Linux distribution, OS version, host name, and the user's
+// name, groups, locale and environment. Page conditions are evaluated
+// against Facts, which can also be written by hand to simulate another
+// machine.
+package facts
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"os/user"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/TsekNet/day1/internal/platform"
+)
+
+// Facts about one machine and user. Empty fields are unknown.
+type Facts struct {
+	OS   string `json:"os" yaml:"os"`
+	Arch string `json:"arch" yaml:"arch"`
+	// Distro is the ID from /etc/os-release, e.g. "ubuntu". DistroLike
+	// lists the distributions it derives from (ID_LIKE).
+	Distro     string   `json:"distro,omitempty" yaml:"distro"`
+	DistroLike []string `json:"distro_like,omitempty" yaml:"distro_like"`
+	// OSVersion is VERSION_ID on Linux, the product version on macOS and
+	// the build version on Windows, e.g. "22.04", "14.5" or "10.0.22631".
+	OSVersion string `json:"os_version,omitempty" yaml:"os_version"`
+	Hostname  string `json:"hostname,omitempty" yaml:"hostname"`
+	// User is the login name without a Windows domain; FullName is the
+	// display name from the account database (GECOS on Unix).
+	User     string   `json:"user,omitempty" yaml:"user"`
+	FullName string   `json:"full_name,omitempty" yaml:"full_name"`
+	Groups   []string `json:"groups,omitempty" yaml:"groups"`
+	// Locale is the user's language as a tag like "pt-BR", from LC_ALL,
+	// LC_MESSAGES or LANG. Empty for the C locale.
+	Locale string            `json:"locale,omitempty" yaml:"locale"`
+	Env    map[string]string `json:"env,omitempty" yaml:"env"`
+}
+
+// osRelease is the file read for the Linux distribution.
+var osRelease = "/etc/os-release"
+
+var (
+	once    sync.Once
+	current Facts
+)
+
+// Current returns the facts of this machine and user, gathered once.
+func Current() Facts {
+	once.Do(func() { current = gather() })
+	return current
+}
+
+func gather() Facts {
+	f := Facts{OS: platform.Current(), Arch: runtime.GOARCH, Env: Environ(os.Environ())}
+	f.Locale = EnvLocale(f.Env)
+	if h, err := os.Hostname(); err == nil {
+		f.Hostname = strings.ToLower(h)
+	}
+	switch runtime.GOOS {
+	case "linux":
+		if data, err := os.ReadFile(osRelease); err == nil {
+			f.Distro, f.DistroLike, f.OSVersion = ParseOSRelease(string(data))
+		}
+	case "darwin":
+		if out, err := exec.Command("sw_vers", "-productVersion").Output(); err == nil {
+			f.OSVersion = strings.TrimSpace(string(out))
+		}
+	case "windows":
+		if out, err := exec.Command("cmd", "/c", "ver").Output(); err == nil {
+			f.OSVersion = windowsVersion(string(out))
+		}
+	}
+	if u, err := user.Current(); err == nil {
+		f.User = loginName(u.Username)
+		f.FullName = strings.TrimSpace(u.Name)
+		f.Groups = groups(u)
+	}
+	return f
+}
+
+// loginName strips the domain from a Windows DOMAIN\user name.
+func loginName(name string) string {
+	if i := strings.LastIndex(name, `\`); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// EnvLocale returns the locale of env the way POSIX programs pick the
+// language of their messages: the first of LC_ALL, LC_MESSAGES and LANG
+// that is set wins.
+func EnvLocale(env map[string]string) string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if v := env[key]; v != "" {
+			return ParseLocale(v)
+		}
+	}
+	return ""
+}
+
+// ParseLocale turns a POSIX locale like "pt_BR.UTF-8" or a language tag
+// like "zh-hant-tw" into a canonical tag: "pt-BR", "zh-Hant-TW". It
+// returns "" for C, POSIX and anything without a 2 or 3 letter language.
+func ParseLocale(s string) string {
+	s, _, _ = strings.Cut(s, ".")
+	s, _, _ = strings.Cut(s, "@")
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(s), "_", "-"), "-")
+	if !isLetters(parts[0]) || len(parts[0]) < 2 || len(parts[0]) > 3 {
+		return ""
+	}
+	parts[0] = strings.ToLower(parts[0])
+	for i, p := range parts[1:] {
+		switch {
+		case len(p) == 4 && isLetters(p):
+			p = strings.ToUpper(p[:1]) + strings.ToLower(p[1:])
+		case len(p) == 2 && isLetters(p), len(p) == 3 && strings.Trim(p, "0123456789") == "":
+			p = strings.ToUpper(p)
+		case p == "":
+			return ""
+		default:
+			p = strings.ToLower(p)
+		}
+		parts[i+1] = p
+	}
+	return strings.Join(parts, "-")
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// ParseOSRelease returns the ID, ID_LIKE and VERSION_ID of an os-release
+// file, lowercased.
+func ParseOSRelease(data string) (id string, like []string, version string) {
+	sc := bufio.NewScanner(strings.NewReader(data))
+	for sc.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(sc.Text()), "=")
+		if !ok {
+			continue
+		}
+		value = strings.ToLower(strings.Trim(value, `"'`))
+		switch key {
+		case "ID":
+			id = value
+		case "ID_LIKE":
+			like = strings.Fields(value)
+		case "VERSION_ID":
+			version = value
+		}
+	}
+	return id, like, version
+}
+
+var windowsVersionRe = regexp.MustCompile(`\[Version ([0-9.]+)\]`)
+
+// windowsVersion extracts "10.0.22631.2861" from the output of ver.
+func windowsVersion(ver string) string {
+	if m := windowsVersionRe.FindStringSubmatch(ver); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// groups returns the names of u's groups.
+func groups(u *user.User) []string {
+	ids, err := u.GroupIds()
+	if err != nil {
+		return nil
+	}
+	var out []string
+	for _, id := range ids {
+		if g, err := user.LookupGroupId(id); err == nil {
+			out = append(out, g.Name)
+		}
+	}
+	return out
+}
+
+// Environ turns "KEY=value" pairs into a map.
+func Environ(pairs []string) map[string]string {
+	env := make(map[string]string, len(pairs))
+	for _, kv := range pairs {
+		if k, v, ok := strings.Cut(kv, "="); ok && k != "" {
+			env[k] = v
+		}
+	}
+	return env
+}
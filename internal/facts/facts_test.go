@@ -0,0 +1,151 @@
+package facts
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseOSRelease(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		data        string
+		wantID      string
+		wantLike    []string
+		wantVersion string
+	}{
+		{
+			name:        "ubuntu",
+			data:        "NAME=\"Ubuntu\"\nVERSION_ID=\"22.04\"\nID=ubuntu\nID_LIKE=debian\n",
+			wantID:      "ubuntu",
+			wantLike:    []string{"debian"},
+			wantVersion: "22.04",
+		},
+		{
+			name:        "rocky",
+			data:        "ID=\"rocky\"\nID_LIKE=\"rhel centos fedora\"\nVERSION_ID=\"9.3\"\n",
+			wantID:      "rocky",
+			wantLike:    []string{"rhel", "centos", "fedora"},
+			wantVersion: "9.3",
+		},
+		{
+			name:   "rolling release without version",
+			data:   "# comment\nID=arch\n\nBUILD_ID=rolling\n",
+			wantID: "arch",
+		},
+		{
+			name:   "uppercase is lowered",
+			data:   "ID='NixOS'\n",
+			wantID: "nixos",
+		},
+		{
+			name: "empty",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			id, like, version := ParseOSRelease(tt.data)
+			if id != tt.wantID {
+				t.Errorf("id = %q, want %q", id, tt.wantID)
+			}
+			if !slices.Equal(like, tt.wantLike) {
+				t.Errorf("like = %q, want %q", like, tt.wantLike)
+			}
+			if version != tt.wantVersion {
+				t.Errorf("version = %q, want %q", version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestWindowsVersion(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		out  string
+		want string
+	}{
+		{"\r\nMicrosoft Windows [Version 10.0.22631.2861]\r\n", "10.0.22631.2861"},
+		{"Microsoft Windows [Version 6.1.7601]", "6.1.7601"},
+		{"'ver' is not recognized", ""},
+	}
+	for _, tt := range tests {
+		if got := windowsVersion(tt.out); got != tt.want {
+			t.Errorf("windowsVersion(%q) = %q, want %q", tt.out, got, tt.want)
+		}
+	}
+}
+
+func TestEnviron(t *testing.T) {
+	t.Parallel()
+
+	got := Environ([]string{"HOME=/home/a", "EMPTY=", "OPTS=a=b", "=C:=C:\\", "BROKEN"})
+	want := map[string]string{"HOME": "/home/a", "EMPTY": "", "OPTS": "a=b"}
+	if len(got) != len(want) {
+		t.Fatalf("Environ = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Environ[%q] = %q, want %q", k, got[k], v)
+		}
+	}
+}
+
+func TestLoginName(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{
+		`CORP\pkumar`: "pkumar",
+		"pkumar":      "pkumar",
+		"":            "",
+	} {
+		if got := loginName(in); got != want {
+			t.Errorf("loginName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseLocale(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]string{
+		"pt_BR.UTF-8":       "pt-BR",
+		"de_DE@euro":        "de-DE",
+		"ja":                "ja",
+		"zh-hant-tw":        "zh-Hant-TW",
+		"es-419":            "es-419",
+		"sr_RS.UTF-8@latin": "sr-RS",
+		"C":                 "",
+		"POSIX":             "",
+		"C.UTF-8":           "",
+		"en_":               "",
+		"":                  "",
+	} {
+		if got := ParseLocale(in); got != want {
+			t.Errorf("ParseLocale(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestEnvLocale(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		env  map[string]string
+		want string
+	}{
+		{map[string]string{"LANG": "de_DE.UTF-8"}, "de-DE"},
+		{map[string]string{"LANG": "de_DE.UTF-8", "LC_MESSAGES": "ja_JP.UTF-8"}, "ja-JP"},
+		{map[string]string{"LANG": "de_DE.UTF-8", "LC_ALL": "pt_BR.UTF-8"}, "pt-BR"},
+		{map[string]string{"LANG": "de_DE.UTF-8", "LC_ALL": "C"}, ""},
+		{map[string]string{}, ""},
+	}
+	for _, tt := range tests {
+		if got := EnvLocale(tt.env); got != tt.want {
+			t.Errorf("EnvLocale(%v) = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+// Package i18n holds the translations of the wizard's own strings: the
+// buttons, the page indicator and the built-in final page. Content
+// authors can override any of them, or add a language, with strings: in
+// day1.yml and day1.<locale>.yml.
+package i18n
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Strings use {n} and {total} as placeholders, filled in by the frontend.
+// settings_panel takes the name of a settings panel in place of %s. The
+// terminal front end fills in {time} and {command}, and shows
+// done_text_terminal instead of done_text.
+var builtin = map[string]map[string]string{
+	"en": {
+		"next":               "Next",
+		"finish":             "Finish",
+		"close":              "Close",
+		"snooze":             "Remind me later",
+		"copied":             "Copied to clipboard.",
+		"progress":           "{n} of {total}",
+		"step":               "Step {n}",
+		"help":               "Need Help?",
+		"whats_new":          "What's new since you last completed onboarding",
+		"done_title":         "You're all set!",
+		"done_text":          "You're ready to go. Close this window to get started.",
+		"required":           "Required",
+		"required_left":      "Check the required items to continue.",
+		"mandatory":          "This onboarding is required and can't be closed until it's complete.",
+		"settings":           "Open your system settings.",
+		"settings_panel":     "Open your system settings and go to %s.",
+		"back":               "Back",
+		"toggle":             "Check or uncheck",
+		"completed":          "Onboarding complete.",
+		"snoozed":            "Snoozed until {time}.",
+		"no_help":            "No help link is configured.",
+		"unknown_command":    "Unknown command {command}.",
+		"done_text_terminal": "You're ready to go. Press Enter to finish.",
+	},
+	"de": {
+		"next":               "Weiter",
+		"finish":             "Fertig",
+		"close":              "Schließen",
+		"snooze":             "Später erinnern",
+		"copied":             "In die Zwischenablage kopiert.",
+		"progress":           "{n} von {total}",
+		"step":               "Schritt {n}",
+		"help":               "Hilfe?",
+		"whats_new":          "Neu seit Ihrem letzten Onboarding",
+		"done_title":         "Alles erledigt!",
+		"done_text":          "Sie sind startklar. Schließen Sie dieses Fenster, um loszulegen.",
+		"required":           "Erforderlich",
+		"required_left":      "Haken Sie die erforderlichen Punkte ab, um fortzufahren.",
+		"mandatory":          "Dieses Onboarding ist verpflichtend und kann erst nach Abschluss geschlossen werden.",
+		"settings":           "Öffnen Sie Ihre Systemeinstellungen.",
+		"settings_panel":     "Öffnen Sie Ihre Systemeinstellungen und gehen Sie zu %s.",
+		"back":               "Zurück",
+		"toggle":             "Abhaken oder zurücksetzen",
+		"completed":          "Onboarding abgeschlossen.",
+		"snoozed":            "Zurückgestellt bis {time}.",
+		"no_help":            "Es ist kein Hilfelink eingerichtet.",
+		"unknown_command":    "Unbekannter Befehl {command}.",
+		"done_text_terminal": "Sie sind startklar. Drücken Sie die Eingabetaste, um abzuschließen.",
+	},
+	"es": {
+		"next":               "Siguiente",
+		"finish":             "Terminar",
+		"close":              "Cerrar",
+		"snooze":             "Recordármelo más tarde",
+		"copied":             "Copiado al portapapeles.",
+		"progress":           "{n} de {total}",
+		"step":               "Paso {n}",
+		"help":               "¿Necesitas ayuda?",
+		"whats_new":          "Novedades desde tu última incorporación",
+		"done_title":         "¡Todo listo!",
+		"done_text":          "Ya puedes empezar. Cierra esta ventana para comenzar.",
+		"required":           "Obligatorio",
+		"required_left":      "Marca los elementos obligatorios para continuar.",
+		"mandatory":          "Esta incorporación es obligatoria y no se puede cerrar hasta completarla.",
+		"settings":           "Abra la configuración del sistema.",
+		"settings_panel":     "Abra la configuración del sistema y vaya a %s.",
+		"back":               "Atrás",
+		"toggle":             "Marcar o desmarcar",
+		"completed":          "Incorporación completada.",
+		"snoozed":            "Pospuesto hasta {time}.",
+		"no_help":            "No hay ningún enlace de ayuda configurado.",
+		"unknown_command":    "Comando desconocido {command}.",
+		"done_text_terminal": "Ya puedes empezar. Pulsa Intro para terminar.",
+	},
+	"fr": {
+		"next":               "Suivant",
+		"finish":             "Terminer",
+		"close":              "Fermer",
+		"snooze":             "Me le rappeler plus tard",
+		"copied":             "Copié dans le presse-papiers.",
+		"progress":           "{n} sur {total}",
+		"step":               "Étape {n}",
+		"help":               "Besoin d'aide ?",
+		"whats_new":          "Nouveautés depuis votre dernier accueil",
+		"done_title":         "Tout est prêt !",
+		"done_text":          "Vous êtes prêt. Fermez cette fenêtre pour commencer.",
+		"required":           "Obligatoire",
+		"required_left":      "Cochez les éléments obligatoires pour continuer.",
+		"mandatory":          "Cet accueil est obligatoire et ne peut pas être fermé avant d'être terminé.",
+		"settings":           "Ouvrez les paramètres système.",
+		"settings_panel":     "Ouvrez les paramètres système et allez dans %s.",
+		"back":               "Retour",
+		"toggle":             "Cocher ou décocher",
+		"completed":          "Accueil terminé.",
+		"snoozed":            "Reporté jusqu'à {time}.",
+		"no_help":            "Aucun lien d'aide n'est configuré.",
+		"unknown_command":    "Commande inconnue {command}.",
+		"done_text_terminal": "Vous êtes prêt. Appuyez sur Entrée pour terminer.",
+	},
+	"ja": {
+		"next":               "次へ",
+		"finish":             "完了",
+		"close":              "閉じる",
+		"snooze":             "後で通知",
+		"copied":             "クリップボードにコピーしました。",
+		"progress":           "{n} / {total}",
+		"step":               "ステップ {n}",
+		"help":               "ヘルプ",
+		"whats_new":          "前回のオンボーディング以降の新着情報",
+		"done_title":         "準備完了です！",
+		"done_text":          "準備が整いました。このウィンドウを閉じて始めましょう。",
+		"required":           "必須",
+		"required_left":      "続行するには必須の項目にチェックを入れてください。",
+		"mandatory":          "このオンボーディングは必須のため、完了するまで閉じられません。",
+		"settings":           "システム設定を開いてください。",
+		"settings_panel":     "システム設定を開き、%s に移動してください。",
+		"back":               "戻る",
+		"toggle":             "チェックを切り替え",
+		"completed":          "オンボーディングが完了しました。",
+		"snoozed":            "{time} まで延期しました。",
+		"no_help":            "ヘルプリンクが設定されていません。",
+		"unknown_command":    "不明なコマンドです: {command}",
+		"done_text_terminal": "準備が整いました。Enter キーを押して終了します。",
+	},
+	"pt": {
+		"next":               "Próximo",
+		"finish":             "Concluir",
+		"close":              "Fechar",
+		"snooze":             "Lembrar mais tarde",
+		"copied":             "Copiado para a área de transferência.",
+		"progress":           "{n} de {total}",
+		"step":               "Etapa {n}",
+		"help":               "Precisa de ajuda?",
+		"whats_new":          "Novidades desde a sua última integração",
+		"done_title":         "Tudo pronto!",
+		"done_text":          "Você está pronto. Feche esta janela para começar.",
+		"required":           "Obrigatório",
+		"required_left":      "Marque os itens obrigatórios para continuar.",
+		"mandatory":          "Esta integração é obrigatória e não pode ser fechada antes de ser concluída.",
+		"settings":           "Abra as configurações do sistema.",
+		"settings_panel":     "Abra as configurações do sistema e vá para %s.",
+		"back":               "Voltar",
+		"toggle":             "Marcar ou desmarcar",
+		"completed":          "Integração concluída.",
+		"snoozed":            "Adiado até {time}.",
+		"no_help":            "Nenhum link de ajuda está configurado.",
+		"unknown_command":    "Comando desconhecido {command}.",
+		"done_text_terminal": "Você está pronto. Pressione Enter para concluir.",
+	},
+}
+
+// Keys returns the names of the strings, sorted.
+func Keys() []string {
+	return slices.Sorted(maps.Keys(builtin["en"]))
+}
+
+// IsKey reports whether key names a string.
+func IsKey(key string) bool {
+	_, ok := builtin["en"][key]
+	return ok
+}
+
+// Strings returns every string for locale, a tag such as pt-BR. Each
+// string comes from overrides, the built-in translation of the most
+// specific tag that has one (pt-BR, then pt), or English.
+func Strings(locale string, overrides map[string]string) map[string]string {
+	out := maps.Clone(builtin["en"])
+	tags := subtags(locale)
+	for i := len(tags) - 1; i >= 0; i-- {
+		maps.Copy(out, builtin[tags[i]])
+	}
+	for k, v := range overrides {
+		if IsKey(k) {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// Missing returns the keys that neither overrides nor a built-in
+// translation cover for locale, which are shown in English.
+func Missing(locale string, overrides map[string]string) []string {
+	for _, tag := range subtags(locale) {
+		if _, ok := builtin[tag]; ok {
+			return nil
+		}
+	}
+	if locale == "" {
+		return nil
+	}
+	var out []string
+	for _, k := range Keys() {
+		if _, ok := overrides[k]; !ok {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// subtags returns locale and its shorter prefixes: zh-Hant-TW, zh-Hant,
+// zh.
+func subtags(locale string) []string {
+	var out []string
+	for tag := locale; tag != ""; {
+		out = append(out, tag)
+		i := strings.LastIndex(tag, "-")
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	return out
+}
+
+// Languages and scripts written right to left. A script subtag decides
+// over the language: az-Arab is right to left, az is not.
+var (
+	rtlLanguages = map[string]bool{
+		"ar": true, "ckb": true, "dv": true, "fa": true, "he": true,
+		"ps": true, "sd": true, "ug": true, "ur": true, "yi": true,
+	}
+	rtlScripts = map[string]bool{
+		"adlm": true, "arab": true, "hebr": true, "nkoo": true,
+		"rohg": true, "syrc": true, "thaa": true,
+	}
+)
+
+// Dir returns the direction locale is written in, "rtl" or "ltr", for
+// the dir attribute of the wizard.
+func Dir(locale string) string {
+	parts := strings.Split(strings.ToLower(locale), "-")
+	for _, p := range parts[1:] {
+		if len(p) == 4 {
+			return dirOf(rtlScripts[p])
+		}
+	}
+	return dirOf(rtlLanguages[parts[0]])
+}
+
+func dirOf(rtl bool) string {
+	if rtl {
+		return "rtl"
+	}
+	return "ltr"
+}
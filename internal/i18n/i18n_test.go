@@ -0,0 +1,99 @@
+package i18n
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestStrings(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		locale    string
+		overrides map[string]string
+		key       string
+		want      string
+	}{
+		{name: "english", locale: "en", key: "next", want: "Next"},
+		{name: "unknown locale is english", locale: "he", key: "finish", want: "Finish"},
+		{name: "empty locale is english", key: "close", want: "Close"},
+		{name: "region falls back to language", locale: "pt-BR", key: "next", want: "Próximo"},
+		{name: "script and region fall back", locale: "de-Latn-AT", key: "progress", want: "{n} von {total}"},
+		{name: "override", locale: "de", overrides: map[string]string{"next": "Los"}, key: "next", want: "Los"},
+		{name: "override adds a language", locale: "he", overrides: map[string]string{"next": "הבא"}, key: "next", want: "הבא"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := Strings(tt.locale, tt.overrides)
+			if got[tt.key] != tt.want {
+				t.Errorf("Strings(%q)[%q] = %q, want %q", tt.locale, tt.key, got[tt.key], tt.want)
+			}
+			if len(got) != len(Keys()) {
+				t.Errorf("Strings(%q) has %d strings, want %d", tt.locale, len(got), len(Keys()))
+			}
+		})
+	}
+
+	if got := Strings("en", map[string]string{"nope": "x"}); got["nope"] != "" {
+		t.Error("Strings kept an unknown override key")
+	}
+}
+
+func TestBuiltinComplete(t *testing.T) {
+	t.Parallel()
+	for tag, strs := range builtin {
+		for _, k := range Keys() {
+			if strs[k] == "" {
+				t.Errorf("builtin %s has no %q", tag, k)
+			}
+		}
+		if len(strs) != len(Keys()) {
+			t.Errorf("builtin %s has %d strings, want %d", tag, len(strs), len(Keys()))
+		}
+	}
+}
+
+func TestMissing(t *testing.T) {
+	t.Parallel()
+
+	if got := Missing("de", nil); got != nil {
+		t.Errorf("Missing(de) = %q, want none", got)
+	}
+	if got := Missing("pt-BR", nil); got != nil {
+		t.Errorf("Missing(pt-BR) = %q, want none", got)
+	}
+	if got := Missing("he", nil); !slices.Equal(got, Keys()) {
+		t.Errorf("Missing(he) = %q, want every key", got)
+	}
+	got := Missing("he", map[string]string{"next": "הבא", "finish": "סיום"})
+	if slices.Contains(got, "next") || slices.Contains(got, "finish") || len(got) != len(Keys())-2 {
+		t.Errorf("Missing(he) with overrides = %q", got)
+	}
+}
+
+func TestDir(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		locale string
+		want   string
+	}{
+		{"", "ltr"},
+		{"en", "ltr"},
+		{"de-AT", "ltr"},
+		{"he", "rtl"},
+		{"ar-EG", "rtl"},
+		{"fa-IR", "rtl"},
+		{"az-Arab", "rtl"},
+		{"az-Latn-AZ", "ltr"},
+		{"uz-Arab-AF", "rtl"},
+		{"sd-Deva", "ltr"},
+	}
+	for _, tt := range tests {
+		if got := Dir(tt.locale); got != tt.want {
+			t.Errorf("Dir(%q) = %q, want %q", tt.locale, got, tt.want)
+		}
+	}
+}
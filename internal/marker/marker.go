@@ -3,9 +3,13 @@
 package marker
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,6 +18,31 @@ const (
 	fileName = ".completed"
 )
 
+// State is what the sentinel records about a completed run.
+type State struct {
+	CompletedAt time.Time `json:"completed_at"`
+	// Version is the content version that was completed: content_version
+	// from day1.yml, or a hash of the pages. Empty for sentinels written
+	// before versioning.
+	Version string `json:"version,omitempty"`
+	// Pages maps page ID to content hash, so a re-run can show only the
+	// pages that changed.
+	Pages map[string]string `json:"pages,omitempty"`
+}
+
+// OlderThan reports whether s was completed for an older content version
+// than version. Dotted numeric versions ("2", "1.10") compare numerically;
+// anything else (e.g. content hashes) is older whenever it differs.
+func (s State) OlderThan(version string) bool {
+	if s.Version == version {
+		return false
+	}
+	if c, ok := compareNumeric(s.Version, version); ok {
+		return c < 0
+	}
+	return true
+}
+
 // Dir returns the platform-appropriate config directory for day1 state files.
 func Dir() (string, error) {
 	base, err := os.UserConfigDir()
@@ -23,7 +52,8 @@ func Dir() (string, error) {
 	return filepath.Join(base, appDir), nil
 }
 
-func path() (string, error) {
+// Path returns the location of the sentinel file.
+func Path() (string, error) {
 	dir, err := Dir()
 	if err != nil {
 		return "", err
@@ -32,7 +62,7 @@ func path() (string, error) {
 }
 
 func Exists() (bool, error) {
-	p, err := path()
+	p, err := Path()
 	if err != nil {
 		return false, err
 	}
@@ -43,19 +73,58 @@ func Exists() (bool, error) {
 	return err == nil, err
 }
 
+// Read returns the sentinel state. ok is false when no sentinel exists.
+// Sentinels holding only a timestamp are read as a State without Version.
+func Read() (s State, ok bool, err error) {
+	p, err := Path()
+	if err != nil {
+		return State{}, false, err
+	}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return State{}, false, nil
+	}
+	if err != nil {
+		return State{}, false, err
+	}
+
+	data = bytes.TrimSpace(data)
+	if bytes.HasPrefix(data, []byte("{")) {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return State{}, true, fmt.Errorf("parse sentinel: %w", err)
+		}
+		return s, true, nil
+	}
+	s.CompletedAt, _ = time.Parse(time.RFC3339, string(data))
+	return s, true, nil
+}
+
+// Write records completion without a content version.
 func Write() error {
-	p, err := path()
+	return WriteState(State{})
+}
+
+// WriteState records completion. A zero CompletedAt is set to now.
+func WriteState(s State) error {
+	p, err := Path()
 	if err != nil {
 		return err
 	}
 	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
 		return fmt.Errorf("mkdir: %w", err)
 	}
-	return os.WriteFile(p, []byte(time.Now().UTC().Format(time.RFC3339)+"\n"), 0o644)
+	if s.CompletedAt.IsZero() {
+		s.CompletedAt = time.Now().UTC().Truncate(time.Second)
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal sentinel: %w", err)
+	}
+	return os.WriteFile(p, append(data, '\n'), 0o644)
 }
 
 func Remove() error {
-	p, err := path()
+	p, err := Path()
 	if err != nil {
 		return err
 	}
@@ -65,3 +134,49 @@ func Remove() error {
 	}
 	return err
 }
+
+// compareNumeric compares dotted numeric versions, with an optional "v"
+// prefix. ok is false if either isn't numeric.
+func compareNumeric(a, b string) (int, bool) {
+	pa, ok := parseNumeric(a)
+	if !ok {
+		return 0, false
+	}
+	pb, ok := parseNumeric(b)
+	if !ok {
+		return 0, false
+	}
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x != y {
+			if x < y {
+				return -1, true
+			}
+			return 1, true
+		}
+	}
+	return 0, true
+}
+
+func parseNumeric(v string) ([]int, bool) {
+	v = strings.TrimPrefix(v, "v")
+	if v == "" {
+		return nil, false
+	}
+	parts := strings.Split(v, ".")
+	out := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
@@ -113,3 +113,102 @@ func setConfigHome(t *testing.T, tmp string) {
 		t.Setenv("LOCALAPPDATA", tmp)
 	}
 }
+
+func TestReadState(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		wantVersion string
+		wantPages   int
+		wantTime    bool
+		wantErr     bool
+	}{
+		{"legacy timestamp", "2025-01-02T03:04:05Z\n", "", 0, true, false},
+		{"json state", `{"completed_at":"2025-01-02T03:04:05Z","version":"3","pages":{"welcome":"abc"}}`, "3", 1, true, false},
+		{"corrupt json", `{"version":`, "", 0, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmp := t.TempDir()
+			setConfigHome(t, tmp)
+			p := filepath.Join(tmp, appDir, fileName)
+			if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(p, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			st, ok, err := Read()
+			if !ok {
+				t.Fatal("Read: ok = false, want true")
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read: %v", err)
+			}
+			if st.Version != tt.wantVersion {
+				t.Errorf("Version = %q, want %q", st.Version, tt.wantVersion)
+			}
+			if len(st.Pages) != tt.wantPages {
+				t.Errorf("len(Pages) = %d, want %d", len(st.Pages), tt.wantPages)
+			}
+			if st.CompletedAt.IsZero() == tt.wantTime {
+				t.Errorf("CompletedAt = %v, want set = %v", st.CompletedAt, tt.wantTime)
+			}
+		})
+	}
+}
+
+func TestWriteStateRoundTrip(t *testing.T) {
+	setConfigHome(t, t.TempDir())
+
+	if _, ok, err := Read(); ok || err != nil {
+		t.Fatalf("Read before write: ok = %v, err = %v", ok, err)
+	}
+	want := State{Version: "1.2", Pages: map[string]string{"welcome": "abc"}}
+	if err := WriteState(want); err != nil {
+		t.Fatalf("WriteState: %v", err)
+	}
+	got, ok, err := Read()
+	if err != nil || !ok {
+		t.Fatalf("Read: ok = %v, err = %v", ok, err)
+	}
+	if got.Version != want.Version || got.Pages["welcome"] != "abc" || got.CompletedAt.IsZero() {
+		t.Errorf("Read() = %+v, want %+v with a timestamp", got, want)
+	}
+}
+
+func TestOlderThan(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		completed string
+		current   string
+		want      bool
+	}{
+		{"1", "2", true},
+		{"2", "2", false},
+		{"3", "2", false},
+		{"1.9", "1.10", true},
+		{"v1.2", "1.2.0", false},
+		{"", "1", true},
+		{"abc123", "def456", true},
+		{"abc123", "abc123", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.completed+"->"+tt.current, func(t *testing.T) {
+			t.Parallel()
+			if got := (State{Version: tt.completed}).OlderThan(tt.current); got != tt.want {
+				t.Errorf("OlderThan(%q, %q) = %v, want %v", tt.completed, tt.current, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package pages
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	goldrenderer "github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// ChecklistItem is a markdown task list item ("- [ ] ...") with an identity
+// that survives reordering and edits to other items.
+type ChecklistItem struct {
+	// ID is the explicit {#id} marker at the end of the item, or a short
+	// hash of its normalized text.
+	ID   string
+	Text string
+	// Required items are marked {#id required} or {required} and must be
+	// checked before the wizard moves past their page or completes.
+	Required bool
+}
+
+var (
+	checkIDMarkerRe = regexp.MustCompile(`\s*\{(?:#([A-Za-z0-9_.-]+)(\s+required)?|(required))\}\s*$`)
+	validIDRe       = regexp.MustCompile(`^[A-Za-z0-9_./-]+$`)
+	spaceRe         = regexp.MustCompile(`\s+`)
+)
+
+const (
+	checkIDAttr       = "data-check-id"
+	checkRequiredAttr = "data-required"
+)
+
+// Checklist returns the task list items of markdown in document order.
+// Items in ::: directive blocks that opts drop are left out; IDs are
+// assigned before blocks are dropped, so they don't depend on the machine.
+func Checklist(markdown string, opts ...RenderOption) []ChecklistItem {
+	doc, source := Parse(markdown, opts...)
+
+	var items []ChecklistItem
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		cb, ok := n.(*extast.TaskCheckBox)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		var id string
+		if v, ok := cb.AttributeString(checkIDAttr); ok {
+			id = string(v.([]byte))
+		}
+		_, required := cb.AttributeString(checkRequiredAttr)
+		items = append(items, ChecklistItem{
+			ID:       id,
+			Text:     strings.TrimSpace(plainText(cb.Parent(), source)),
+			Required: required,
+		})
+		return ast.WalkSkipChildren, nil
+	})
+	return items
+}
+
+// ValidID reports whether id may be used as a page or checklist item ID.
+func ValidID(id string) bool { return validIDRe.MatchString(id) }
+
+// checklistTransformer strips {#id} markers from task list items and
+// records each item's ID, and whether it is required, as attributes of
+// its checkbox.
+type checklistTransformer struct{}
+
+func (checklistTransformer) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	assignChecklistIDs(doc, reader.Source())
+}
+
+// assignChecklistIDs walks doc for task checkboxes, removes trailing {#id}
+// markers from their text and sets the data-check-id attribute, and
+// data-required for required items. Duplicate IDs within a page get a
+// numeric suffix so every item stays addressable.
+func assignChecklistIDs(doc ast.Node, source []byte) {
+	seen := map[string]int{}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		cb, ok := n.(*extast.TaskCheckBox)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+
+		id, required := stripIDMarker(cb.Parent(), source)
+		if required {
+			cb.SetAttributeString(checkRequiredAttr, []byte{})
+		}
+		if id == "" {
+			id = hashID(plainText(cb.Parent(), source))
+		}
+		seen[id]++
+		if seen[id] > 1 {
+			id = fmt.Sprintf("%s-%d", id, seen[id])
+		}
+		cb.SetAttributeString(checkIDAttr, []byte(id))
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// stripIDMarker removes a trailing {#id}, {#id required} or {required}
+// from the text at the end of block and returns the id, or "" if there is
+// none, and whether the item is required. The marker can span several
+// text nodes, since GFM's linkify splits text at spaces.
+func stripIDMarker(block ast.Node, source []byte) (id string, required bool) {
+	last, ok := block.LastChild().(*ast.Text)
+	if !ok {
+		return "", false
+	}
+	first := last
+	for {
+		prev, ok := first.PreviousSibling().(*ast.Text)
+		if !ok || prev.Segment.Stop != first.Segment.Start {
+			break
+		}
+		first = prev
+	}
+	start := first.Segment.Start
+	value := source[start:last.Segment.Stop]
+	m := checkIDMarkerRe.FindSubmatchIndex(value)
+	if m == nil {
+		return "", false
+	}
+	if m[2] >= 0 {
+		id = string(value[m[2]:m[3]])
+	}
+	cut := start + m[0]
+	for n := ast.Node(first); n != nil; {
+		next := n.NextSibling()
+		t := n.(*ast.Text)
+		switch {
+		case t.Segment.Start >= cut:
+			block.RemoveChild(block, t)
+		case t.Segment.Stop > cut:
+			t.Segment = text.NewSegment(t.Segment.Start, cut)
+		}
+		n = next
+	}
+	return id, m[4] >= 0 || m[6] >= 0
+}
+
+// plainText concatenates the text content below n.
+func plainText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch t := c.(type) {
+		case *ast.Text:
+			b.Write(t.Segment.Value(source))
+			if t.SoftLineBreak() || t.HardLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(t.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// hashID derives an ID from item text, ignoring case and whitespace.
+func hashID(s string) string {
+	norm := strings.ToLower(spaceRe.ReplaceAllString(strings.TrimSpace(s), " "))
+	sum := sha256.Sum256([]byte(norm))
+	return hex.EncodeToString(sum[:4])
+}
+
+// checkBoxRenderer renders task checkboxes like the GFM extension but
+// includes the data-check-id attribute.
+type checkBoxRenderer struct{}
+
+func (checkBoxRenderer) RegisterFuncs(reg goldrenderer.NodeRendererFuncRegisterer) {
+	reg.Register(extast.KindTaskCheckBox, renderCheckBox)
+}
+
+func renderCheckBox(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		return ast.WalkContinue, nil
+	}
+	n := node.(*extast.TaskCheckBox)
+	w.WriteString(`<input`)
+	if id, ok := n.AttributeString(checkIDAttr); ok {
+		fmt.Fprintf(w, ` %s="%s"`, checkIDAttr, util.EscapeHTML(id.([]byte)))
+	}
+	if n.IsChecked {
+		w.WriteString(` checked=""`)
+	}
+	w.WriteString(` disabled="" type="checkbox"> `)
+	return ast.WalkContinue, nil
+}
@@ -1,10 +1,12 @@
 package pages
 
 import (
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"time"
 
+	"github.com/TsekNet/day1/internal/urischeme"
 	"gopkg.in/yaml.v3"
 )
 
@@ -16,20 +18,157 @@ type Brand struct {
 }
 
 type Config struct {
-	Brand       Brand    `yaml:"brand"`
-	HelpURL     string   `yaml:"help_url"`
-	Theme       string   `yaml:"theme"`
-	Title       string   `yaml:"title"`
-	AccentColor string   `yaml:"accent_color"`
-	FinalPage   string   `yaml:"final_page"`
-	Pages       []string `yaml:"pages"`
-}
-
-// LoadConfig reads day1.yml from pagesDir. Returns zero Config if the file
-// doesn't exist.
-func LoadConfig(pagesDir string) (Config, error) {
-	data, err := os.ReadFile(filepath.Join(pagesDir, configFileName))
-	if os.IsNotExist(err) {
+	Brand       Brand       `yaml:"brand"`
+	HelpURL     string      `yaml:"help_url"`
+	Theme       string      `yaml:"theme"`
+	Title       string      `yaml:"title"`
+	AccentColor string      `yaml:"accent_color"`
+	FinalPage   string      `yaml:"final_page"`
+	Pages       []PageEntry `yaml:"pages"`
+	// ContentVersion re-shows the wizard to users who completed an older
+	// version. When empty, a hash of the loaded pages is used instead.
+	ContentVersion string            `yaml:"content_version"`
+	Links          LinkConfig        `yaml:"links"`
+	Actions        map[string]Action `yaml:"actions"`
+	// Vars are custom values for page templates, as {{ .Vars.name }}.
+	Vars map[string]string `yaml:"vars"`
+	// EnvPrefix is the prefix of the environment variables page templates
+	// can read. Defaults to DefaultEnvPrefix.
+	EnvPrefix string `yaml:"env_prefix"`
+	// Locale is the language of pages without a locale suffix. Defaults
+	// to DefaultLocale.
+	Locale string `yaml:"locale"`
+	// Strings override the wizard's own strings, such as the Next button,
+	// for the default language. day1.<locale>.yml sets them for others.
+	Strings map[string]string `yaml:"strings"`
+	// Mode is ModeMandatory to keep the wizard from being closed before
+	// it is completed. Defaults to ModeOptional.
+	Mode string `yaml:"mode"`
+	// Snooze configures "Remind me later" and how often the wizard can be
+	// put off before it becomes mandatory.
+	Snooze SnoozeConfig `yaml:"snooze"`
+}
+
+// Values of Config.Mode.
+const (
+	ModeOptional  = "optional"
+	ModeMandatory = "mandatory"
+)
+
+// Mandatory reports whether the wizard may only be left by completing it.
+func (c Config) Mandatory() bool { return c.Mode == ModeMandatory }
+
+// SnoozeConfig is the snooze section of day1.yml.
+type SnoozeConfig struct {
+	// Duration is how long "Remind me later" hides the wizard, as a Go
+	// duration like 4h. "0" turns snoozing off. Defaults to
+	// DefaultSnooze.
+	Duration string `yaml:"duration"`
+	// Max is how many times the wizard can be snoozed or closed before it
+	// becomes mandatory. 0 means no limit.
+	Max int `yaml:"max"`
+}
+
+// DefaultSnooze is how long "Remind me later" hides the wizard by default.
+const DefaultSnooze = 24 * time.Hour
+
+// Interval returns Duration, or DefaultSnooze when it is empty.
+func (s SnoozeConfig) Interval() (time.Duration, error) {
+	if s.Duration == "" {
+		return DefaultSnooze, nil
+	}
+	d, err := time.ParseDuration(s.Duration)
+	if err != nil {
+		return 0, fmt.Errorf("snooze duration %q must be a duration like 4h or 30m", s.Duration)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("snooze duration %q must not be negative", s.Duration)
+	}
+	return d, nil
+}
+
+func (c Config) envPrefix() string {
+	if c.EnvPrefix == "" {
+		return DefaultEnvPrefix
+	}
+	return c.EnvPrefix
+}
+
+// Action is what a day1:action/<name> link does: open URL, or copy Copy to
+// the clipboard.
+type Action struct {
+	URL  string `yaml:"url"`
+	Copy string `yaml:"copy"`
+}
+
+// PageEntry is an item of the pages list: a file name, or a mapping with
+// the file and a condition that applies on top of the page's own.
+//
+//	pages:
+//	  - welcome.md
+//	  - file: vpn.md
+//	    when: {group: engineering}
+type PageEntry struct {
+	File string `yaml:"file"`
+	When *When  `yaml:"when"`
+}
+
+func (e *PageEntry) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*e = PageEntry{File: n.Value}
+		return nil
+	}
+	type plain PageEntry
+	return n.Decode((*plain)(e))
+}
+
+// LinkConfig extends the URI allow-list: extra schemes that links may open and
+// optional host patterns for web links, e.g. "*.example.com".
+type LinkConfig struct {
+	Schemes    []LinkScheme `yaml:"schemes"`
+	AllowHosts []string     `yaml:"allow_hosts"`
+	DenyHosts  []string     `yaml:"deny_hosts"`
+}
+
+// LinkScheme is an extra allowed URI scheme such as slack or mailto.
+type LinkScheme struct {
+	Scheme string `yaml:"scheme"`
+	// Kind is web, settings or app. Defaults to app.
+	Kind string `yaml:"kind"`
+	// Platform limits the scheme to one platform. Defaults to all.
+	Platform string `yaml:"platform"`
+}
+
+// LinkPolicy returns the built-in URI policy extended by the links
+// section.
+func (c Config) LinkPolicy() (*urischeme.Policy, error) {
+	extra := make([]urischeme.Scheme, 0, len(c.Links.Schemes))
+	for _, ls := range c.Links.Schemes {
+		kind := urischeme.KindApp
+		if ls.Kind != "" {
+			k, err := urischeme.ParseKind(ls.Kind)
+			if err != nil {
+				return nil, fmt.Errorf("scheme %q: %w", ls.Scheme, err)
+			}
+			kind = k
+		}
+		platform := ls.Platform
+		if platform == "all" {
+			platform = ""
+		}
+		if platform != "" && !IsPlatform(platform) {
+			return nil, fmt.Errorf("scheme %q: unknown platform %q", ls.Scheme, ls.Platform)
+		}
+		extra = append(extra, urischeme.Scheme{Prefix: ls.Scheme, Kind: kind, Platform: platform})
+	}
+	return urischeme.NewPolicy(extra, c.Links.AllowHosts, c.Links.DenyHosts)
+}
+
+// LoadConfig reads day1.yml from the root of fsys. Returns zero Config if
+// the file doesn't exist.
+func LoadConfig(fsys fs.FS) (Config, error) {
+	data, err := fs.ReadFile(fsys, configFileName)
+	if errors.Is(err, fs.ErrNotExist) {
 		return Config{}, nil
 	}
 	if err != nil {
@@ -0,0 +1,98 @@
+package pages
+
+import (
+	"unicode"
+
+	"github.com/TsekNet/day1/internal/i18n"
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+)
+
+// Dirs are the values of dir: in frontmatter.
+var Dirs = []string{"ltr", "rtl", "auto"}
+
+// Dir returns the direction the page is written in: its dir:
+// frontmatter, or the direction of the language of its translation, or
+// of def for the original.
+func (p Page) Dir(def string) string {
+	switch {
+	case p.Frontmatter.Dir != "":
+		return p.Frontmatter.Dir
+	case p.Locale != "":
+		return i18n.Dir(p.Locale)
+	}
+	return i18n.Dir(def)
+}
+
+// markMixedDir sets dir="auto" on the headings, paragraphs, list items
+// and table cells under doc that have text written in the other
+// direction than dir, so the browser lays each out by its own first
+// strong character: an English command in a Hebrew page, or an Arabic
+// name in an English one. Code is not counted.
+func markMixedDir(doc ast.Node, source []byte, dir string) {
+	other := "rtl"
+	if dir == "rtl" {
+		other = "ltr"
+	}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.(type) {
+		case *ast.Heading, *ast.Paragraph, *extast.TableCell:
+			if hasDir(n, source, other) {
+				n.SetAttributeString("dir", "auto")
+			}
+		case *ast.ListItem:
+			// A tight item's text is in TextBlocks, which render without
+			// an element of their own; nested lists are marked separately.
+			for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+				if _, ok := c.(*ast.TextBlock); ok && hasDir(c, source, other) {
+					n.SetAttributeString("dir", "auto")
+					break
+				}
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// hasDir reports whether the text under n, outside code, has a strong
+// character written in direction dir.
+func hasDir(n ast.Node, source []byte, dir string) bool {
+	found := false
+	ast.Walk(n, func(c ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var text []byte
+		switch c := c.(type) {
+		case *ast.CodeSpan:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			text = c.Segment.Value(source)
+		case *ast.String:
+			text = c.Value
+		}
+		for _, r := range string(text) {
+			if runeDir(r) == dir {
+				found = true
+				return ast.WalkStop, nil
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return found
+}
+
+// runeDir returns the direction of a strong character, or "" for
+// digits, punctuation and other weak or neutral characters.
+func runeDir(r rune) string {
+	switch {
+	case unicode.In(r, unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko, unicode.Adlam, unicode.Hanifi_Rohingya):
+		return "rtl"
+	case unicode.IsLetter(r):
+		return "ltr"
+	}
+	return ""
+}
@@ -0,0 +1,225 @@
+package pages
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/platform"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"gopkg.in/yaml.v3"
+)
+
+// Directive blocks show part of a page only on some machines:
+//
+//	::: platform windows
+//	Open **Settings > Privacy & security**.
+//	:::
+//
+//	::: platform !windows
+//	Open **System Settings > Privacy & Security**.
+//	:::
+//
+//	::: when group: engineering, os_version: ">= 14"
+//	Request access to the build farm.
+//	:::
+//
+// "platform" takes platforms, one of which must match, and !platforms,
+// none of which may. "when" takes the keys of a page's when: condition
+// as a YAML flow mapping. Blocks nest; a line of only ::: closes the
+// innermost one.
+
+var kindDirective = ast.NewNodeKind("Directive")
+
+type directive struct {
+	ast.BaseBlock
+	// start is the offset of the opening line in the source.
+	start int
+	name  string
+	cond  *When
+	// not lists the platforms of !platform arguments.
+	not    []string
+	err    error
+	closed bool
+}
+
+func (d *directive) Kind() ast.NodeKind { return kindDirective }
+
+func (d *directive) Dump(source []byte, level int) {
+	ast.DumpHelper(d, source, level, map[string]string{"Name": d.name}, nil)
+}
+
+// shows reports whether the block is kept for a machine with facts f.
+// With osOnly only platforms and when.os are evaluated. Invalid blocks
+// are never kept, so a typo can't show a block to every machine;
+// validate reports them.
+func (d *directive) shows(f facts.Facts, osOnly bool) bool {
+	if d.err != nil {
+		return false
+	}
+	for _, p := range d.not {
+		if platform.Matches(p, f.OS) {
+			return false
+		}
+	}
+	if osOnly {
+		return d.cond.checkOS(f.OS) == nil
+	}
+	return d.cond.Check(f) == nil
+}
+
+var (
+	directiveOpenRe  = regexp.MustCompile(`^:::[ \t]*([A-Za-z]+)(?:[ \t]+(.*?))?[ \t]*$`)
+	directiveCloseRe = regexp.MustCompile(`^:::[ \t]*$`)
+)
+
+// parseDirective returns the condition of a "::: name args" line.
+func parseDirective(name, args string) (cond *When, not []string, err error) {
+	cond = &When{}
+	switch name {
+	case "platform":
+		fields := strings.Fields(args)
+		if len(fields) == 0 {
+			return cond, nil, fmt.Errorf("::: platform needs at least one platform")
+		}
+		for _, p := range fields {
+			negated := strings.HasPrefix(p, "!")
+			p = strings.TrimPrefix(p, "!")
+			if !IsPlatform(p) {
+				return cond, nil, fmt.Errorf("::: platform: unknown platform %q", p)
+			}
+			if negated {
+				not = append(not, p)
+			} else {
+				cond.OS = append(cond.OS, p)
+			}
+		}
+		return cond, not, nil
+	case "when":
+		if strings.TrimSpace(args) == "" {
+			return cond, nil, fmt.Errorf("::: when needs a condition")
+		}
+		if err := decodeStrict([]byte("{"+args+"}"), cond); err != nil {
+			return cond, nil, fmt.Errorf("::: when: %s", yamlMessage(err))
+		}
+		return cond, nil, cond.validate()
+	}
+	return cond, nil, fmt.Errorf("unknown directive ::: %s (want platform or when)", name)
+}
+
+// yamlMessage flattens the errors of decoding one line of YAML into one
+// message without line numbers.
+func yamlMessage(err error) string {
+	msgs := []string{strings.TrimPrefix(err.Error(), "yaml: ")}
+	var te *yaml.TypeError
+	if errors.As(err, &te) {
+		msgs = te.Errors
+	}
+	out := make([]string, len(msgs))
+	for i, msg := range msgs {
+		out[i] = msg
+		if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+			out[i] = m[2]
+		}
+	}
+	return strings.Join(out, "; ")
+}
+
+// directiveParser opens a directive block on a "::: name args" line and
+// closes it on a line of only ":::".
+type directiveParser struct{}
+
+func (directiveParser) Trigger() []byte { return []byte{':'} }
+
+func (directiveParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	line, segment := reader.PeekLine()
+	pos := pc.BlockOffset()
+	if pos < 0 {
+		return nil, parser.NoChildren
+	}
+	m := directiveOpenRe.FindSubmatch(util.TrimRightSpace(line[pos:]))
+	if m == nil {
+		return nil, parser.NoChildren
+	}
+	d := &directive{start: segment.Start, name: string(m[1])}
+	d.cond, d.not, d.err = parseDirective(d.name, string(m[2]))
+	reader.Advance(segment.Len() - trailingNewline(line))
+	return d, parser.HasChildren
+}
+
+func (directiveParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	line, segment := reader.PeekLine()
+	w, pos := util.IndentWidth(line, reader.LineOffset())
+	if w < 4 && directiveCloseRe.Match(util.TrimRightSpace(line[pos:])) && innermost(node, pc) {
+		node.(*directive).closed = true
+		reader.Advance(segment.Len() - trailingNewline(line))
+		return parser.Close
+	}
+	return parser.Continue | parser.HasChildren
+}
+
+// innermost reports whether node is the open block a ":::" line closes:
+// no directive or fenced code block is open inside it.
+func innermost(node ast.Node, pc parser.Context) bool {
+	blocks := pc.OpenedBlocks()
+	for i := len(blocks) - 1; i >= 0 && blocks[i].Node != node; i-- {
+		switch blocks[i].Node.(type) {
+		case *directive, *ast.FencedCodeBlock:
+			return false
+		}
+	}
+	return true
+}
+
+func trailingNewline(line []byte) int {
+	if bytes.HasSuffix(line, []byte("\n")) {
+		return 1
+	}
+	return 0
+}
+
+func (directiveParser) Close(ast.Node, text.Reader, parser.Context) {}
+
+func (directiveParser) CanInterruptParagraph() bool { return true }
+
+func (directiveParser) CanAcceptIndentedLine() bool { return false }
+
+// applyDirectives removes the directive blocks of doc that show rejects
+// and unwraps the others, so renderers never see a directive node. A nil
+// show keeps every block.
+func applyDirectives(doc ast.Node, show func(*directive) bool) {
+	for _, d := range findDirectives(doc) {
+		parent := d.Parent()
+		if show == nil || show(d) {
+			for c := d.FirstChild(); c != nil; c = d.FirstChild() {
+				d.RemoveChild(d, c)
+				parent.InsertBefore(parent, d, c)
+			}
+		}
+		parent.RemoveChild(parent, d)
+	}
+}
+
+// directives returns the directive blocks of markdown in document order,
+// for validation.
+func directives(markdown string) ([]*directive, []byte) {
+	source := []byte(markdown)
+	return findDirectives(renderer.Parser().Parse(text.NewReader(source))), source
+}
+
+func findDirectives(doc ast.Node) []*directive {
+	var out []*directive
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if d, ok := n.(*directive); ok && entering {
+			out = append(out, d)
+		}
+		return ast.WalkContinue, nil
+	})
+	return out
+}
@@ -0,0 +1,162 @@
+package pages
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// An include action splices a shared markdown file into a page:
+//
+//	{{ include "snippets/it-contact.md" }}
+//
+// The path is relative to the pages dir and, like day1.yml page paths,
+// may not be absolute or contain "..". The included file may have
+// frontmatter: when its platform or when: doesn't match the machine it
+// includes nothing. Includes nest; a file that includes itself, directly
+// or through others, fails the load. Included files are never pages,
+// even when they sit next to the pages.
+//
+// Includes are spliced before templates are expanded, so an included
+// file can use template fields and an include inside {{ if }} is only
+// shown when the condition holds.
+
+var includeRe = regexp.MustCompile(`\{\{[ \t]*include[ \t]+"([^"{}\n]*)"[ \t]*\}\}`)
+
+const includeUsage = `include takes one quoted path, like {{ include "snippets/it-contact.md" }}`
+
+// includeFunc is the template function an include action reaches only
+// when it isn't a plain quoted path.
+func includeFunc(string) (string, error) {
+	return "", errors.New(includeUsage)
+}
+
+// validPagePath reports whether name is a relative path inside the pages
+// dir without "..".
+func validPagePath(name string) bool {
+	return !strings.Contains(name, "..") && fs.ValidPath(name)
+}
+
+// includer splices include actions. show decides whether an included
+// file's frontmatter matches; nil includes every file. Included files are
+// translated for the locale tags of chain like pages.
+type includer struct {
+	fsys  fs.FS
+	show  func(Frontmatter) error
+	chain []string
+	stack []string
+}
+
+// storedSource returns the file name as stored followed by the files its
+// include actions name, recursively and whether or not they are shown,
+// for Page.Hash. Missing files and cycles are left to splice to report.
+func storedSource(fsys fs.FS, name string, seen map[string]bool) string {
+	if seen[name] {
+		return ""
+	}
+	seen[name] = true
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return ""
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\x00%s\x00", name, data)
+	for _, m := range includeRe.FindAllStringSubmatch(string(data), -1) {
+		if validPagePath(m[1]) {
+			b.WriteString(storedSource(fsys, m[1], seen))
+		}
+	}
+	return b.String()
+}
+
+// splice replaces the include actions of markdown from file name with
+// the files they include.
+func (in *includer) splice(name, markdown string) (string, error) {
+	if !strings.Contains(markdown, "{{") {
+		return markdown, nil
+	}
+	in.stack = append(in.stack, name)
+	defer func() { in.stack = in.stack[:len(in.stack)-1] }()
+
+	var err error
+	out := includeRe.ReplaceAllStringFunc(markdown, func(action string) string {
+		if err != nil {
+			return ""
+		}
+		var s string
+		s, err = in.include(name, includeRe.FindStringSubmatch(action)[1])
+		return s
+	})
+	return out, err
+}
+
+func (in *includer) include(from, file string) (string, error) {
+	if !validPagePath(file) {
+		return "", fmt.Errorf("%s: invalid include path: %s", from, file)
+	}
+	if i := slices.Index(in.stack, file); i >= 0 {
+		chain := append(slices.Clone(in.stack[i:]), file)
+		return "", fmt.Errorf("%s: include cycle: %s", from, strings.Join(chain, " -> "))
+	}
+	read := file
+	if t, _ := translation(in.fsys, file, in.chain); t != "" {
+		read = t
+	}
+	raw, err := fs.ReadFile(in.fsys, read)
+	if err != nil {
+		return "", fmt.Errorf("%s: include %s: %w", from, file, err)
+	}
+	fm, body, err := ParseFrontmatter(string(raw), read)
+	if err != nil {
+		return "", err
+	}
+	if in.show != nil && in.show(fm) != nil {
+		return "", nil
+	}
+	out, err := in.splice(file, body)
+	return strings.TrimRight(out, "\n"), err
+}
+
+// ExpandIncludes splices the include actions of markdown from file name,
+// reading included files from fsys. WithFacts and WithPlatform skip the
+// files whose frontmatter doesn't match; without them every file is
+// included. WithLocale includes their translations.
+func ExpandIncludes(fsys fs.FS, name, markdown string, opts ...RenderOption) (string, error) {
+	rc := newRenderConfig(opts)
+	cfg, _ := LoadConfig(fsys)
+	in := &includer{fsys: fsys, chain: cfg.localeChain(rc.locale)}
+	if show := rc.include; show != nil {
+		in.show = func(fm Frontmatter) error { return show(fm, nil) }
+	}
+	return in.splice(name, markdown)
+}
+
+// includedFiles returns the files the include actions of pages name,
+// directly or through other included files, whether or not they match
+// this machine. Unreadable files are left for validate to report.
+func includedFiles(fsys fs.FS, pages []Page) map[string]bool {
+	out := map[string]bool{}
+	var queue []string
+	add := func(markdown string) {
+		for _, m := range includeRe.FindAllStringSubmatch(markdown, -1) {
+			if file := m[1]; validPagePath(file) && !out[file] {
+				out[file] = true
+				queue = append(queue, file)
+			}
+		}
+	}
+	for _, p := range pages {
+		add(p.Markdown)
+	}
+	for len(queue) > 0 {
+		file := queue[0]
+		queue = queue[1:]
+		if raw, err := fs.ReadFile(fsys, file); err == nil {
+			add(string(raw))
+		}
+	}
+	return out
+}
@@ -2,61 +2,209 @@ package pages
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
-	"runtime"
+	"io/fs"
+	"slices"
 	"sort"
 	"strings"
+
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/platform"
 )
 
-func Load(dir string) ([]Page, error) {
-	return LoadForPlatform(dir, runtime.GOOS)
+// Load reads the pages shown on this machine from fsys. fsys is rooted
+// at the pages directory: an os.DirFS, an embed.FS sub-tree, a zip archive
+// or an Overlay.
+func Load(fsys fs.FS) ([]Page, error) {
+	return LoadForFacts(fsys, facts.Current())
+}
+
+// LoadForPlatform reads the pages that can be shown on platform, or on
+// any platform when it is "all". Only the platform and when.os conditions
+// are evaluated and templates are left unexpanded apart from includes, so
+// validation and export see the pages of every machine of that platform.
+func LoadForPlatform(fsys fs.FS, platform string) ([]Page, error) {
+	shown, _, err := load(fsys, platformCondition(platform), nil)
+	return shown, err
+}
+
+// LoadForFacts reads the pages shown on a machine with facts f.
+func LoadForFacts(fsys fs.FS, f facts.Facts) ([]Page, error) {
+	shown, _, err := Select(fsys, f)
+	return shown, err
+}
+
+// Hidden is a page whose conditions don't match, and why.
+type Hidden struct {
+	Page   Page
+	Reason error
 }
 
-func LoadForPlatform(dir, platform string) ([]Page, error) {
-	cfg, _ := LoadConfig(dir)
+// Select returns the pages a machine with facts f is shown, in order, and
+// the pages its conditions hide. Shown pages are translated for f.Locale
+// where a translation exists and their templates are expanded with f; an
+// unknown variable fails the load.
+func Select(fsys fs.FS, f facts.Facts) ([]Page, []Hidden, error) {
+	cfg, _ := LoadConfig(fsys)
+	shown, hidden, err := load(fsys, factsCondition(f), cfg.localeChain(f.Locale))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ExpandTemplates(shown, NewTemplateData(f, cfg)); err != nil {
+		return nil, nil, err
+	}
+	return shown, hidden, nil
+}
+
+// ExpandTemplates expands the templates of loaded in place with data.
+// Hash keeps covering the templates as written.
+func ExpandTemplates(loaded []Page, data TemplateData) error {
+	for i, p := range loaded {
+		md, err := ExpandTemplate(p.file(), p.Markdown, data)
+		if err != nil {
+			return err
+		}
+		if md != p.Markdown {
+			loaded[i].Markdown, loaded[i].template = md, p.Markdown
+		}
+	}
+	return nil
+}
+
+// condition decides whether a page is shown: nil, or why not. entry is the
+// condition of the page's day1.yml entry, if any.
+type condition func(fm Frontmatter, entry *When) error
+
+// factsCondition shows the pages a machine with facts f satisfies.
+func factsCondition(f facts.Facts) condition {
+	return func(fm Frontmatter, entry *When) error {
+		if err := checkPlatform(fm, f.OS); err != nil {
+			return err
+		}
+		for _, w := range []*When{fm.When, entry} {
+			if w != nil {
+				if err := w.Check(f); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// platformCondition shows the pages some machine of platform p can see.
+func platformCondition(p string) condition {
+	return func(fm Frontmatter, entry *When) error {
+		if p == "all" {
+			return nil
+		}
+		if err := checkPlatform(fm, p); err != nil {
+			return err
+		}
+		for _, w := range []*When{fm.When, entry} {
+			if w != nil {
+				if err := w.checkOS(p); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func checkPlatform(fm Frontmatter, current string) error {
+	if !platform.Matches(fm.Platform, current) {
+		return fmt.Errorf("platform is %s, want %s", current, fm.Platform)
+	}
+	return nil
+}
+
+// load reads the pages, drops the files other pages include, and
+// translates the shown ones for the locale tags of chain and splices
+// their includes.
+func load(fsys fs.FS, show condition, chain []string) ([]Page, []Hidden, error) {
+	cfg, _ := LoadConfig(fsys)
+	var shown []Page
+	var hidden []Hidden
+	var err error
 	if len(cfg.Pages) > 0 {
-		return loadList(dir, cfg.Pages, platform)
+		shown, hidden, err = loadList(fsys, cfg.Pages, show)
+	} else {
+		shown, hidden, err = loadAll(fsys, show)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	all := slices.Clone(shown)
+	for _, h := range hidden {
+		all = append(all, h.Page)
 	}
-	return loadAll(dir, platform)
+	included := includedFiles(fsys, all)
+	shown = slices.DeleteFunc(shown, func(p Page) bool { return included[p.SourceFile] })
+	hidden = slices.DeleteFunc(hidden, func(h Hidden) bool { return included[h.Page.SourceFile] })
+
+	in := &includer{fsys: fsys, show: func(fm Frontmatter) error { return show(fm, nil) }, chain: chain}
+	for i, p := range shown {
+		p.source = storedSource(fsys, p.SourceFile, map[string]bool{})
+		if file, tag := translation(fsys, p.SourceFile, chain); file != "" {
+			if p, err = translate(fsys, p, file, tag); err != nil {
+				return nil, nil, err
+			}
+		}
+		if p.Markdown, err = in.splice(p.file(), p.Markdown); err != nil {
+			return nil, nil, err
+		}
+		shown[i] = p
+	}
+	return shown, hidden, nil
 }
 
-func loadList(dir string, names []string, platform string) ([]Page, error) {
-	out := make([]Page, 0, len(names))
-	for _, name := range names {
-		if strings.Contains(name, "..") || filepath.IsAbs(name) {
-			return nil, fmt.Errorf("invalid page path: %s", name)
+func loadList(fsys fs.FS, entries []PageEntry, show condition) ([]Page, []Hidden, error) {
+	out := make([]Page, 0, len(entries))
+	var hidden []Hidden
+	for _, e := range entries {
+		if !validPagePath(e.File) {
+			return nil, nil, fmt.Errorf("invalid page path: %s", e.File)
 		}
-		p, err := readPage(dir, name, platform)
+		p, err := readPage(fsys, e.File)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		if p != nil {
-			out = append(out, *p)
+		if err := show(p.Frontmatter, e.When); err != nil {
+			hidden = append(hidden, Hidden{Page: p, Reason: err})
+			continue
 		}
+		out = append(out, p)
 	}
-	return out, nil
+	return out, hidden, nil
 }
 
 // loadAll is the fallback when day1.yml has no `pages:` list.
-func loadAll(dir, platform string) ([]Page, error) {
-	entries, err := os.ReadDir(dir)
+func loadAll(fsys fs.FS, show condition) ([]Page, []Hidden, error) {
+	entries, err := fs.ReadDir(fsys, ".")
 	if err != nil {
-		return nil, fmt.Errorf("read pages dir %s: %w", dir, err)
+		return nil, nil, fmt.Errorf("read pages dir: %w", err)
 	}
 
 	var out []Page
+	var hidden []Hidden
 	for _, e := range entries {
-		if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+		// Hidden files (editor backups, macOS "._" forks) are never pages.
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") || strings.HasPrefix(e.Name(), ".") {
 			continue
 		}
-		p, err := readPage(dir, e.Name(), platform)
+		if isTranslation(fsys, e.Name()) {
+			continue
+		}
+		p, err := readPage(fsys, e.Name())
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
-		if p != nil {
-			out = append(out, *p)
+		if err := show(p.Frontmatter, nil); err != nil {
+			hidden = append(hidden, Hidden{Page: p, Reason: err})
+			continue
 		}
+		out = append(out, p)
 	}
 
 	sort.SliceStable(out, func(i, j int) bool {
@@ -65,30 +213,28 @@ func loadAll(dir, platform string) ([]Page, error) {
 		}
 		return out[i].SourceFile < out[j].SourceFile
 	})
-	return out, nil
+	return out, hidden, nil
 }
 
-// readPage returns nil (not error) when filtered out by platform.
-func readPage(dir, name, platform string) (*Page, error) {
-	raw, err := os.ReadFile(filepath.Join(dir, name))
+func readPage(fsys fs.FS, name string) (Page, error) {
+	raw, err := fs.ReadFile(fsys, name)
 	if err != nil {
-		return nil, fmt.Errorf("read %s: %w", name, err)
+		return Page{}, fmt.Errorf("read %s: %w", name, err)
 	}
 
 	fm, body, err := ParseFrontmatter(string(raw), name)
 	if err != nil {
-		return nil, err
-	}
-
-	if fm.Platform != "all" && fm.Platform != platform {
-		return nil, nil
+		return Page{}, err
 	}
 
 	if fm.Title == "" {
 		fm.Title = titleFromFilename(name)
 	}
+	if fm.ID == "" {
+		fm.ID = idFromFilename(name)
+	}
 
-	return &Page{Frontmatter: fm, Markdown: body, SourceFile: name}, nil
+	return Page{Frontmatter: fm, Markdown: body, SourceFile: name}, nil
 }
 
 // titleFromFilename: "tools-access.md" -> "Tools Access"
@@ -0,0 +1,273 @@
+package pages
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Translations sit next to the page they translate, with the locale
+// before the extension, or in a directory named after the locale:
+//
+//	welcome.md        the default language (day1.yml locale:, "en")
+//	welcome.de.md     German
+//	pt/welcome.md     Portuguese, also shown for pt-BR
+//	day1.pt-BR.yml    title, help_url, final_page and strings for pt-BR
+//
+// A translation replaces the title, dir and body of a page; its ID, order,
+// platform and when: come from the original, so checklist state and
+// progress carry over between languages. A machine's locale falls back
+// from the most specific tag to the default language: pt-BR, pt, en.
+
+// DefaultLocale is the language of pages without a locale suffix when
+// day1.yml doesn't set locale.
+const DefaultLocale = "en"
+
+// localeTagRe matches the tags day1 looks for in file names: a two
+// letter language with an optional script and region, as written by
+// facts.ParseLocale.
+var localeTagRe = regexp.MustCompile(`^[a-z]{2}(-[A-Z][a-z]{3})?(-[A-Z]{2}|-[0-9]{3})?$`)
+
+func isLocaleTag(s string) bool {
+	return localeTagRe.MatchString(s)
+}
+
+// DefaultLocale returns the language of the pages without a locale
+// suffix.
+func (c Config) DefaultLocale() string {
+	if c.Locale == "" {
+		return DefaultLocale
+	}
+	return c.Locale
+}
+
+// localeChain returns the tags tried for locale, most specific first,
+// up to the default language: "pt-BR" gives pt-BR, pt.
+func (c Config) localeChain(locale string) []string {
+	var out []string
+	for tag := locale; tag != "" && tag != c.DefaultLocale(); {
+		out = append(out, tag)
+		i := strings.LastIndex(tag, "-")
+		if i < 0 {
+			break
+		}
+		tag = tag[:i]
+	}
+	return out
+}
+
+// localeConfig is what day1.<locale>.yml may set.
+type localeConfig struct {
+	Title     string            `yaml:"title"`
+	HelpURL   string            `yaml:"help_url"`
+	FinalPage string            `yaml:"final_page"`
+	Strings   map[string]string `yaml:"strings"`
+}
+
+func localeConfigName(tag string) string {
+	return "day1." + tag + ".yml"
+}
+
+// LoadLocalizedConfig reads day1.yml and applies the day1.<tag>.yml
+// files of locale's chain, least specific first. The strings of day1.yml
+// are for the default language and only apply to it.
+func LoadLocalizedConfig(fsys fs.FS, locale string) (Config, error) {
+	cfg, err := LoadConfig(fsys)
+	if err != nil {
+		return cfg, err
+	}
+	chain := cfg.localeChain(locale)
+	if len(chain) > 0 {
+		cfg.Strings = nil
+	}
+	for i := len(chain) - 1; i >= 0; i-- {
+		name := localeConfigName(chain[i])
+		data, err := fs.ReadFile(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("read config: %w", err)
+		}
+		var lc localeConfig
+		if err := yaml.Unmarshal(data, &lc); err != nil {
+			return cfg, fmt.Errorf("parse %s: %w", name, err)
+		}
+		cfg.Title = firstSet(lc.Title, cfg.Title)
+		cfg.HelpURL = firstSet(lc.HelpURL, cfg.HelpURL)
+		cfg.FinalPage = firstSet(lc.FinalPage, cfg.FinalPage)
+		if len(lc.Strings) > 0 {
+			if cfg.Strings == nil {
+				cfg.Strings = map[string]string{}
+			}
+			maps.Copy(cfg.Strings, lc.Strings)
+		}
+	}
+	return cfg, nil
+}
+
+func firstSet(a, b string) string {
+	if a != "" {
+		return a
+	}
+	return b
+}
+
+// Locales returns the tags the content in fsys has translations for:
+// translated pages, locale directories and day1.<tag>.yml files.
+func Locales(fsys fs.FS) []string {
+	found := map[string]bool{}
+	fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		base := path.Base(name)
+		if strings.HasPrefix(base, "day1.") && strings.HasSuffix(base, ".yml") && name == base {
+			if tag := strings.TrimSuffix(strings.TrimPrefix(base, "day1."), ".yml"); isLocaleTag(tag) {
+				found[tag] = true
+			}
+			return nil
+		}
+		if !strings.HasSuffix(base, ".md") {
+			return nil
+		}
+		if _, tag := splitTranslation(name); tag != "" {
+			found[tag] = true
+		}
+		if dir, _, ok := strings.Cut(name, "/"); ok && isLocaleTag(dir) {
+			found[dir] = true
+		}
+		return nil
+	})
+	return slices.Sorted(maps.Keys(found))
+}
+
+// ContentLocale returns the most specific tag of locale's chain that the
+// content has translations for, or the default language. It is the
+// language the wizard is shown in, UI strings included.
+func ContentLocale(fsys fs.FS, locale string) string {
+	cfg, _ := LoadConfig(fsys)
+	have := Locales(fsys)
+	for _, tag := range cfg.localeChain(locale) {
+		if slices.Contains(have, tag) {
+			return tag
+		}
+	}
+	return cfg.DefaultLocale()
+}
+
+// splitTranslation splits "guides/vpn.de.md" into the page it translates,
+// "guides/vpn.md", and its tag. It returns "", "" for other names.
+func splitTranslation(name string) (page, tag string) {
+	stem := strings.TrimSuffix(name, ".md")
+	i := strings.LastIndex(stem, ".")
+	if i < 0 || !isLocaleTag(stem[i+1:]) || path.Base(stem[:i]) == "" {
+		return "", ""
+	}
+	return stem[:i] + ".md", stem[i+1:]
+}
+
+// isTranslation reports whether name translates a page that exists.
+func isTranslation(fsys fs.FS, name string) bool {
+	page, _ := splitTranslation(name)
+	if page == "" {
+		return false
+	}
+	_, err := fs.Stat(fsys, page)
+	return err == nil
+}
+
+// translation returns the file translating name for the first tag of
+// chain that has one, and the tag, or "", "".
+func translation(fsys fs.FS, name string, chain []string) (file, tag string) {
+	stem := strings.TrimSuffix(name, ".md")
+	for _, tag := range chain {
+		for _, file := range []string{stem + "." + tag + ".md", tag + "/" + name} {
+			if _, err := fs.Stat(fsys, file); err == nil {
+				return file, tag
+			}
+		}
+	}
+	return "", ""
+}
+
+// TranslationFile returns the file translating name into exactly tag, or
+// "" when there is none.
+func TranslationFile(fsys fs.FS, name, tag string) string {
+	file, _ := translation(fsys, name, []string{tag})
+	return file
+}
+
+// LocalizedFile returns the translation of the file name for locale, or
+// name itself when it has none.
+func LocalizedFile(fsys fs.FS, name, locale string) string {
+	cfg, _ := LoadConfig(fsys)
+	if file, _ := translation(fsys, name, cfg.localeChain(locale)); file != "" {
+		return file
+	}
+	return name
+}
+
+// translate replaces the title and body of p with those of file.
+func translate(fsys fs.FS, p Page, file, tag string) (Page, error) {
+	raw, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return p, fmt.Errorf("read %s: %w", file, err)
+	}
+	fm, body, err := ParseFrontmatter(string(raw), file)
+	if err != nil {
+		return p, err
+	}
+	if fm.Title != "" {
+		p.Frontmatter.Title = fm.Title
+	}
+	p.Frontmatter.Dir = fm.Dir
+	p.Markdown, p.Locale, p.translation = body, tag, file
+	return p, nil
+}
+
+// Source is a file written in the default language.
+type Source struct {
+	File string
+	// Title is the page title, from frontmatter or the file name. It is
+	// empty for included files and the final page.
+	Title string
+}
+
+// Sources returns the files that translations translate: the pages of
+// every platform in order, the files they include and the final page.
+func Sources(fsys fs.FS) ([]Source, error) {
+	cfg, _ := LoadConfig(fsys)
+	show := platformCondition("all")
+	var all []Page
+	var err error
+	if len(cfg.Pages) > 0 {
+		all, _, err = loadList(fsys, cfg.Pages, show)
+	} else {
+		all, _, err = loadAll(fsys, show)
+	}
+	if err != nil {
+		return nil, err
+	}
+	included := includedFiles(fsys, all)
+	var out []Source
+	for _, p := range all {
+		if !included[p.SourceFile] {
+			out = append(out, Source{File: p.SourceFile, Title: p.Frontmatter.Title})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(included)) {
+		out = append(out, Source{File: name})
+	}
+	if final := cfg.FinalPage; final != "" && validPagePath(final) {
+		out = append(out, Source{File: final})
+	}
+	return out, nil
+}
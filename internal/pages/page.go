@@ -4,47 +4,168 @@ package pages
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"path"
+	"path/filepath"
 	"regexp"
 	"strings"
 
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/urischeme"
 	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldrenderer "github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 	"gopkg.in/yaml.v3"
 )
 
 type Frontmatter struct {
+	// ID identifies the page in saved state. Defaults to the source
+	// filename without its .md extension.
+	ID       string `yaml:"id"`
 	Title    string `yaml:"title"`
 	Order    int    `yaml:"order"`
 	Platform string `yaml:"platform"`
+	// When further limits the machines the page is shown on.
+	When *When `yaml:"when"`
+	// Dir is the direction the page is written in: ltr, rtl or auto.
+	// Defaults to the direction of the page's language.
+	Dir string `yaml:"dir"`
+	// Required makes every checklist item of the page required.
+	Required bool `yaml:"required"`
 }
 
 type Page struct {
 	Frontmatter Frontmatter
 	Markdown    string
 	SourceFile  string
+	// Locale is the locale of the translation the title and body come
+	// from, or "" for the original.
+	Locale string
+	// translation is the file of that translation.
+	translation string
+	// template is Markdown before templates were expanded, if they were.
+	template string
+	// source is the page's file as stored and the files it includes, set
+	// by the loader for Hash.
+	source string
+}
+
+// file is the file the page's body was read from.
+func (p Page) file() string {
+	if p.translation != "" {
+		return p.translation
+	}
+	return p.SourceFile
+}
+
+// ID returns the page's stable identifier.
+func (p Page) ID() string {
+	if p.Frontmatter.ID != "" {
+		return p.Frontmatter.ID
+	}
+	return idFromFilename(p.SourceFile)
+}
+
+// HasID reports whether id names the page: its ID, or its source file
+// without the .md extension, which is what relative links resolve to.
+func (p Page) HasID(id string) bool {
+	return id == p.ID() || id == idFromFilename(p.SourceFile)
+}
+
+// Hash returns a short digest of the page, used to detect pages that
+// changed since a user last completed the wizard. For loaded pages it
+// covers the page's file as stored and every file it includes, before
+// translation, includes, templates or conditions are resolved, so the
+// locale and facts of a machine don't change it. Pages built in memory
+// hash their title, platform and unexpanded markdown.
+func (p Page) Hash() string {
+	h := sha256.New()
+	if p.source != "" {
+		io.WriteString(h, p.source)
+		return hex.EncodeToString(h.Sum(nil)[:8])
+	}
+	body := p.Markdown
+	if p.template != "" {
+		body = p.template
+	}
+	fmt.Fprintf(h, "%s\x00%s\x00%s", p.Frontmatter.Title, p.Frontmatter.Platform, body)
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// SourceHash returns a short digest of the markdown files of fsys in the
+// default language, as stored. Translations and locale directories are
+// left out and nothing is resolved, so the locale and facts of a machine
+// don't change it; it is the content version when day1.yml doesn't set
+// content_version.
+func SourceHash(fsys fs.FS) (string, error) {
+	h := sha256.New()
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if name != "." && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || path.Ext(name) != ".md" {
+			return nil
+		}
+		if _, tag := splitTranslation(name); tag != "" {
+			return nil
+		}
+		if dir, _, ok := strings.Cut(name, "/"); ok && isLocaleTag(dir) {
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s\x00%s\x00", name, data)
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("hash content: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8]), nil
+}
+
+// idFromFilename: "guides/vpn.md" -> "guides/vpn"
+func idFromFilename(name string) string {
+	return strings.TrimSuffix(filepath.ToSlash(name), ".md")
 }
 
 var (
 	fmDelim  = regexp.MustCompile(`(?m)^---\s*$`)
 	imgSrcRe = regexp.MustCompile(`(<img\s[^>]*?src=")([^"]+)(")`)
-	renderer = goldmark.New(goldmark.WithExtensions(extension.GFM, extension.Typographer))
+	renderer = goldmark.New(
+		goldmark.WithExtensions(extension.GFM, extension.Typographer),
+		goldmark.WithParserOptions(
+			parser.WithBlockParsers(util.Prioritized(directiveParser{}, 650)),
+			parser.WithASTTransformers(util.Prioritized(checklistTransformer{}, 100)),
+		),
+		goldmark.WithRendererOptions(goldrenderer.WithNodeRenderers(util.Prioritized(checkBoxRenderer{}, 100))),
+	)
 )
 
 // ParseFrontmatter splits raw markdown into YAML frontmatter + body.
 // Returns platform="all" if no delimiters are found.
 func ParseFrontmatter(raw, filename string) (Frontmatter, string, error) {
-	locs := fmDelim.FindAllStringIndex(raw, 3)
-	if len(locs) < 2 {
+	fmBlock, body, _, ok := splitFrontmatter(raw)
+	if !ok {
 		return Frontmatter{Platform: "all"}, raw, nil
 	}
 
-	fmBlock := raw[locs[0][1]:locs[1][0]]
-	body := raw[locs[1][1]:]
-	if len(body) > 0 && body[0] == '\n' {
-		body = body[1:]
-	}
-
 	var fm Frontmatter
 	if err := yaml.Unmarshal([]byte(fmBlock), &fm); err != nil {
 		return Frontmatter{}, "", fmt.Errorf("parse frontmatter in %s: %w", filename, err)
@@ -55,11 +176,104 @@ func ParseFrontmatter(raw, filename string) (Frontmatter, string, error) {
 	return fm, body, nil
 }
 
+// splitFrontmatter returns the YAML block and body of raw. bodyLine is the
+// 1-based line in raw on which the body starts. ok is false when raw has no
+// frontmatter delimiters.
+func splitFrontmatter(raw string) (block, body string, bodyLine int, ok bool) {
+	locs := fmDelim.FindAllStringIndex(raw, 3)
+	if len(locs) < 2 {
+		return "", raw, 1, false
+	}
+
+	block = raw[locs[0][1]:locs[1][0]]
+	body = raw[locs[1][1]:]
+	if len(body) > 0 && body[0] == '\n' {
+		body = body[1:]
+	}
+	bodyLine = strings.Count(raw[:len(raw)-len(body)], "\n") + 1
+	return block, body, bodyLine, true
+}
+
+// RenderOption configures RenderHTML and the other functions that parse
+// page markdown.
+type RenderOption func(*renderConfig)
+
+type renderConfig struct {
+	source string
+	// show decides which directive blocks are kept; nil keeps all.
+	show func(*directive) bool
+	// include decides which included files are spliced; nil splices all.
+	include condition
+	// locale is the locale included files are translated for.
+	locale string
+	// dir is the direction of the page; blocks with text in the other
+	// direction get dir="auto".
+	dir string
+}
+
+func newRenderConfig(opts []RenderOption) renderConfig {
+	var rc renderConfig
+	for _, opt := range opts {
+		opt(&rc)
+	}
+	return rc
+}
+
+// WithSource names the file the markdown was read from, relative to the
+// pages dir, so relative links to other pages resolve from its directory.
+func WithSource(name string) RenderOption {
+	return func(c *renderConfig) { c.source = name }
+}
+
+// WithFacts keeps only the ::: directive blocks and included files whose
+// condition a machine with facts f satisfies. Without WithFacts or
+// WithPlatform every block is kept.
+func WithFacts(f facts.Facts) RenderOption {
+	return func(c *renderConfig) {
+		c.show = func(d *directive) bool { return d.shows(f, false) }
+		c.include = factsCondition(f)
+	}
+}
+
+// WithPlatform keeps the ::: directive blocks and included files for
+// platform p, evaluating only platforms and when.os like LoadForPlatform.
+func WithPlatform(p string) RenderOption {
+	return func(c *renderConfig) {
+		c.show = func(d *directive) bool { return d.shows(facts.Facts{OS: p}, true) }
+		c.include = platformCondition(p)
+	}
+}
+
+// WithLocale translates the files ExpandIncludes includes for locale.
+func WithLocale(locale string) RenderOption {
+	return func(c *renderConfig) { c.locale = locale }
+}
+
+// WithDir renders the markdown for a page written in direction dir, ltr
+// by default, so that blocks with text in the other direction are marked.
+func WithDir(dir string) RenderOption {
+	return func(c *renderConfig) { c.dir = dir }
+}
+
 // RenderHTML converts markdown to HTML. assetsPrefix is prepended to relative
-// image src attributes so the Wails AssetHandler can serve them.
-func RenderHTML(markdown, assetsPrefix string) (string, error) {
+// image src attributes so the Wails AssetHandler can serve them. Relative
+// links to other .md pages become day1:page links, ::: directive blocks
+// are kept or dropped as WithFacts or WithPlatform decide, and blocks of
+// mixed-direction text get dir="auto" (see WithDir).
+func RenderHTML(markdown, assetsPrefix string, opts ...RenderOption) (string, error) {
+	rc := newRenderConfig(opts)
+	doc, source := Parse(markdown, opts...)
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if l, ok := n.(*ast.Link); ok && entering {
+			if dest, ok := pageLink(string(l.Destination), rc.source); ok {
+				l.Destination = []byte(dest)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	markMixedDir(doc, source, rc.dir)
 	var buf bytes.Buffer
-	if err := renderer.Convert([]byte(markdown), &buf); err != nil {
+	if err := renderer.Renderer().Render(&buf, source, doc); err != nil {
 		return "", fmt.Errorf("goldmark: %w", err)
 	}
 	if assetsPrefix == "" {
@@ -68,6 +282,83 @@ func RenderHTML(markdown, assetsPrefix string) (string, error) {
 	return rewriteImageSrcs(buf.String(), assetsPrefix), nil
 }
 
+// pageLink returns the day1:page link for dest when it is a relative link
+// to a .md file, resolved from the directory of source. A #fragment is
+// kept.
+func pageLink(dest, source string) (string, bool) {
+	if dest == "" || linkSchemeRe.MatchString(dest) || strings.HasPrefix(dest, "/") || strings.HasPrefix(dest, "#") {
+		return "", false
+	}
+	p, frag, _ := strings.Cut(dest, "#")
+	p, _, _ = strings.Cut(p, "?")
+	if u, err := url.PathUnescape(p); err == nil {
+		p = u
+	}
+	if !strings.HasSuffix(p, ".md") {
+		return "", false
+	}
+	p = path.Join(path.Dir(filepath.ToSlash(source)), p)
+	if p == ".." || strings.HasPrefix(p, "../") {
+		return "", false
+	}
+	link := urischeme.PageURL(idFromFilename(p))
+	if frag != "" {
+		link += "#" + frag
+	}
+	return link, true
+}
+
+// Parse parses markdown with the same extensions as RenderHTML, including
+// checklist item IDs and ::: directive blocks, for front ends that render
+// the AST themselves.
+func Parse(markdown string, opts ...RenderOption) (ast.Node, []byte) {
+	source := []byte(markdown)
+	doc := renderer.Parser().Parse(text.NewReader(source))
+	applyDirectives(doc, newRenderConfig(opts).show)
+	return doc, source
+}
+
+var linkSchemeRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:`)
+
+// Links returns the destinations of links in markdown that carry a URI
+// scheme, in document order. Relative links between pages are skipped.
+func Links(markdown string, opts ...RenderOption) []string {
+	var out []string
+	for _, dest := range linkDests(markdown, opts...) {
+		if linkSchemeRe.MatchString(dest) {
+			out = append(out, dest)
+		}
+	}
+	return out
+}
+
+// linkDests returns the destination of every link and autolink in
+// markdown, in document order.
+func linkDests(markdown string, opts ...RenderOption) []string {
+	doc, source := Parse(markdown, opts...)
+	var out []string
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var dest string
+		switch l := n.(type) {
+		case *ast.Link:
+			dest = string(l.Destination)
+		case *ast.AutoLink:
+			dest = string(l.URL(source))
+			if l.AutoLinkType == ast.AutoLinkEmail && !strings.HasPrefix(strings.ToLower(dest), "mailto:") {
+				dest = "mailto:" + dest
+			}
+		}
+		if dest != "" {
+			out = append(out, dest)
+		}
+		return ast.WalkContinue, nil
+	})
+	return out
+}
+
 var skipPrefixes = []string{"http://", "https://", "//", "/", "data:"}
 
 func rewriteImageSrcs(html, prefix string) string {
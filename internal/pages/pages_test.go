@@ -1,11 +1,16 @@
 package pages
 
 import (
+	"archive/zip"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+	"testing/fstest"
+
+	"github.com/TsekNet/day1/internal/facts"
+	"gopkg.in/yaml.v3"
 )
 
 func TestParseFrontmatter(t *testing.T) {
@@ -21,8 +26,8 @@ func TestParseFrontmatter(t *testing.T) {
 		wantErr   bool
 	}{
 		{
-			name: "full frontmatter",
-			raw: "---\ntitle: Welcome\norder: 1\nplatform: darwin\n---\n# Hello\n",
+			name:      "full frontmatter",
+			raw:       "---\ntitle: Welcome\norder: 1\nplatform: darwin\n---\n# Hello\n",
 			wantTitle: "Welcome",
 			wantOrder: 1,
 			wantPlat:  "darwin",
@@ -37,8 +42,8 @@ func TestParseFrontmatter(t *testing.T) {
 			wantBody:  "# Just markdown\nSome text.",
 		},
 		{
-			name: "frontmatter without platform defaults to all",
-			raw:  "---\ntitle: Setup\norder: 2\n---\nContent here.",
+			name:      "frontmatter without platform defaults to all",
+			raw:       "---\ntitle: Setup\norder: 2\n---\nContent here.",
 			wantTitle: "Setup",
 			wantOrder: 2,
 			wantPlat:  "all",
@@ -94,7 +99,9 @@ func TestRenderHTML(t *testing.T) {
 		name         string
 		markdown     string
 		assetsPrefix string
+		opts         []RenderOption
 		wantContains []string
+		wantMissing  []string
 	}{
 		{
 			name:         "heading",
@@ -133,12 +140,89 @@ func TestRenderHTML(t *testing.T) {
 			markdown:     "**bold** and *italic*",
 			wantContains: []string{"<strong>bold</strong>", "<em>italic</em>"},
 		},
+		{
+			name:         "relative page link",
+			markdown:     "[VPN](guides/vpn.md#setup) [Docs](https://example.com/a.md) [Top](#top)",
+			wantContains: []string{`href="day1:page/guides/vpn#setup"`, `href="https://example.com/a.md"`, `href="#top"`},
+		},
+		{
+			name:         "page link from subdirectory",
+			markdown:     "[Back](../welcome.md) [Next](mfa.md)",
+			opts:         []RenderOption{WithSource("guides/vpn.md")},
+			wantContains: []string{`href="day1:page/welcome"`, `href="day1:page/guides/mfa"`},
+		},
+		{
+			name:         "mixed direction in an ltr page",
+			markdown:     "# Hello\n\nשלום from the team\n\n- one\n- מחשב\n\n| A | שם |\n|---|---|\n| 1 | 2 |\n\nRun `echo שלום`",
+			wantContains: []string{"<h1>Hello</h1>", `<p dir="auto">שלום from the team</p>`, "<li>one</li>", `<li dir="auto">מחשב</li>`, `<th dir="auto">שם</th>`, "<th>A</th>", "<p>Run <code>"},
+		},
+		{
+			name:         "mixed direction in an rtl page",
+			markdown:     "# שלום\n\nהתקינו את VPN\n\nמחשב",
+			opts:         []RenderOption{WithDir("rtl")},
+			wantContains: []string{"<h1>שלום</h1>", `<p dir="auto">התקינו את VPN</p>`, "<p>מחשב</p>"},
+		},
+		{
+			name:         "directives kept without facts",
+			markdown:     "::: platform windows\nWin\n:::\n\n::: platform !windows\nOther\n:::\n",
+			wantContains: []string{"<p>Win</p>", "<p>Other</p>"},
+			wantMissing:  []string{":::"},
+		},
+		{
+			name:         "platform directives",
+			markdown:     "::: platform windows\nWin\n:::\n\n::: platform !windows\nOther\n:::\n\n::: platform darwin linux\nUnix\n:::\n",
+			opts:         []RenderOption{WithFacts(facts.Facts{OS: "linux"})},
+			wantContains: []string{"<p>Other</p>", "<p>Unix</p>"},
+			wantMissing:  []string{"Win"},
+		},
+		{
+			name:         "wsl sees windows blocks",
+			markdown:     "::: platform windows\nWin\n:::\n::: platform !windows\nOther\n:::\n",
+			opts:         []RenderOption{WithFacts(facts.Facts{OS: "wsl"})},
+			wantContains: []string{"<p>Win</p>"},
+			wantMissing:  []string{"Other"},
+		},
+		{
+			name:         "when directive",
+			markdown:     "Intro\n::: when group: eng, os_version: \">= 14\"\nBuild farm\n:::\n::: when os_version: \"< 14\"\nUpgrade\n:::\n",
+			opts:         []RenderOption{WithFacts(facts.Facts{OS: "darwin", OSVersion: "14.2", Groups: []string{"eng"}})},
+			wantContains: []string{"<p>Intro</p>", "<p>Build farm</p>"},
+			wantMissing:  []string{"Upgrade"},
+		},
+		{
+			name:         "nested directives and fenced code",
+			markdown:     "::: platform linux\n::: when distro: ubuntu\n```\n:::\n```\n:::\nLinux\n:::\nAfter\n",
+			opts:         []RenderOption{WithFacts(facts.Facts{OS: "linux", Distro: "fedora"})},
+			wantContains: []string{"<p>Linux</p>", "<p>After</p>"},
+			wantMissing:  []string{"<pre>"},
+		},
+		{
+			name:         "invalid directives are hidden",
+			markdown:     "::: platform windos\nWin\n:::\n::: when grup: eng\nEng\n:::\nAfter\n",
+			opts:         []RenderOption{WithFacts(facts.Facts{OS: "linux", Groups: []string{"eng"}})},
+			wantContains: []string{"<p>After</p>"},
+			wantMissing:  []string{"Win", "Eng"},
+		},
+		{
+			name:         "invalid directives are hidden for a platform",
+			markdown:     "::: platform windos\nWin\n:::\nAfter\n",
+			opts:         []RenderOption{WithPlatform("windows")},
+			wantContains: []string{"<p>After</p>"},
+			wantMissing:  []string{"Win"},
+		},
+		{
+			name:         "platform option ignores other facts",
+			markdown:     "::: when group: eng\nEng\n:::\n::: when os: linux\nLinux\n:::\n",
+			opts:         []RenderOption{WithPlatform("darwin")},
+			wantContains: []string{"<p>Eng</p>"},
+			wantMissing:  []string{"Linux"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
-			got, err := RenderHTML(tt.markdown, tt.assetsPrefix)
+			got, err := RenderHTML(tt.markdown, tt.assetsPrefix, tt.opts...)
 			if err != nil {
 				t.Fatalf("RenderHTML: %v", err)
 			}
@@ -147,6 +231,11 @@ func TestRenderHTML(t *testing.T) {
 					t.Errorf("output missing %q\ngot: %s", want, got)
 				}
 			}
+			for _, unwanted := range tt.wantMissing {
+				if strings.Contains(got, unwanted) {
+					t.Errorf("output contains %q\ngot: %s", unwanted, got)
+				}
+			}
 		})
 	}
 }
@@ -174,14 +263,36 @@ func TestLoadForPlatform(t *testing.T) {
 		{
 			name: "platform filtering",
 			files: map[string]string{
-				"01-mac.md":  "---\ntitle: Mac Only\norder: 1\nplatform: darwin\n---\n",
-				"02-all.md":  "---\ntitle: Everyone\norder: 2\n---\n",
-				"03-win.md":  "---\ntitle: Win Only\norder: 3\nplatform: windows\n---\n",
+				"01-mac.md": "---\ntitle: Mac Only\norder: 1\nplatform: darwin\n---\n",
+				"02-all.md": "---\ntitle: Everyone\norder: 2\n---\n",
+				"03-win.md": "---\ntitle: Win Only\norder: 3\nplatform: windows\n---\n",
 			},
 			platform:   "darwin",
 			wantCount:  2,
 			wantTitles: []string{"Mac Only", "Everyone"},
 		},
+		{
+			name: "wsl shows linux and windows pages",
+			files: map[string]string{
+				"01-mac.md":   "---\ntitle: Mac Only\norder: 1\nplatform: darwin\n---\n",
+				"02-linux.md": "---\ntitle: Linux\norder: 2\nplatform: linux\n---\n",
+				"03-win.md":   "---\ntitle: Windows\norder: 3\nplatform: windows\n---\n",
+				"04-wsl.md":   "---\ntitle: WSL\norder: 4\nplatform: wsl\n---\n",
+			},
+			platform:   "wsl",
+			wantCount:  3,
+			wantTitles: []string{"Linux", "Windows", "WSL"},
+		},
+		{
+			name: "wsl pages hidden on linux",
+			files: map[string]string{
+				"01-linux.md": "---\ntitle: Linux\norder: 1\nplatform: linux\n---\n",
+				"02-wsl.md":   "---\ntitle: WSL\norder: 2\nplatform: wsl\n---\n",
+			},
+			platform:   "linux",
+			wantCount:  1,
+			wantTitles: []string{"Linux"},
+		},
 		{
 			name: "order from frontmatter beats filename",
 			files: map[string]string{
@@ -231,7 +342,7 @@ func TestLoadForPlatform(t *testing.T) {
 				}
 			}
 
-			got, err := LoadForPlatform(dir, tt.platform)
+			got, err := LoadForPlatform(os.DirFS(dir), tt.platform)
 			if err != nil {
 				t.Fatalf("LoadForPlatform: %v", err)
 			}
@@ -247,13 +358,462 @@ func TestLoadForPlatform(t *testing.T) {
 	}
 }
 
+func TestWhenCheck(t *testing.T) {
+	t.Parallel()
+
+	ubuntu := facts.Facts{
+		OS:         "linux",
+		Arch:       "amd64",
+		Distro:     "ubuntu",
+		DistroLike: []string{"debian"},
+		OSVersion:  "22.04",
+		Hostname:   "eng-laptop-42",
+		Groups:     []string{"staff", "Engineering"},
+		Env:        map[string]string{"TEAM": "sre"},
+	}
+	tests := []struct {
+		name    string
+		when    string
+		facts   facts.Facts
+		wantErr string
+	}{
+		{name: "empty", when: "{}", facts: ubuntu},
+		{name: "os list", when: "os: [darwin, linux]", facts: ubuntu},
+		{name: "os mismatch", when: "os: windows", facts: ubuntu, wantErr: "os is linux, want windows"},
+		{name: "wsl matches linux", when: "os: linux", facts: facts.Facts{OS: "wsl"}},
+		{name: "arch", when: "arch: arm64", facts: ubuntu, wantErr: "arch is amd64, want arm64"},
+		{name: "distro", when: "distro: Ubuntu", facts: ubuntu},
+		{name: "distro like", when: "distro: debian", facts: ubuntu},
+		{name: "distro mismatch", when: "distro: [fedora, rhel]", facts: ubuntu, wantErr: "distro is ubuntu, want fedora or rhel"},
+		{name: "distro unknown", when: "distro: fedora", facts: facts.Facts{OS: "darwin"}, wantErr: "distro is unknown, want fedora"},
+		{name: "version range", when: `os_version: ">= 20.04, < 24"`, facts: ubuntu},
+		{name: "version below", when: `os_version: ">= 24.04"`, facts: ubuntu, wantErr: "os_version is 22.04, want >= 24.04"},
+		{name: "version prefix", when: `os_version: "14"`, facts: facts.Facts{OSVersion: "14.5"}},
+		{name: "version prefix mismatch", when: `os_version: "14"`, facts: facts.Facts{OSVersion: "13.6"}, wantErr: "os_version is 13.6"},
+		{name: "version numeric", when: `os_version: ">= 10.0.22000"`, facts: facts.Facts{OSVersion: "10.0.9200"}, wantErr: "want >= 10.0.22000"},
+		{name: "version unknown", when: `os_version: "!= 1"`, facts: facts.Facts{}, wantErr: "os_version is unknown"},
+		{name: "hostname pattern", when: "hostname: [lab-*, ENG-*]", facts: ubuntu},
+		{name: "hostname mismatch", when: "hostname: lab-*", facts: ubuntu, wantErr: "hostname is eng-laptop-42, want lab-*"},
+		{name: "group", when: "group: engineering", facts: ubuntu},
+		{name: "group mismatch", when: "group: [finance, legal]", facts: ubuntu, wantErr: "not in group finance or legal"},
+		{name: "env", when: "env: {TEAM: s*}", facts: ubuntu},
+		{name: "env set", when: `env: {TEAM: "*"}`, facts: ubuntu},
+		{name: "env unset", when: `env: {CI: "*"}`, facts: ubuntu, wantErr: `env CI is "", want "*"`},
+		{name: "all keys must match", when: "os: linux\ngroup: finance", facts: ubuntu, wantErr: "not in group finance"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			var w When
+			if err := yaml.Unmarshal([]byte(tt.when), &w); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			err := w.Check(tt.facts)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"day1.yml":   {Data: []byte("pages:\n  - welcome.md\n  - file: vpn.md\n    when: {group: engineering}\n  - apt.md\n  - mac.md\n")},
+		"welcome.md": {Data: []byte("---\ntitle: Welcome\n---\n")},
+		"vpn.md":     {Data: []byte("---\ntitle: VPN\n---\n")},
+		"apt.md":     {Data: []byte("---\ntitle: APT\nwhen:\n  distro: debian\n---\n")},
+		"mac.md":     {Data: []byte("---\ntitle: Mac\nplatform: darwin\n---\n")},
+	}
+	shown, hidden, err := Select(fsys, facts.Facts{OS: "linux", Distro: "ubuntu", DistroLike: []string{"debian"}})
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	var ids []string
+	for _, p := range shown {
+		ids = append(ids, p.ID())
+	}
+	if got := strings.Join(ids, ","); got != "welcome,apt" {
+		t.Errorf("shown = %s, want welcome,apt", got)
+	}
+	want := map[string]string{
+		"vpn": "not in group engineering",
+		"mac": "platform is linux, want darwin",
+	}
+	if len(hidden) != len(want) {
+		t.Fatalf("got %d hidden pages, want %d", len(hidden), len(want))
+	}
+	for _, h := range hidden {
+		if got := h.Reason.Error(); got != want[h.Page.ID()] {
+			t.Errorf("hidden %s: reason %q, want %q", h.Page.ID(), got, want[h.Page.ID()])
+		}
+	}
+
+	// Load evaluates the same conditions against the current machine;
+	// LoadForPlatform only looks at the platform and when.os.
+	loaded, err := LoadForPlatform(fsys, "linux")
+	if err != nil {
+		t.Fatalf("LoadForPlatform: %v", err)
+	}
+	if len(loaded) != 3 {
+		t.Errorf("LoadForPlatform got %d pages, want 3", len(loaded))
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	t.Parallel()
+
+	f := facts.Facts{
+		OS:       "linux",
+		Hostname: "eng-42",
+		User:     "pkumar",
+		FullName: "Priya Kumar",
+		Env:      map[string]string{"DAY1_TEAM": "sre", "HOME": "/home/pkumar"},
+	}
+	cfg := Config{Vars: map[string]string{"start_date": "March 3"}}
+	tests := []struct {
+		name     string
+		markdown string
+		facts    facts.Facts
+		cfg      Config
+		want     string
+		wantErr  string
+	}{
+		{name: "no template", markdown: "Hi {there}", want: "Hi {there}"},
+		{name: "facts", markdown: "Welcome, {{ .FirstName }}! `{{ .Hostname }}` ({{ .User }})", want: "Welcome, Priya! `eng-42` (pkumar)"},
+		{name: "first name falls back to user", markdown: "Hi {{ .FirstName }}", facts: facts.Facts{User: "pkumar"}, want: "Hi pkumar"},
+		{name: "vars and env", markdown: "{{ .Vars.start_date }}, team {{ .Env.DAY1_TEAM }}", want: "March 3, team sre"},
+		{name: "conditionals", markdown: `{{ if eq .OS "linux" }}apt{{ else }}other{{ end }}`, want: "apt"},
+		{name: "optional env", markdown: `[{{ index .Env "DAY1_DESK" }}]`, want: "[]"},
+		{name: "unset env is empty", markdown: "[{{ .Env.DAY1_DESK }}]", want: "[]"},
+		{name: "range over vars", markdown: "{{ range $k, $v := .Vars }}{{ $k }}={{ $v }}{{ end }}", want: "start_date=March 3"},
+		{
+			name:     "values are escaped",
+			markdown: "Hi {{ .FullName }}",
+			facts:    facts.Facts{FullName: "<img src=x onerror=alert(1)> [x](javascript:alert(1)) **b**\n# h"},
+			want:     `Hi \<img src=x onerror=alert(1)\> \[x\](javascript:alert(1)) \*\*b\*\* \# h`,
+		},
+		{name: "env without prefix hidden", markdown: "{{ .Env.HOME }}", wantErr: `map has no entry for key "HOME"`},
+		{name: "custom prefix", markdown: "{{ .Env.HOME }}", cfg: Config{EnvPrefix: "HO"}, want: "/home/pkumar"},
+		{name: "unknown var", markdown: "{{ .Vars.desk }}", wantErr: `map has no entry for key "desk"`},
+		{name: "unknown field", markdown: "line\n{{ .Email }}", wantErr: "welcome.md:2:3: executing"},
+		{name: "syntax error", markdown: "{{ .User ", wantErr: "welcome.md:1: unclosed action"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			ff, cc := f, cfg
+			if tt.facts.User != "" || tt.facts.FullName != "" {
+				ff = tt.facts
+			}
+			if tt.cfg.EnvPrefix != "" {
+				cc = tt.cfg
+			}
+			got, err := ExpandTemplate("welcome.md", tt.markdown, NewTemplateData(ff, cc))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ExpandTemplate error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExpandTemplate: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ExpandTemplate = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectExpandsTemplates(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"day1.yml":   {Data: []byte("vars: {desk: 4F}\n")},
+		"welcome.md": {Data: []byte("---\ntitle: Welcome\n---\nHi {{ .User }}, desk {{ .Vars.desk }}\n")},
+	}
+	a, _, err := Select(fsys, facts.Facts{OS: "linux", User: "alice"})
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	b, _, err := Select(fsys, facts.Facts{OS: "linux", User: "bob"})
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if got := a[0].Markdown; got != "Hi alice, desk 4F\n" {
+		t.Errorf("Markdown = %q", got)
+	}
+	if a[0].Hash() != b[0].Hash() {
+		t.Error("Hash depends on expanded facts, want the template's hash")
+	}
+
+	fsys["welcome.md"] = &fstest.MapFile{Data: []byte("Hi {{ .Vars.floor }}\n")}
+	if _, _, err := Select(fsys, facts.Facts{OS: "linux"}); err == nil || !strings.Contains(err.Error(), `no entry for key "floor"`) {
+		t.Errorf("Select with unknown var: err = %v", err)
+	}
+}
+
+func TestHashIgnoresLocaleAndFacts(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"welcome.md":    {Data: []byte("# Welcome\n\n{{ include \"sre.md\" }}\n")},
+		"welcome.de.md": {Data: []byte("# Willkommen\n\n{{ include \"sre.md\" }}\n")},
+		"sre.md":        {Data: []byte("---\nwhen:\n  env: {TEAM: sre}\n---\nYou're on call.\n")},
+	}
+	machines := []facts.Facts{
+		{OS: "linux", Locale: "en"},
+		{OS: "linux", Locale: "de"},
+		{OS: "linux", Locale: "de", Env: map[string]string{"TEAM": "sre"}},
+	}
+	var hashes []string
+	for _, f := range machines {
+		shown, err := LoadForFacts(fsys, f)
+		if err != nil || len(shown) != 1 {
+			t.Fatalf("LoadForFacts(%+v) = %d pages, %v", f, len(shown), err)
+		}
+		hashes = append(hashes, shown[0].Hash())
+	}
+	if hashes[0] != hashes[1] || hashes[1] != hashes[2] {
+		t.Errorf("Hash = %v, want the same for every locale and team", hashes)
+	}
+
+	before, err := SourceHash(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys["welcome.de.md"] = &fstest.MapFile{Data: []byte("# Hallo\n")}
+	fsys["pt/welcome.md"] = &fstest.MapFile{Data: []byte("# Olá\n")}
+	if got, _ := SourceHash(fsys); got != before {
+		t.Error("SourceHash changed with a translation")
+	}
+	fsys["sre.md"] = &fstest.MapFile{Data: []byte("You're on call this week.\n")}
+	if got, _ := SourceHash(fsys); got == before {
+		t.Error("SourceHash didn't change when a page changed")
+	}
+	shown, _ := LoadForFacts(fsys, machines[0])
+	if shown[0].Hash() == hashes[0] {
+		t.Error("Hash didn't change when a file it includes changed, though it isn't shown")
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"day1.yml":            {Data: []byte("vars: {phone: x100}\n")},
+		"welcome.md":          {Data: []byte("# Hi {{ .User }}\n\n{{ include \"snippets/contact.md\" }}\n\n{{ include \"mac.md\" }}\n")},
+		"snippets/contact.md": {Data: []byte("Call {{ .Vars.phone }}.\n\n")},
+		"mac.md":              {Data: []byte("---\nplatform: darwin\n---\nOpen System Settings.\n")},
+	}
+	tests := []struct {
+		os   string
+		want string
+	}{
+		{"linux", "# Hi alice\n\nCall x100.\n\n\n"},
+		{"darwin", "# Hi alice\n\nCall x100.\n\nOpen System Settings.\n"},
+	}
+	for _, tt := range tests {
+		shown, _, err := Select(fsys, facts.Facts{OS: tt.os, User: "alice"})
+		if err != nil {
+			t.Fatalf("Select(%s): %v", tt.os, err)
+		}
+		if len(shown) != 1 {
+			t.Fatalf("Select(%s) = %d pages, want only welcome.md", tt.os, len(shown))
+		}
+		if shown[0].Markdown != tt.want {
+			t.Errorf("Select(%s) Markdown = %q, want %q", tt.os, shown[0].Markdown, tt.want)
+		}
+	}
+
+	before, _ := LoadForFacts(fsys, facts.Facts{OS: "linux"})
+	fsys["snippets/contact.md"] = &fstest.MapFile{Data: []byte("Call {{ .Vars.phone }} or chat.\n")}
+	after, _ := LoadForFacts(fsys, facts.Facts{OS: "linux"})
+	if before[0].Hash() == after[0].Hash() {
+		t.Error("Hash didn't change when an included file changed")
+	}
+
+	errTests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name:  "parent dir",
+			files: map[string]string{"a.md": `{{ include "../secret.md" }}`},
+			want:  "a.md: invalid include path: ../secret.md",
+		},
+		{
+			name:  "absolute",
+			files: map[string]string{"a.md": `{{ include "/etc/passwd" }}`},
+			want:  "a.md: invalid include path: /etc/passwd",
+		},
+		{
+			name:  "missing",
+			files: map[string]string{"a.md": `{{ include "gone.md" }}`},
+			want:  "a.md: include gone.md:",
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"a.md":          `{{ include "snippets/b.md" }}`,
+				"snippets/b.md": `{{ include "snippets/c.md" }}`,
+				"snippets/c.md": `{{ include "snippets/b.md" }}`,
+			},
+			want: "include cycle: snippets/b.md -> snippets/c.md -> snippets/b.md",
+		},
+		{
+			name:  "self",
+			files: map[string]string{"a.md": `{{ include "s.md" }}`, "s.md": `{{ include "s.md" }}`},
+			want:  "include cycle: s.md -> s.md",
+		},
+		{
+			name:  "not a quoted path",
+			files: map[string]string{"a.md": `{{ include (printf "x.md") }}`},
+			want:  "include takes one quoted path",
+		},
+	}
+	for _, tt := range errTests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			fsys := fstest.MapFS{}
+			for name, data := range tt.files {
+				fsys[name] = &fstest.MapFile{Data: []byte(data)}
+			}
+			if _, _, err := Select(fsys, facts.Facts{OS: "linux"}); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Select: err = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocales(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"day1.yml":       {Data: []byte("title: Welcome\nhelp_url: https://help.example.com\nfinal_page: done.md\nstrings: {next: Onward}\npages:\n  - welcome.md\n  - vpn.md\n")},
+		"day1.pt.yml":    {Data: []byte("title: Bem-vindo\nstrings: {next: Avançar}\n")},
+		"day1.pt-BR.yml": {Data: []byte("help_url: https://ajuda.example.com\n")},
+		"welcome.md":     {Data: []byte("---\ntitle: Welcome\n---\nHello {{ .User }}.\n")},
+		"welcome.de.md":  {Data: []byte("---\ntitle: Willkommen\n---\nHallo {{ .User }}.\n")},
+		"pt/welcome.md":  {Data: []byte("---\ntitle: Bem-vindo\n---\nOlá.\n")},
+		"vpn.md":         {Data: []byte("---\ntitle: VPN\nplatform: linux\n---\nConnect.\n")},
+		"done.md":        {Data: []byte("Done.\n")},
+		"done.de.md":     {Data: []byte("Fertig.\n")},
+	}
+
+	if got, want := strings.Join(Locales(fsys), ","), "de,pt,pt-BR"; got != want {
+		t.Errorf("Locales = %s, want %s", got, want)
+	}
+
+	tests := []struct {
+		locale      string
+		wantContent string
+		wantTitle   string
+		wantBody    string
+		wantLocale  string
+	}{
+		{locale: "", wantContent: "en", wantTitle: "Welcome", wantBody: "Hello alice.\n"},
+		{locale: "de-AT", wantContent: "de", wantTitle: "Willkommen", wantBody: "Hallo alice.\n", wantLocale: "de"},
+		{locale: "pt-BR", wantContent: "pt-BR", wantTitle: "Bem-vindo", wantBody: "Olá.\n", wantLocale: "pt"},
+		{locale: "fr", wantContent: "en", wantTitle: "Welcome", wantBody: "Hello alice.\n"},
+	}
+	for _, tt := range tests {
+		if got := ContentLocale(fsys, tt.locale); got != tt.wantContent {
+			t.Errorf("ContentLocale(%q) = %q, want %q", tt.locale, got, tt.wantContent)
+		}
+		shown, _, err := Select(fsys, facts.Facts{OS: "linux", User: "alice", Locale: tt.locale})
+		if err != nil {
+			t.Fatalf("Select(%q): %v", tt.locale, err)
+		}
+		if len(shown) != 2 {
+			t.Fatalf("Select(%q) = %d pages, want 2", tt.locale, len(shown))
+		}
+		p := shown[0]
+		if p.ID() != "welcome" || p.Frontmatter.Title != tt.wantTitle || p.Markdown != tt.wantBody || p.Locale != tt.wantLocale {
+			t.Errorf("Select(%q) = %s %q %q %q, want welcome %q %q %q", tt.locale,
+				p.ID(), p.Frontmatter.Title, p.Markdown, p.Locale, tt.wantTitle, tt.wantBody, tt.wantLocale)
+		}
+		if shown[1].Locale != "" {
+			t.Errorf("Select(%q): untranslated vpn.md has locale %q", tt.locale, shown[1].Locale)
+		}
+	}
+
+	cfg, err := LoadLocalizedConfig(fsys, "pt-BR")
+	if err != nil {
+		t.Fatalf("LoadLocalizedConfig: %v", err)
+	}
+	if cfg.Title != "Bem-vindo" || cfg.HelpURL != "https://ajuda.example.com" || cfg.FinalPage != "done.md" || cfg.Strings["next"] != "Avançar" {
+		t.Errorf("LoadLocalizedConfig(pt-BR) = %q %q %q %v", cfg.Title, cfg.HelpURL, cfg.FinalPage, cfg.Strings)
+	}
+	if cfg, _ := LoadLocalizedConfig(fsys, "de"); cfg.Strings != nil {
+		t.Errorf("LoadLocalizedConfig(de) kept the default language's strings: %v", cfg.Strings)
+	}
+	if cfg, _ := LoadLocalizedConfig(fsys, "en"); cfg.Strings["next"] != "Onward" {
+		t.Errorf("LoadLocalizedConfig(en) strings = %v", cfg.Strings)
+	}
+
+	if got := LocalizedFile(fsys, "done.md", "de"); got != "done.de.md" {
+		t.Errorf("LocalizedFile(done.md, de) = %s", got)
+	}
+	if got := LocalizedFile(fsys, "done.md", "pt"); got != "done.md" {
+		t.Errorf("LocalizedFile(done.md, pt) = %s", got)
+	}
+}
+
+func TestPageDir(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"day1.yml":      {Data: []byte("pages:\n  - welcome.md\n  - vpn.md\n")},
+		"welcome.md":    {Data: []byte("---\ntitle: Welcome\ndir: ltr\n---\nHello.\n")},
+		"welcome.he.md": {Data: []byte("---\ntitle: ברוכים הבאים\n---\nשלום.\n")},
+		"welcome.ar.md": {Data: []byte("---\ntitle: مرحبا\ndir: auto\n---\nمرحبا.\n")},
+		"vpn.md":        {Data: []byte("Connect.\n")},
+	}
+
+	tests := []struct {
+		locale string
+		def    string
+		want   []string
+	}{
+		{locale: "en", def: "en", want: []string{"ltr", "ltr"}},
+		{locale: "he", def: "en", want: []string{"rtl", "ltr"}},
+		{locale: "ar", def: "en", want: []string{"auto", "ltr"}},
+		{locale: "fr", def: "he", want: []string{"ltr", "rtl"}},
+	}
+	for _, tt := range tests {
+		shown, _, err := Select(fsys, facts.Facts{OS: "linux", Locale: tt.locale})
+		if err != nil {
+			t.Fatalf("Select(%q): %v", tt.locale, err)
+		}
+		var got []string
+		for _, p := range shown {
+			got = append(got, p.Dir(tt.def))
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("Select(%q) dirs = %q, want %q", tt.locale, got, tt.want)
+		}
+	}
+}
+
 func TestLoadTestdata(t *testing.T) {
 	t.Parallel()
 
 	root := testdataRoot(t)
 	pagesDir := filepath.Join(root, "testdata", "pages")
 
-	pages, err := LoadForPlatform(pagesDir, runtime.GOOS)
+	pages, err := LoadForPlatform(os.DirFS(pagesDir), runtime.GOOS)
 	if err != nil {
 		t.Fatalf("LoadForPlatform(testdata): %v", err)
 	}
@@ -340,7 +900,7 @@ func TestLoadConfig(t *testing.T) {
 			if tt.yaml != "" || tt.wantErr {
 				os.WriteFile(dir+"/day1.yml", []byte(tt.yaml), 0o644)
 			}
-			cfg, err := LoadConfig(dir)
+			cfg, err := LoadConfig(os.DirFS(dir))
 			if tt.wantErr {
 				if err == nil {
 					t.Fatal("expected error")
@@ -365,7 +925,7 @@ func TestLoadConfig(t *testing.T) {
 
 func TestLoadConfigMissing(t *testing.T) {
 	t.Parallel()
-	cfg, err := LoadConfig(t.TempDir())
+	cfg, err := LoadConfig(os.DirFS(t.TempDir()))
 	if err != nil {
 		t.Fatalf("missing config should not error: %v", err)
 	}
@@ -390,3 +950,563 @@ func testdataRoot(t *testing.T) string {
 		dir = parent
 	}
 }
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []string
+	}{
+		{
+			name: "clean directory",
+			files: map[string]string{
+				"day1.yml": "title: Hi\naccent_color: \"#abc\"\npages:\n  - a.md\n",
+				"a.md":     "---\ntitle: A\n---\n# A\n",
+			},
+		},
+		{
+			name: "unknown config key",
+			files: map[string]string{
+				"day1.yml": "title: Hi\ncolour: red\n",
+				"a.md":     "# A\n",
+			},
+			want: []string{"day1.yml:2: field colour not found"},
+		},
+		{
+			name: "missing and orphan pages",
+			files: map[string]string{
+				"day1.yml":  "pages:\n  - a.md\n  - gone.md\n",
+				"a.md":      "# A\n",
+				"orphan.md": "# O\n",
+			},
+			want: []string{
+				`day1.yml:3: page "gone.md" listed in pages but not found`,
+				"orphan.md: not listed in day1.yml pages",
+			},
+		},
+		{
+			name: "bad frontmatter key and platform",
+			files: map[string]string{
+				"a.md": "---\ntitle: A\nplatfrom: linux\nplatform: beos\n---\n# A\n",
+			},
+			want: []string{
+				"a.md:3: field platfrom not found",
+				`a.md:4: unknown platform "beos"`,
+				"no pages shown on darwin",
+				"no pages shown on linux",
+				"no pages shown on windows",
+			},
+		},
+		{
+			name: "bad mode and required page without checklist",
+			files: map[string]string{
+				"day1.yml": "mode: strict\n",
+				"a.md":     "---\ntitle: A\nrequired: true\n---\n# A\n",
+			},
+			want: []string{
+				`day1.yml:1: mode "strict" must be optional or mandatory`,
+				"a.md:3: required: true but the page has no checklist items",
+			},
+		},
+		{
+			name: "bad snooze",
+			files: map[string]string{
+				"day1.yml": "snooze:\n  duration: 2d\n  max: -1\n",
+				"a.md":     "# A\n",
+			},
+			want: []string{
+				`day1.yml:2: snooze duration "2d" must be a duration like 4h or 30m`,
+				"day1.yml:3: snooze max -1 must not be negative",
+			},
+		},
+		{
+			name: "bad dir",
+			files: map[string]string{
+				"a.md": "---\ntitle: A\ndir: right\n---\n# A\n",
+			},
+			want: []string{`a.md:3: unknown dir "right" (want ltr, rtl, auto)`},
+		},
+		{
+			name: "missing image",
+			files: map[string]string{
+				"a.md": "---\ntitle: A\n---\n# A\n\n![x](img/missing.png)\n",
+			},
+			want: []string{`a.md:6: image "img/missing.png" not found`},
+		},
+		{
+			name: "present image",
+			files: map[string]string{
+				"a.md":     "# A\n\n![x](logo.png)\n",
+				"logo.png": "png",
+				"day1.yml": "brand:\n  logo: logo.png\n",
+			},
+		},
+		{
+			name: "invalid accent and help url",
+			files: map[string]string{
+				"day1.yml": "accent_color: green\nhelp_url: javascript:alert(1)\n",
+				"a.md":     "# A\n",
+			},
+			want: []string{
+				`day1.yml:1: accent_color "green"`,
+				`day1.yml:2: help_url "javascript:alert(1)" is blocked on windows, darwin, linux`,
+			},
+		},
+		{
+			name: "missing final page",
+			files: map[string]string{
+				"day1.yml": "final_page: done.md\n",
+				"a.md":     "# A\n",
+			},
+			want: []string{`day1.yml:1: final_page "done.md" not found`},
+		},
+		{
+			name: "duplicate page id",
+			files: map[string]string{
+				"a.md": "---\nid: same\n---\n# A\n",
+				"b.md": "---\nid: same\n---\n# B\n",
+			},
+			want: []string{`b.md: page id "same" already used by a.md`},
+		},
+		{
+			name: "invalid page id",
+			files: map[string]string{
+				"a.md": "---\nid: \"has space\"\n---\n# A\n",
+			},
+			want: []string{`a.md: page id "has space"`},
+		},
+		{
+			name: "no pages for a platform",
+			files: map[string]string{
+				"a.md": "---\nplatform: darwin\n---\n# A\n",
+			},
+			want: []string{"no pages shown on linux", "no pages shown on windows"},
+		},
+		{
+			name: "blocked links",
+			files: map[string]string{
+				"day1.yml": "links:\n  schemes:\n    - scheme: slack\n  deny_hosts: [\"*.evil.com\"]\n",
+				"a.md":     "# A\n\n[Chat](slack://open) [Zoom](zoommtg://x)\n\n[Bad](https://www.evil.com/x)\n",
+				"b.md":     "---\nplatform: darwin\n---\n[Update](ms-settings:windowsupdate) [Lock](x-apple.systempreferences:com.apple.preference.security)\n",
+			},
+			want: []string{
+				`a.md:3: link "zoommtg://x" is blocked: scheme is not allowed`,
+				`a.md:5: link "https://www.evil.com/x" is blocked: host www.evil.com is denied`,
+				`b.md:4: link "ms-settings:windowsupdate" is blocked: scheme ms-settings is only allowed on windows`,
+			},
+		},
+		{
+			name: "day1 links and actions",
+			files: map[string]string{
+				"day1.yml": "actions:\n  wifi:\n    copy: x\n  empty: {}\n  bad:\n    url: zoommtg://x\n",
+				"a.md":     "# A\n\n[B](b.md) [Gone](gone.md) [Two](day1:check/b/two)\n\n[X](day1:check/nope) [W](day1:action/wifi) [V](day1:action/vpn) [J](day1:jump)\n\n- [ ] one {#one}\n",
+				"b.md":     "---\nid: bee\n---\n- [ ] two {#two}\n",
+			},
+			want: []string{
+				`day1.yml:4: action "empty" needs either url or copy`,
+				`day1.yml:6: action "bad": url "zoommtg://x" is blocked: scheme is not allowed`,
+				`a.md:3: link "gone.md" points to unknown page "gone"`,
+				`a.md:5: link "day1:check/nope" points to unknown checklist item "nope" in a.md`,
+				`a.md:5: link "day1:action/vpn" runs unknown action "vpn"`,
+				`a.md:5: link "day1:jump" is blocked: unknown day1 link "jump"`,
+			},
+		},
+		{
+			name: "invalid links section",
+			files: map[string]string{
+				"day1.yml": "links:\n  schemes:\n    - scheme: javascript\n",
+				"a.md":     "# A\n",
+			},
+			want: []string{`day1.yml:2: links: scheme "javascript" can't be allowed`},
+		},
+		{
+			name: "when conditions",
+			files: map[string]string{
+				"day1.yml": "pages:\n  - a.md\n  - file: b.md\n    when: {os: beos}\n  - file: c.md\n    wen: {os: linux}\n",
+				"a.md":     "---\ntitle: A\nwhen:\n  os_version: \">= 1x\"\n---\n# A\n",
+				"b.md":     "# B\n",
+				"c.md":     "# C\n",
+			},
+			want: []string{
+				`a.md:4: when: invalid os_version ">= 1x"`,
+				`day1.yml:3: page "b.md": when: unknown os "beos"`,
+				"day1.yml:6: field wen not found",
+			},
+		},
+		{
+			name: "directives",
+			files: map[string]string{
+				"a.md": "---\ntitle: A\n---\n::: platform beos\n:::\n::: when grup: eng\n:::\n::: note\n:::\n::: platform darwin\n[Lock](ms-settings:lockscreen)\n:::\n::: platform windows\n[Lock](ms-settings:privacy)\n",
+			},
+			want: []string{
+				`a.md:4: ::: platform: unknown platform "beos"`,
+				"a.md:6: ::: when: field grup not found",
+				"a.md:8: unknown directive ::: note (want platform or when)",
+				`a.md:11: link "ms-settings:lockscreen" is blocked`,
+				"a.md:13: ::: platform is not closed",
+			},
+		},
+		{
+			name: "templates",
+			files: map[string]string{
+				"day1.yml": "vars:\n  start: Monday\n",
+				"a.md":     "---\ntitle: A\n---\nHi {{ .FirstName }} {{ .Vars.start }}\n{{ .Email }}\n{{ .Vars.desk }} {{ .Env.HOME }}\n{{ with .Vars }}{{ .start }}{{ end }}\n{{ .User.Name }} {{ .Vars.start.Day }}\n",
+				"b.md":     "# B\n\n{{ if .User }}\n",
+			},
+			want: []string{
+				"a.md:5: unknown template field .Email (want one of .User, .FullName",
+				"a.md:6: template variable .Vars.desk is not set in day1.yml vars",
+				"a.md:6: environment variable HOME doesn't start with DAY1_",
+				"a.md:8: template field .User is a string and has no field .Name",
+				"a.md:8: template value .Vars.start is a string and has no field .Day",
+				"b.md:4: unexpected EOF",
+			},
+		},
+		{
+			name: "includes",
+			files: map[string]string{
+				"day1.yml":            "pages:\n  - a.md\n  - b.md\n  - snippets/contact.md\n",
+				"a.md":                "# A\n\n{{ include \"snippets/contact.md\" }}\n{{ include \"../secret.md\" }}\n{{ include \"gone.md\" }}\n{{ include (printf \"x.md\") }}\n",
+				"b.md":                "# B\n\n{{ include \"loop.md\" }}\n",
+				"loop.md":             "{{ include \"b.md\" }}\n",
+				"snippets/contact.md": "Call {{ .Vars.phone }}.\n",
+			},
+			want: []string{
+				`a.md:4: invalid include path "../secret.md"`,
+				`a.md:5: included file "gone.md" not found`,
+				"a.md:6: include takes one quoted path",
+				"snippets/contact.md: listed in day1.yml pages but included by a.md",
+				"loop.md:1: include cycle: b.md -> loop.md -> b.md",
+				"snippets/contact.md:1: template variable .Vars.phone is not set",
+			},
+		},
+		{
+			name: "locales",
+			files: map[string]string{
+				"day1.yml":    "locale: english\nstrings: {nxt: Go}\npages:\n  - a.md\n",
+				"a.md":        "# A\n",
+				"a.de.md":     "# A\n",
+				"gone.fr.md":  "# Gone\n",
+				"day1.he.yml": "title: x\ncolour: red\n",
+			},
+			want: []string{
+				`day1.yml:1: locale "english" must be a language tag like en or pt-BR`,
+				`day1.yml:2: unknown string "nxt"`,
+				"no english translation for strings",
+				"day1.he.yml:2: field colour not found",
+				"day1.he.yml: no he translation for strings back, close, completed, copied, done_text",
+				"gone.fr.md: translates gone.md, which is not a page",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var got []string
+			for _, p := range Validate(dir) {
+				got = append(got, p.String())
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d problems, want %d:\n%s", len(got), len(tt.want), strings.Join(got, "\n"))
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(strings.Join(got, "\n"), want) {
+					t.Errorf("problems missing %q\ngot:\n%s", want, strings.Join(got, "\n"))
+				}
+			}
+		})
+	}
+}
+
+func TestValidateTestdata(t *testing.T) {
+	t.Parallel()
+	dir := filepath.Join(testdataRoot(t), "testdata", "pages")
+	if problems := Validate(dir); len(problems) > 0 {
+		t.Errorf("testdata/pages has problems: %v", problems)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	t.Parallel()
+	md := "[a](https://x.example) [rel](other.md) [anchor](#top) <https://auto.example> <me@example.com>\n\n- [ ] [Chat](slack://open)\n"
+	got := Links(md)
+	want := []string{"https://x.example", "https://auto.example", "mailto:me@example.com", "slack://open"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Links() = %v, want %v", got, want)
+	}
+}
+
+func TestLinkPolicy(t *testing.T) {
+	t.Parallel()
+	cfg := Config{Links: LinkConfig{
+		Schemes: []LinkScheme{
+			{Scheme: "mailto"},
+			{Scheme: "itportal", Platform: "windows"},
+			{Scheme: "intranet", Kind: "web"},
+		},
+		AllowHosts: []string{"*.corp.example"},
+	}}
+	policy, err := cfg.LinkPolicy()
+	if err != nil {
+		t.Fatalf("LinkPolicy() = %v", err)
+	}
+	for _, tt := range []struct {
+		url, goos string
+		want      bool
+	}{
+		{"mailto:it@corp.example", "linux", true},
+		{"itportal://ticket", "windows", true},
+		{"itportal://ticket", "darwin", false},
+		{"intranet://wiki.corp.example/x", "linux", true},
+		{"intranet://wiki.other.example/x", "linux", false},
+		{"https://github.com", "linux", false},
+	} {
+		if got := policy.AllowedOn(tt.url, tt.goos); got != tt.want {
+			t.Errorf("AllowedOn(%q, %q) = %v, want %v", tt.url, tt.goos, got, tt.want)
+		}
+	}
+
+	for _, bad := range []LinkScheme{
+		{Scheme: "x", Kind: "program"},
+		{Scheme: "x", Platform: "beos"},
+		{Scheme: "file"},
+	} {
+		cfg := Config{Links: LinkConfig{Schemes: []LinkScheme{bad}}}
+		if _, err := cfg.LinkPolicy(); err == nil {
+			t.Errorf("LinkPolicy(%+v): expected error", bad)
+		}
+	}
+}
+
+func TestChecklist(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		markdown string
+		opts     []RenderOption
+		wantIDs  []string
+		wantText []string
+		wantReq  []bool
+	}{
+		{
+			name:     "explicit ids",
+			markdown: "- [ ] **Email** — [Open](https://x) {#email}\n- [x] MFA {#mfa}\n",
+			wantIDs:  []string{"email", "mfa"},
+			wantText: []string{"Email — Open", "MFA"},
+		},
+		{
+			name:     "required items",
+			markdown: "- [ ] Training {#training required}\n- [ ] Policy {required}\n- [ ] Chat {#chat}\n- [ ] Lunch {optional}\n",
+			wantIDs:  []string{"training", hashID("policy"), "chat", hashID("lunch {optional}")},
+			wantText: []string{"Training", "Policy", "Chat", "Lunch {optional}"},
+			wantReq:  []bool{true, true, false, false},
+		},
+		{
+			name:     "hash ignores case and whitespace",
+			markdown: "- [ ] Join  the chat\n- [ ] join the CHAT\n",
+			wantIDs:  []string{hashID("join the chat"), hashID("join the chat") + "-2"},
+		},
+		{
+			name:     "plain list items ignored",
+			markdown: "- not a task\n- [ ] task\n",
+			wantIDs:  []string{hashID("task")},
+		},
+		{
+			name:     "marker outside checklist is text",
+			markdown: "Heading {#x}\n",
+		},
+		{
+			name:     "ids don't depend on hidden blocks",
+			markdown: "::: platform windows\n- [ ] Join the chat\n:::\n\n::: platform darwin\n- [ ] Join the chat\n- [ ] Enable FileVault {#vault}\n:::\n",
+			opts:     []RenderOption{WithPlatform("darwin")},
+			wantIDs:  []string{hashID("join the chat") + "-2", "vault"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got := Checklist(tt.markdown, tt.opts...)
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("got %d items, want %d: %+v", len(got), len(tt.wantIDs), got)
+			}
+			for i, want := range tt.wantIDs {
+				if got[i].ID != want {
+					t.Errorf("item[%d].ID = %q, want %q", i, got[i].ID, want)
+				}
+			}
+			for i, want := range tt.wantText {
+				if got[i].Text != want {
+					t.Errorf("item[%d].Text = %q, want %q", i, got[i].Text, want)
+				}
+			}
+			for i, want := range tt.wantReq {
+				if got[i].Required != want {
+					t.Errorf("item[%d].Required = %v, want %v", i, got[i].Required, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderChecklistIDs(t *testing.T) {
+	t.Parallel()
+	got, err := RenderHTML("- [ ] Sign in {#email}\n- [x] Done\n- [ ] Take the training {#training required}\n", "")
+	if err != nil {
+		t.Fatalf("RenderHTML: %v", err)
+	}
+	for _, want := range []string{
+		`<input data-check-id="email" disabled="" type="checkbox"> Sign in</li>`,
+		`<input data-check-id="` + hashID("Done") + `" checked="" disabled="" type="checkbox">`,
+		`<input data-check-id="training" disabled="" type="checkbox"> Take the training</li>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q\ngot: %s", want, got)
+		}
+	}
+	if strings.Contains(got, "{#email}") {
+		t.Errorf("id marker not stripped: %s", got)
+	}
+}
+
+func TestPageID(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"welcome.md": "---\ntitle: Welcome\n---\n",
+		"vpn.md":     "---\nid: network-setup\n---\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := LoadForPlatform(os.DirFS(dir), "linux")
+	if err != nil {
+		t.Fatalf("LoadForPlatform: %v", err)
+	}
+	ids := map[string]string{}
+	for _, p := range got {
+		ids[p.SourceFile] = p.ID()
+	}
+	if ids["welcome.md"] != "welcome" {
+		t.Errorf("welcome.md id = %q, want welcome", ids["welcome.md"])
+	}
+	if ids["vpn.md"] != "network-setup" {
+		t.Errorf("vpn.md id = %q, want network-setup", ids["vpn.md"])
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	t.Parallel()
+
+	base := fstest.MapFS{
+		"day1.yml":   {Data: []byte("title: Base\n")},
+		"welcome.md": {Data: []byte("---\ntitle: Base Welcome\norder: 1\n---\n# Base")},
+		"tools.md":   {Data: []byte("---\ntitle: Tools\norder: 2\n---\n# Tools")},
+	}
+	company := fstest.MapFS{
+		"welcome.md":     {Data: []byte("---\ntitle: Company Welcome\norder: 1\n---\n# Company")},
+		"vpn.md":         {Data: []byte("---\ntitle: VPN\norder: 3\n---\n# VPN")},
+		"assets/vpn.png": {Data: []byte("png")},
+	}
+	fsys := Overlay(company, base)
+
+	if err := fstest.TestFS(fsys, "day1.yml", "welcome.md", "tools.md", "vpn.md", "assets/vpn.png"); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadForPlatform(fsys, "linux")
+	if err != nil {
+		t.Fatalf("LoadForPlatform: %v", err)
+	}
+	var titles []string
+	for _, p := range got {
+		titles = append(titles, p.Frontmatter.Title)
+	}
+	if want := "Company Welcome,Tools,VPN"; strings.Join(titles, ",") != want {
+		t.Errorf("titles = %v, want %s", titles, want)
+	}
+	if cfg, _ := LoadConfig(fsys); cfg.Title != "Base" {
+		t.Errorf("config title = %q, want Base from the lower layer", cfg.Title)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "pages.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for name, content := range map[string]string{
+		"day1.yml":   "pages:\n  - welcome.md\n",
+		"welcome.md": "---\ntitle: Zipped\n---\n# Hi",
+	} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not pages"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{archive, "Zipped", false},
+		{filepath.Join(testdataRoot(t), "testdata", "pages"), "", false},
+		{filepath.Join(dir, "notes.txt"), "", true},
+		{filepath.Join(dir, "missing"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.path), func(t *testing.T) {
+			fsys, cleanup, err := Open(tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer cleanup()
+			got, err := LoadForPlatform(fsys, "linux")
+			if err != nil || len(got) == 0 {
+				t.Fatalf("LoadForPlatform: %d pages, err %v", len(got), err)
+			}
+			if tt.want != "" && got[0].Frontmatter.Title != tt.want {
+				t.Errorf("title = %q, want %q", got[0].Frontmatter.Title, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package pages
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Open returns the pages at path as an fs.FS rooted at day1.yml: either a
+// directory or a .zip archive. Callers must call cleanup.
+func Open(path string) (fsys fs.FS, cleanup func(), err error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return os.DirFS(path), func() {}, nil
+	}
+	if !strings.EqualFold(filepath.Ext(path), ".zip") {
+		return nil, nil, fmt.Errorf("%s: not a directory or .zip archive", path)
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open archive: %w", err)
+	}
+	return zr, func() { zr.Close() }, nil
+}
+
+// Overlay returns an fs.FS that reads each file from the first layer that
+// has it, so a small layer of company pages can override or extend a base
+// such as the built-in pages. Directory listings are merged.
+func Overlay(layers ...fs.FS) fs.FS { return overlayFS(layers) }
+
+type overlayFS []fs.FS
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	for _, layer := range o {
+		f, err := layer.Open(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if info, err := f.Stat(); err != nil || !info.IsDir() {
+			return f, err
+		}
+		entries, err := o.ReadDir(name)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &overlayDir{File: f, entries: entries}, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir merges the listings of every layer that has name. Entries of
+// earlier layers shadow entries of the same name in later ones.
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrInvalid}
+	}
+	seen := map[string]bool{}
+	var out []fs.DirEntry
+	found := false
+	for _, layer := range o {
+		entries, err := fs.ReadDir(layer, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = true
+		for _, e := range entries {
+			if !seen[e.Name()] {
+				seen[e.Name()] = true
+				out = append(out, e)
+			}
+		}
+	}
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name() < out[j].Name() })
+	return out, nil
+}
+
+// overlayDir is a directory of the first layer that has it, listing the
+// merged entries of all layers.
+type overlayDir struct {
+	fs.File
+	entries []fs.DirEntry
+	off     int
+}
+
+func (d *overlayDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.off:]
+	if n <= 0 {
+		d.off = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	d.off += n
+	return rest[:n], nil
+}
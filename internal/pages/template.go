@@ -0,0 +1,357 @@
+package pages
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+	"text/template/parse"
+
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/version"
+)
+
+// DefaultEnvPrefix is the prefix environment variables need to be visible
+// to page templates when day1.yml doesn't set env_prefix.
+const DefaultEnvPrefix = "DAY1_"
+
+// TemplateData is what page templates see as dot:
+//
+//	Welcome, {{ .FirstName }}! Your laptop is {{ .Hostname }}.
+//	You start on {{ .Vars.start_date }} with team {{ .Env.DAY1_TEAM }}.
+//
+// Every value is escaped for markdown when it is printed, so facts can't
+// add links, HTML or formatting to a page. A missing Vars key is an
+// error. An environment variable with the env prefix that isn't set
+// prints as empty, so a machine without it still shows the page.
+type TemplateData struct {
+	User      string
+	FullName  string
+	FirstName string
+	Hostname  string
+	OS        string
+	Arch      string
+	Distro    string
+	OSVersion string
+	// Version is the day1 version.
+	Version string
+	// Vars are the vars: of day1.yml.
+	Vars map[string]string
+	// Env holds the environment variables that start with the env_prefix
+	// of day1.yml, keyed by their full name.
+	Env map[string]string
+
+	envPrefix string
+	// placeholder prints unset environment variables as their name.
+	placeholder bool
+}
+
+// NewTemplateData returns the template data of a machine with facts f for
+// the content configured by cfg.
+func NewTemplateData(f facts.Facts, cfg Config) TemplateData {
+	d := TemplateData{
+		User:      f.User,
+		FullName:  f.FullName,
+		FirstName: f.User,
+		Hostname:  f.Hostname,
+		OS:        f.OS,
+		Arch:      f.Arch,
+		Distro:    f.Distro,
+		OSVersion: f.OSVersion,
+		Version:   version.Version,
+		Vars:      cfg.Vars,
+		Env:       map[string]string{},
+		envPrefix: cfg.envPrefix(),
+	}
+	if d.Vars == nil {
+		d.Vars = map[string]string{}
+	}
+	if first, _, _ := strings.Cut(f.FullName, " "); first != "" {
+		d.FirstName = first
+	}
+	for k, v := range f.Env {
+		if strings.HasPrefix(k, d.envPrefix) {
+			d.Env[k] = v
+		}
+	}
+	return d
+}
+
+// PlaceholderData returns template data for content shown to no machine
+// in particular, such as an exported site: facts print as their name in
+// capitals, like FIRST_NAME, and environment variables as theirs. OS is
+// platform unless it is "all". Vars are those of cfg.
+func PlaceholderData(cfg Config, platform string) TemplateData {
+	d := NewTemplateData(facts.Facts{
+		OS:        "OS",
+		Arch:      "ARCH",
+		Distro:    "DISTRO",
+		OSVersion: "OS_VERSION",
+		Hostname:  "HOSTNAME",
+		User:      "USER",
+		FullName:  "FULL_NAME",
+	}, cfg)
+	d.FirstName = "FIRST_NAME"
+	if platform != "all" {
+		d.OS = platform
+	}
+	d.placeholder = true
+	return d
+}
+
+// ExpandTemplate runs markdown from file name as a text/template with
+// data. Markdown without "{{" is returned as is.
+func ExpandTemplate(name, markdown string, data TemplateData) (string, error) {
+	if !strings.Contains(markdown, "{{") {
+		return markdown, nil
+	}
+	t, err := parseTemplate(name, markdown)
+	if err != nil {
+		return "", err
+	}
+	data.Env = withUnsetEnv(data.Env, markdown, data.envPrefix, data.placeholder)
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// envRefRe matches the environment variables a template reads as
+// .Env.NAME.
+var envRefRe = regexp.MustCompile(`\.Env\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// withUnsetEnv returns env with an empty value, or with placeholder its
+// name, for every variable with prefix that markdown reads but env lacks.
+func withUnsetEnv(env map[string]string, markdown, prefix string, placeholder bool) map[string]string {
+	out := maps.Clone(env)
+	if out == nil {
+		out = map[string]string{}
+	}
+	for _, m := range envRefRe.FindAllStringSubmatch(markdown, -1) {
+		if _, ok := out[m[1]]; !ok && strings.HasPrefix(m[1], prefix) {
+			out[m[1]] = ""
+			if placeholder {
+				out[m[1]] = m[1]
+			}
+		}
+	}
+	return out
+}
+
+// escapeFunc is appended to every template action that prints a value.
+const escapeFunc = "_day1_escape"
+
+func parseTemplate(name, markdown string) (*template.Template, error) {
+	t, err := template.New(name).
+		Option("missingkey=error").
+		Funcs(template.FuncMap{escapeFunc: escapeValue, "include": includeFunc}).
+		Parse(markdown)
+	if err != nil {
+		return nil, err
+	}
+	for _, tt := range t.Templates() {
+		if tt.Tree != nil {
+			escapeActions(tt.Tree, tt.Tree.Root)
+		}
+	}
+	return t, nil
+}
+
+// escapeActions pipes the value of every printing action below n through
+// escapeFunc, the way html/template adds its escapers.
+func escapeActions(tree *parse.Tree, n parse.Node) {
+	switch n := n.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, c := range n.Nodes {
+			escapeActions(tree, c)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) > 0 {
+			return
+		}
+		id := parse.NewIdentifier(escapeFunc).SetTree(tree).SetPos(n.Pos)
+		n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos, Args: []parse.Node{id}})
+	case *parse.IfNode:
+		escapeActions(tree, n.List)
+		escapeActions(tree, n.ElseList)
+	case *parse.RangeNode:
+		escapeActions(tree, n.List)
+		escapeActions(tree, n.ElseList)
+	case *parse.WithNode:
+		escapeActions(tree, n.List)
+		escapeActions(tree, n.ElseList)
+	}
+}
+
+// markdownSpecial are the characters that can start markdown or HTML
+// anywhere in a line.
+const markdownSpecial = "\\`*[]<>&~|!#"
+
+// escapeValue prints v as literal markdown text on one line.
+func escapeValue(v any) string {
+	s := strings.Join(strings.Fields(fmt.Sprint(v)), " ")
+	var b strings.Builder
+	for i, r := range s {
+		switch {
+		case strings.ContainsRune(markdownSpecial, r):
+			b.WriteByte('\\')
+		case r == '_' && !(i > 0 && isAlnum(s[i-1]) && i+1 < len(s) && isAlnum(s[i+1])):
+			b.WriteByte('\\')
+		case i == 0 && strings.ContainsRune("-+=", r):
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func isAlnum(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// templateFields are the names templates may use after the root dot.
+var templateFields = func() []string {
+	var names []string
+	for _, f := range reflect.VisibleFields(reflect.TypeOf(TemplateData{})) {
+		if f.IsExported() {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}()
+
+// templateProblem is a template error at a line of the markdown.
+type templateProblem struct {
+	line int
+	msg  string
+}
+
+// checkTemplate reports syntax errors in markdown and references to
+// fields, vars or environment variables that no machine can have, and
+// include calls that aren't a plain include action. Fields are only
+// checked where dot is still the template data, outside with and range.
+func checkTemplate(name, markdown string, cfg Config) []templateProblem {
+	if !strings.Contains(markdown, "{{") {
+		return nil
+	}
+	// Include actions are spliced before expansion; validate checks them
+	// separately.
+	markdown = includeRe.ReplaceAllString(markdown, "")
+	t, err := parseTemplate(name, markdown)
+	if err != nil {
+		return []templateProblem{templateErrorLine(name, err)}
+	}
+	var out []templateProblem
+	add := func(tree *parse.Tree, n parse.Node, msg string) {
+		if msg != "" {
+			loc, _ := tree.ErrorContext(n)
+			out = append(out, templateProblem{line: locLine(loc), msg: msg})
+		}
+	}
+	var walk func(tree *parse.Tree, n parse.Node, root bool)
+	walk = func(tree *parse.Tree, n parse.Node, root bool) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(tree, c, root)
+			}
+		case *parse.ActionNode:
+			walk(tree, n.Pipe, root)
+		case *parse.PipeNode:
+			for _, c := range n.Cmds {
+				for _, arg := range c.Args {
+					walk(tree, arg, root)
+				}
+			}
+		case *parse.IfNode:
+			walk(tree, n.Pipe, root)
+			walk(tree, n.List, root)
+			walk(tree, n.ElseList, root)
+		case *parse.RangeNode:
+			walk(tree, n.Pipe, root)
+			walk(tree, n.List, false)
+			walk(tree, n.ElseList, root)
+		case *parse.WithNode:
+			walk(tree, n.Pipe, root)
+			walk(tree, n.List, false)
+			walk(tree, n.ElseList, root)
+		case *parse.IdentifierNode:
+			if n.Ident == "include" {
+				add(tree, n, includeUsage)
+			}
+		case *parse.FieldNode:
+			if root {
+				add(tree, n, checkField(n.Ident, cfg))
+			}
+		case *parse.VariableNode:
+			if n.Ident[0] == "$" && len(n.Ident) > 1 {
+				add(tree, n, checkField(n.Ident[1:], cfg))
+			}
+		}
+	}
+	for _, tt := range t.Templates() {
+		if tt.Tree != nil {
+			walk(tt.Tree, tt.Tree.Root, tt.Name() == name)
+		}
+	}
+	return out
+}
+
+// checkField returns why .ident can't be evaluated, or "".
+func checkField(ident []string, cfg Config) string {
+	if !slices.Contains(templateFields, ident[0]) {
+		return fmt.Sprintf("unknown template field .%s (want one of .%s)", ident[0], strings.Join(templateFields, ", ."))
+	}
+	if len(ident) < 2 {
+		return ""
+	}
+	if ident[0] != "Vars" && ident[0] != "Env" {
+		return fmt.Sprintf("template field .%s is a string and has no field .%s", ident[0], ident[1])
+	}
+	if len(ident) > 2 {
+		return fmt.Sprintf("template value .%s is a string and has no field .%s", strings.Join(ident[:2], "."), ident[2])
+	}
+	switch key := ident[1]; ident[0] {
+	case "Vars":
+		if _, ok := cfg.Vars[key]; !ok {
+			return fmt.Sprintf("template variable .Vars.%s is not set in day1.yml vars", key)
+		}
+	case "Env":
+		if prefix := cfg.envPrefix(); !strings.HasPrefix(key, prefix) {
+			return fmt.Sprintf("environment variable %s doesn't start with %s and is never visible to templates", key, prefix)
+		}
+	}
+	return ""
+}
+
+// templateErrorLine extracts the line from a text/template error such as
+// "template: a.md:3: unexpected EOF".
+func templateErrorLine(name string, err error) templateProblem {
+	msg := strings.TrimPrefix(err.Error(), "template: "+name+":")
+	if msg == err.Error() {
+		return templateProblem{msg: msg}
+	}
+	loc, rest, _ := strings.Cut(msg, ": ")
+	return templateProblem{line: locLine(name + ":" + loc), msg: rest}
+}
+
+// locLine returns the line of a "name:line:col" location.
+func locLine(loc string) int {
+	parts := strings.Split(loc, ":")
+	if len(parts) < 2 {
+		return 0
+	}
+	var n int
+	fmt.Sscanf(parts[1], "%d", &n)
+	return n
+}
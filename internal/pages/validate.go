@@ -0,0 +1,780 @@
+package pages
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/TsekNet/day1/internal/i18n"
+	"github.com/TsekNet/day1/internal/platform"
+	"github.com/TsekNet/day1/internal/urischeme"
+	"gopkg.in/yaml.v3"
+)
+
+// Platforms lists every GOOS value day1 ships for. Validation loads the
+// pages once per platform so platform-filtered pages are checked too. The
+// virtual platform.WSL is not listed: it shows linux and windows pages.
+var Platforms = []string{"windows", "darwin", "linux"}
+
+// Problem is a single validation finding. Line is 1-based, or 0 when the
+// problem applies to the whole file.
+type Problem struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (p Problem) String() string {
+	if p.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Msg)
+	}
+	return fmt.Sprintf("%s: %s", p.File, p.Msg)
+}
+
+var (
+	accentColorRe = regexp.MustCompile(`^#([0-9A-Fa-f]{3}|[0-9A-Fa-f]{6})$`)
+	yamlLineRe    = regexp.MustCompile(`line (\d+): (.*)`)
+	actionNameRe  = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+var (
+	validThemes = map[string]bool{"": true, "auto": true, "light": true, "dark": true}
+	validModes  = map[string]bool{"": true, ModeOptional: true, ModeMandatory: true}
+)
+
+// Validate lints the pages directory or .zip archive at dir the same way
+// the wizard would load it and returns every problem found, sorted by file
+// and line. An empty result means the content is safe to ship.
+func Validate(dir string) []Problem {
+	v := &validator{dir: dir}
+	fsys, cleanup, err := Open(dir)
+	if err != nil {
+		v.add(dir, 0, "%v", err)
+		return v.problems
+	}
+	defer cleanup()
+	v.fsys = fsys
+	v.run()
+	sort.SliceStable(v.problems, func(i, j int) bool {
+		if v.problems[i].File != v.problems[j].File {
+			return v.problems[i].File < v.problems[j].File
+		}
+		return v.problems[i].Line < v.problems[j].Line
+	})
+	return v.problems
+}
+
+type validator struct {
+	dir      string // for messages about the content as a whole
+	fsys     fs.FS
+	problems []Problem
+	seen     map[string]bool
+	links    *urischeme.Policy
+	cfg      Config
+	refs     []internalRef
+	items    map[string]map[string]bool // checklist item IDs by file
+}
+
+// internalRef is a day1: link, or a relative link to a page, found in a
+// page. Its target is checked once every page is known.
+type internalRef struct {
+	file string
+	line int
+	dest string // as written
+	link urischeme.Internal
+}
+
+func (v *validator) add(file string, line int, format string, args ...any) {
+	p := Problem{File: file, Line: line, Msg: fmt.Sprintf(format, args...)}
+	if v.seen == nil {
+		v.seen = map[string]bool{}
+	}
+	if key := p.String(); !v.seen[key] {
+		v.seen[key] = true
+		v.problems = append(v.problems, p)
+	}
+}
+
+func (v *validator) run() {
+	v.links = urischeme.Default()
+	cfg, root := v.checkConfig()
+	v.cfg = cfg
+
+	files, unlisted := v.checkPageList(cfg, root)
+	finalPage := ""
+	if cfg.FinalPage != "" {
+		line := nodeLine(root, "final_page")
+		if !safeRelPath(cfg.FinalPage) {
+			v.add(configFileName, line, "final_page %q must be a relative path without '..'", cfg.FinalPage)
+		} else if _, err := fs.Stat(v.fsys, cfg.FinalPage); err != nil {
+			v.add(configFileName, line, "final_page %q not found", cfg.FinalPage)
+		} else {
+			finalPage = cfg.FinalPage
+		}
+	}
+
+	roots := files
+	if finalPage != "" {
+		roots = append(slices.Clone(files), finalPage)
+	}
+	included := v.checkIncludes(roots)
+	files = slices.DeleteFunc(files, func(name string) bool {
+		by, ok := included[name]
+		if ok && len(cfg.Pages) > 0 {
+			v.add(name, 0, "listed in %s pages but included by %s; included files are never pages", configFileName, by)
+		}
+		return ok
+	})
+
+	ids := map[string]string{}
+	for _, name := range files {
+		id := v.checkPage(name)
+		if prev, ok := ids[id]; ok {
+			v.add(name, 0, "page id %q already used by %s", id, prev)
+			continue
+		}
+		ids[id] = name
+	}
+	if finalPage != "" {
+		v.checkPage(finalPage)
+	}
+	for _, name := range slices.Sorted(maps.Keys(included)) {
+		v.checkPage(name)
+	}
+	known := map[string]bool{finalPage: finalPage != ""}
+	for _, name := range files {
+		known[name] = true
+	}
+	for name := range included {
+		known[name] = true
+	}
+	v.checkLocales(cfg, known)
+	for _, name := range unlisted {
+		// checkLocales reports translations of missing pages.
+		if _, tag := splitTranslation(name); !known[name] && tag == "" {
+			v.add(name, 0, "not listed in %s pages and will never be shown", configFileName)
+		}
+	}
+	v.checkRefs(cfg, ids)
+
+	// The loader stops at the first error, so its findings are only new
+	// when nothing more specific was reported above.
+	found := len(v.problems) > 0
+	for _, goos := range Platforms {
+		loaded, err := LoadForPlatform(v.fsys, goos)
+		switch {
+		case err != nil && !found:
+			v.add(configFileName, 0, "load pages for %s: %v", goos, err)
+		case err == nil && len(loaded) == 0:
+			v.add(v.dir, 0, "no pages shown on %s", goos)
+		}
+	}
+}
+
+// checkConfig strictly decodes day1.yml and checks individual values. The
+// returned node is the document root, or nil if the file is absent or broken.
+func (v *validator) checkConfig() (Config, *yaml.Node) {
+	data, err := fs.ReadFile(v.fsys, configFileName)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		v.add(configFileName, 0, "%v", err)
+		return Config{}, nil
+	}
+
+	var cfg Config
+	if err := decodeStrict(data, &cfg); err != nil {
+		v.addYAMLErrors(configFileName, 0, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return cfg, nil
+	}
+	root := &doc
+	if root.Kind == yaml.DocumentNode && len(root.Content) > 0 {
+		root = root.Content[0]
+	}
+
+	if cfg.AccentColor != "" && !accentColorRe.MatchString(cfg.AccentColor) {
+		v.add(configFileName, nodeLine(root, "accent_color"),
+			"accent_color %q must be a hex color like #188038", cfg.AccentColor)
+	}
+	if !validThemes[cfg.Theme] {
+		v.add(configFileName, nodeLine(root, "theme"),
+			"theme %q must be auto, light or dark", cfg.Theme)
+	}
+	if !validModes[cfg.Mode] {
+		v.add(configFileName, nodeLine(root, "mode"),
+			"mode %q must be optional or mandatory", cfg.Mode)
+	}
+	if _, err := cfg.Snooze.Interval(); err != nil {
+		v.add(configFileName, nodeLine(root, "snooze", "duration"), "%v", err)
+	}
+	if cfg.Snooze.Max < 0 {
+		v.add(configFileName, nodeLine(root, "snooze", "max"),
+			"snooze max %d must not be negative", cfg.Snooze.Max)
+	}
+	if policy, err := cfg.LinkPolicy(); err != nil {
+		v.add(configFileName, nodeLine(root, "links"), "links: %v", err)
+	} else {
+		v.links = policy
+	}
+	v.checkHelpURL(configFileName, nodeLine(root, "help_url"), cfg.HelpURL)
+	if cfg.Locale != "" && !isLocaleTag(cfg.Locale) {
+		v.add(configFileName, nodeLine(root, "locale"),
+			"locale %q must be a language tag like en or pt-BR", cfg.Locale)
+	}
+	v.checkStrings(configFileName, root, cfg.Strings)
+	if logo := cfg.Brand.Logo; logo != "" {
+		v.checkAsset(configFileName, nodeLine(root, "brand", "logo"), logo)
+	}
+	v.checkActions(cfg, root)
+	return cfg, root
+}
+
+// checkHelpURL reports a help_url that is blocked on some platform.
+func (v *validator) checkHelpURL(file string, line int, helpURL string) {
+	if helpURL == "" {
+		return
+	}
+	var blocked []string
+	for _, goos := range Platforms {
+		if !v.links.AllowedOn(helpURL, goos) {
+			blocked = append(blocked, goos)
+		}
+	}
+	if len(blocked) > 0 {
+		v.add(file, line, "help_url %q is blocked on %s", helpURL, strings.Join(blocked, ", "))
+	}
+}
+
+// checkStrings reports strings: keys that name no string of the wizard.
+func (v *validator) checkStrings(file string, root *yaml.Node, strs map[string]string) {
+	for _, k := range slices.Sorted(maps.Keys(strs)) {
+		if !i18n.IsKey(k) {
+			v.add(file, nodeLine(root, "strings", k), "unknown string %q (want one of %s)", k, strings.Join(i18n.Keys(), ", "))
+		}
+	}
+}
+
+// checkActions reports actions that do nothing, or that open a link
+// blocked on every platform.
+func (v *validator) checkActions(cfg Config, root *yaml.Node) {
+	names := make([]string, 0, len(cfg.Actions))
+	for name := range cfg.Actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		a := cfg.Actions[name]
+		line := nodeLine(root, "actions", name)
+		switch {
+		case !actionNameRe.MatchString(name):
+			v.add(configFileName, line, "action name %q may only contain letters, digits, '.', '_' and '-'", name)
+		case (a.URL == "") == (a.Copy == ""):
+			v.add(configFileName, line, "action %q needs either url or copy", name)
+		case a.URL != "":
+			if in, err := urischeme.ParseInternal(a.URL); err == nil && in.Op == urischeme.OpAction {
+				v.add(configFileName, line, "action %q can't run another action", name)
+				continue
+			}
+			var err error
+			for _, goos := range Platforms {
+				if err = v.links.Check(a.URL, goos); err == nil {
+					break
+				}
+			}
+			if err != nil {
+				v.add(configFileName, line, "action %q: url %q is blocked: %v", name, a.URL, err)
+			}
+		}
+	}
+}
+
+// checkRefs reports day1: links and relative page links whose page,
+// checklist item or action doesn't exist. ids maps page IDs to files.
+func (v *validator) checkRefs(cfg Config, ids map[string]string) {
+	files := map[string]string{}
+	for id, name := range ids {
+		files[id] = name
+		files[idFromFilename(name)] = name
+	}
+	for _, r := range v.refs {
+		switch r.link.Op {
+		case urischeme.OpPage:
+			if _, ok := files[r.link.Page]; !ok {
+				v.add(r.file, r.line, "link %q points to unknown page %q", r.dest, r.link.Page)
+			}
+		case urischeme.OpCheck:
+			file := r.file
+			if r.link.Page != "" {
+				var ok bool
+				if file, ok = files[r.link.Page]; !ok {
+					v.add(r.file, r.line, "link %q points to unknown page %q", r.dest, r.link.Page)
+					continue
+				}
+			}
+			if r.link.Item != urischeme.CheckAll && !v.items[file][r.link.Item] {
+				v.add(r.file, r.line, "link %q points to unknown checklist item %q in %s", r.dest, r.link.Item, file)
+			}
+		case urischeme.OpAction:
+			if _, ok := cfg.Actions[r.link.Name]; !ok {
+				v.add(r.file, r.line, "link %q runs unknown action %q", r.dest, r.link.Name)
+			}
+		}
+	}
+}
+
+// checkPageList reports missing pages and returns the page files that
+// exist and should be checked individually, and the files on disk that
+// day1.yml doesn't list.
+func (v *validator) checkPageList(cfg Config, root *yaml.Node) (files, unlisted []string) {
+	entries, err := fs.ReadDir(v.fsys, ".")
+	if err != nil {
+		v.add(v.dir, 0, "%v", err)
+		return nil, nil
+	}
+	var onDisk []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".md") && !strings.HasPrefix(e.Name(), ".") && !isTranslation(v.fsys, e.Name()) {
+			onDisk = append(onDisk, e.Name())
+		}
+	}
+
+	if len(cfg.Pages) == 0 {
+		return onDisk, nil
+	}
+
+	listed := map[string]bool{}
+	seq := nodeAt(root, "pages")
+	for i, entry := range cfg.Pages {
+		name, line := entry.File, 0
+		if seq != nil && i < len(seq.Content) {
+			line = seq.Content[i].Line
+			v.checkPageEntry(seq.Content[i])
+		}
+		if entry.When != nil {
+			if err := entry.When.validate(); err != nil {
+				v.add(configFileName, line, "page %q: %v", name, err)
+			}
+		}
+		if listed[name] {
+			v.add(configFileName, line, "page %q listed more than once", name)
+			continue
+		}
+		listed[name] = true
+		if !safeRelPath(name) {
+			v.add(configFileName, line, "invalid page path %q", name)
+			continue
+		}
+		if _, err := fs.Stat(v.fsys, name); err != nil {
+			v.add(configFileName, line, "page %q listed in pages but not found", name)
+			continue
+		}
+		files = append(files, name)
+	}
+
+	for _, name := range onDisk {
+		if !listed[name] {
+			unlisted = append(unlisted, name)
+		}
+	}
+	return files, unlisted
+}
+
+// checkLocales checks the day1.<tag>.yml file of every locale the content
+// has translations for and every translated page. known holds the files a
+// translation may translate.
+func (v *validator) checkLocales(cfg Config, known map[string]bool) {
+	if missing := i18n.Missing(cfg.DefaultLocale(), cfg.Strings); len(missing) > 0 {
+		v.add(configFileName, 0, "no %s translation for strings %s; add them under strings: or they are shown in English",
+			cfg.DefaultLocale(), strings.Join(missing, ", "))
+	}
+	for _, tag := range Locales(v.fsys) {
+		if final := v.checkLocaleConfig(tag); final != "" {
+			known[final] = true
+		}
+	}
+	fs.WalkDir(v.fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(name, ".md") {
+			return nil
+		}
+		page, tag := splitTranslation(name)
+		if dir, rest, ok := strings.Cut(name, "/"); tag == "" && ok && isLocaleTag(dir) {
+			page, tag = rest, dir
+		}
+		switch {
+		case tag == "", known[name]:
+			// Not a translation, or a locale's final page, checked above.
+		case !known[page]:
+			v.add(name, 0, "translates %s, which is not a page, included file or final page", page)
+		default:
+			v.checkPage(name)
+		}
+		return nil
+	})
+}
+
+// checkLocaleConfig strictly decodes day1.<tag>.yml, if it exists, and
+// reports strings the locale has no translation for. It returns the
+// final page the file sets.
+func (v *validator) checkLocaleConfig(tag string) (finalPage string) {
+	name := localeConfigName(tag)
+	data, err := fs.ReadFile(v.fsys, name)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		v.add(name, 0, "%v", err)
+		return ""
+	}
+	if err == nil {
+		var lc localeConfig
+		if err := decodeStrict(data, &lc); err != nil {
+			v.addYAMLErrors(name, 0, err)
+		}
+		var doc yaml.Node
+		root := &doc
+		if yaml.Unmarshal(data, &doc) == nil && len(doc.Content) > 0 {
+			root = doc.Content[0]
+		}
+		v.checkHelpURL(name, nodeLine(root, "help_url"), lc.HelpURL)
+		v.checkStrings(name, root, lc.Strings)
+		if lc.FinalPage != "" {
+			line := nodeLine(root, "final_page")
+			if !safeRelPath(lc.FinalPage) {
+				v.add(name, line, "final_page %q must be a relative path without '..'", lc.FinalPage)
+			} else if _, err := fs.Stat(v.fsys, lc.FinalPage); err != nil {
+				v.add(name, line, "final_page %q not found", lc.FinalPage)
+			} else {
+				v.checkPage(lc.FinalPage)
+				finalPage = lc.FinalPage
+			}
+		}
+	}
+	cfg, _ := LoadLocalizedConfig(v.fsys, tag)
+	if missing := i18n.Missing(tag, cfg.Strings); len(missing) > 0 {
+		v.add(name, 0, "no %s translation for strings %s; add them under strings: or they are shown in English",
+			tag, strings.Join(missing, ", "))
+	}
+	return finalPage
+}
+
+// checkIncludes reports include actions with an invalid path, a missing
+// file or a cycle, and returns the files that roots include, directly or
+// not, each mapped to the first file that includes it.
+func (v *validator) checkIncludes(roots []string) map[string]string {
+	included := map[string]string{}
+	var visit func(stack []string)
+	visit = func(stack []string) {
+		name := stack[len(stack)-1]
+		data, err := fs.ReadFile(v.fsys, name)
+		if err != nil {
+			return
+		}
+		raw := string(data)
+		for _, m := range includeRe.FindAllStringSubmatchIndex(raw, -1) {
+			file := raw[m[2]:m[3]]
+			line := strings.Count(raw[:m[0]], "\n") + 1
+			if !validPagePath(file) {
+				v.add(name, line, "invalid include path %q (want a relative path without '..')", file)
+				continue
+			}
+			if i := slices.Index(stack, file); i >= 0 {
+				v.add(name, line, "include cycle: %s", strings.Join(append(slices.Clone(stack[i:]), file), " -> "))
+				continue
+			}
+			if _, err := fs.Stat(v.fsys, file); err != nil {
+				v.add(name, line, "included file %q not found", file)
+				continue
+			}
+			if _, ok := included[file]; !ok {
+				included[file] = name
+			}
+			visit(append(slices.Clone(stack), file))
+		}
+	}
+	for _, name := range roots {
+		visit([]string{name})
+	}
+	return included
+}
+
+// checkPageEntry strictly decodes a mapping entry of the pages list, which
+// PageEntry's own decoder accepts loosely.
+func (v *validator) checkPageEntry(n *yaml.Node) {
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	data, err := yaml.Marshal(n)
+	if err != nil {
+		return
+	}
+	type plain PageEntry
+	if err := decodeStrict(data, &plain{}); err != nil {
+		v.addYAMLErrors(configFileName, n.Line-1, err)
+	}
+}
+
+// checkPage validates frontmatter, rendering and image references of a
+// single markdown file and returns the page's ID.
+func (v *validator) checkPage(name string) string {
+	id := idFromFilename(name)
+	data, err := fs.ReadFile(v.fsys, name)
+	if err != nil {
+		v.add(name, 0, "%v", err)
+		return id
+	}
+	raw := string(data)
+
+	targets := Platforms
+	block, body, bodyLine, ok := splitFrontmatter(raw)
+	if ok {
+		blockLine := strings.Count(raw[:strings.Index(raw, block)], "\n") + 1
+		var fm Frontmatter
+		if err := decodeStrict([]byte(block), &fm); err != nil {
+			v.addYAMLErrors(name, blockLine-1, err)
+		}
+		if fm.ID != "" {
+			id = fm.ID
+		}
+		keyLine := func(key string) int {
+			var doc yaml.Node
+			if yaml.Unmarshal([]byte(block), &doc) == nil && len(doc.Content) > 0 {
+				return blockLine + nodeLine(doc.Content[0], key) - 1
+			}
+			return blockLine
+		}
+		if fm.Platform != "" && fm.Platform != "all" && !IsPlatform(fm.Platform) {
+			v.add(name, keyLine("platform"), "unknown platform %q (want all, %s or %s)",
+				fm.Platform, strings.Join(Platforms, ", "), platform.WSL)
+		} else if IsPlatform(fm.Platform) {
+			targets = []string{fm.Platform}
+		}
+		if fm.Required && len(Checklist(body)) == 0 {
+			v.add(name, keyLine("required"), "required: true but the page has no checklist items")
+		}
+		if fm.Dir != "" && !slices.Contains(Dirs, fm.Dir) {
+			v.add(name, keyLine("dir"), "unknown dir %q (want %s)", fm.Dir, strings.Join(Dirs, ", "))
+		}
+		if fm.When != nil {
+			if err := fm.When.validate(); err != nil {
+				v.add(name, keyLine("when"), "%v", err)
+			}
+			targets = slices.DeleteFunc(slices.Clone(targets), func(goos string) bool {
+				return fm.When.checkOS(goos) != nil
+			})
+		}
+	}
+	v.checkDirectives(name, body, bodyLine)
+	for _, p := range checkTemplate(name, body, v.cfg) {
+		line := 0
+		if p.line > 0 {
+			line = bodyLine + p.line - 1
+		}
+		v.add(name, line, "%s", p.msg)
+	}
+	v.checkLinks(name, body, bodyLine, targets)
+	v.collectRefs(name, body, bodyLine)
+
+	if !ValidID(id) {
+		v.add(name, 0, "page id %q may only contain letters, digits, '.', '_', '-' and '/'", id)
+	}
+
+	html, err := RenderHTML(body, "")
+	if err != nil {
+		v.add(name, 0, "render: %v", err)
+		return id
+	}
+	for _, m := range imgSrcRe.FindAllStringSubmatch(html, -1) {
+		src := m[2]
+		if isExternal(src) {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(src); err == nil {
+			src = unescaped
+		}
+		v.checkAsset(name, bodyLine+lineOf(body, src)-1, src)
+	}
+	return id
+}
+
+// checkDirectives reports ::: directive blocks with an invalid condition
+// or without a closing ::: line.
+func (v *validator) checkDirectives(name, body string, bodyLine int) {
+	blocks, source := directives(body)
+	for _, d := range blocks {
+		line := bodyLine + bytes.Count(source[:d.start], []byte("\n"))
+		if d.err != nil {
+			v.add(name, line, "%v", d.err)
+		}
+		if !d.closed {
+			v.add(name, line, "::: %s is not closed (add a line with only :::)", d.name)
+		}
+	}
+}
+
+// checkLinks reports links that are blocked on every platform the page is
+// shown on. Settings links for one platform on a shared page are fine, and
+// so are links in ::: platform blocks for the platforms that allow them.
+func (v *validator) checkLinks(name, body string, bodyLine int, targets []string) {
+	var links []string
+	blocked := map[string]error{}
+	for _, goos := range targets {
+		for _, link := range Links(body, WithPlatform(goos)) {
+			err, seen := blocked[link]
+			if !seen {
+				links = append(links, link)
+			} else if err == nil {
+				continue
+			}
+			blocked[link] = v.links.Check(link, goos)
+		}
+	}
+	for _, link := range links {
+		if err := blocked[link]; err != nil {
+			v.add(name, bodyLine+lineOf(body, link)-1, "link %q is blocked: %v", link, err)
+		}
+	}
+}
+
+// collectRefs records the day1: links and relative page links of a page
+// for checkRefs, along with its checklist item IDs.
+func (v *validator) collectRefs(name, body string, bodyLine int) {
+	if v.items == nil {
+		v.items = map[string]map[string]bool{}
+	}
+	items := map[string]bool{}
+	for _, item := range Checklist(body) {
+		items[item.ID] = true
+	}
+	v.items[name] = items
+
+	for _, dest := range linkDests(body) {
+		link := dest
+		if l, ok := pageLink(dest, name); ok {
+			link = l
+		}
+		if !strings.HasPrefix(strings.ToLower(link), urischeme.InternalPrefix) {
+			continue
+		}
+		in, err := urischeme.ParseInternal(link)
+		if err != nil {
+			continue // reported by checkLinks
+		}
+		v.refs = append(v.refs, internalRef{file: name, line: bodyLine + lineOf(body, dest) - 1, dest: dest, link: in})
+	}
+}
+
+// checkAsset reports src if it escapes the pages dir or doesn't exist.
+func (v *validator) checkAsset(file string, line int, src string) {
+	if !safeRelPath(src) {
+		v.add(file, line, "image %q must be a relative path without '..'", src)
+		return
+	}
+	if _, err := fs.Stat(v.fsys, path.Clean(src)); err != nil {
+		v.add(file, line, "image %q not found", src)
+	}
+}
+
+// addYAMLErrors converts yaml.v3 errors into problems. lineOffset is added
+// to the line numbers yaml reports.
+func (v *validator) addYAMLErrors(file string, lineOffset int, err error) {
+	var msgs []string
+	var te *yaml.TypeError
+	if errors.As(err, &te) {
+		msgs = te.Errors
+	} else {
+		msgs = []string{strings.TrimPrefix(err.Error(), "yaml: ")}
+	}
+	for _, msg := range msgs {
+		if m := yamlLineRe.FindStringSubmatch(msg); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			v.add(file, n+lineOffset, "%s", m[2])
+			continue
+		}
+		v.add(file, 0, "%s", msg)
+	}
+}
+
+// decodeStrict unmarshals data into out, rejecting keys out doesn't declare.
+func decodeStrict(data []byte, out any) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(out); err != nil && err != io.EOF {
+		return err
+	}
+	return nil
+}
+
+// nodeAt walks mapping keys from root and returns the value node, or nil.
+func nodeAt(root *yaml.Node, keys ...string) *yaml.Node {
+	n := root
+	for _, key := range keys {
+		if n == nil || n.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == key {
+				next = n.Content[i+1]
+				break
+			}
+		}
+		n = next
+	}
+	return n
+}
+
+func nodeLine(root *yaml.Node, keys ...string) int {
+	if n := nodeAt(root, keys...); n != nil {
+		return n.Line
+	}
+	return 0
+}
+
+// lineOf returns the 1-based line of the first occurrence of needle in s,
+// or 1 if not found.
+func lineOf(s, needle string) int {
+	i := strings.Index(s, needle)
+	if i < 0 {
+		return 1
+	}
+	return strings.Count(s[:i], "\n") + 1
+}
+
+func safeRelPath(p string) bool {
+	return p != "" && !strings.Contains(p, "..") && !filepath.IsAbs(p) && !strings.HasPrefix(p, "/")
+}
+
+func isExternal(src string) bool {
+	for _, s := range skipPrefixes {
+		if strings.HasPrefix(src, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPlatform reports whether goos is one of Platforms or platform.WSL.
+func IsPlatform(goos string) bool {
+	if goos == platform.WSL {
+		return true
+	}
+	for _, p := range Platforms {
+		if p == goos {
+			return true
+		}
+	}
+	return false
+}
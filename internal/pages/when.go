@@ -0,0 +1,232 @@
+package pages
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/platform"
+	"gopkg.in/yaml.v3"
+)
+
+// When is a condition on the machine a page is shown on. Every key that is
+// set must match; a list matches when any of its values does.
+//
+//	when:
+//	  os: [linux, darwin]
+//	  distro: ubuntu
+//	  os_version: ">= 22.04, < 25"
+//	  hostname: "eng-*"
+//	  group: engineering
+//	  env: {TEAM: sre}
+type When struct {
+	OS   StringList `yaml:"os"`
+	Arch StringList `yaml:"arch"`
+	// Distro matches the os-release ID or any ID_LIKE entry.
+	Distro StringList `yaml:"distro"`
+	// OSVersion is a comma-separated list of comparisons such as ">= 13"
+	// or "< 10.0.22000", all of which must hold.
+	OSVersion string `yaml:"os_version"`
+	// Hostname holds path.Match patterns such as "lab-*".
+	Hostname StringList `yaml:"hostname"`
+	Group    StringList `yaml:"group"`
+	// Env maps variable names to path.Match patterns for their value. An
+	// unset variable never matches, so "*" means "set".
+	Env map[string]string `yaml:"env"`
+}
+
+// StringList is a YAML string or list of strings.
+type StringList []string
+
+func (l *StringList) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*l = StringList{n.Value}
+		return nil
+	}
+	var list []string
+	if err := n.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Check returns nil if f satisfies the condition, or an error saying which
+// fact doesn't.
+func (w *When) Check(f facts.Facts) error {
+	if err := w.checkOS(f.OS); err != nil {
+		return err
+	}
+	if len(w.Arch) > 0 && !containsFold(w.Arch, f.Arch) {
+		return mismatch("arch", f.Arch, w.Arch)
+	}
+	if len(w.Distro) > 0 && !containsFold(w.Distro, f.Distro) && !slices.ContainsFunc(f.DistroLike, func(d string) bool {
+		return containsFold(w.Distro, d)
+	}) {
+		return mismatch("distro", f.Distro, w.Distro)
+	}
+	if w.OSVersion != "" {
+		ok, err := versionMatches(w.OSVersion, f.OSVersion)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("os_version is %s, want %s", orUnknown(f.OSVersion), w.OSVersion)
+		}
+	}
+	if len(w.Hostname) > 0 && !matchAny(w.Hostname, strings.ToLower(f.Hostname)) {
+		return mismatch("hostname", f.Hostname, w.Hostname)
+	}
+	if len(w.Group) > 0 && !slices.ContainsFunc(f.Groups, func(g string) bool { return containsFold(w.Group, g) }) {
+		return fmt.Errorf("not in group %s", strings.Join(w.Group, " or "))
+	}
+	for _, name := range sortedKeys(w.Env) {
+		value, ok := f.Env[name]
+		if matched, _ := path.Match(w.Env[name], value); !ok || !matched {
+			return fmt.Errorf("env %s is %q, want %q", name, value, w.Env[name])
+		}
+	}
+	return nil
+}
+
+// checkOS checks only the os key against platform p.
+func (w *When) checkOS(p string) error {
+	if len(w.OS) == 0 || slices.ContainsFunc(w.OS, func(target string) bool { return platform.Matches(target, p) }) {
+		return nil
+	}
+	return mismatch("os", p, w.OS)
+}
+
+// validate reports values that can never be evaluated.
+func (w *When) validate() error {
+	for _, target := range w.OS {
+		if target != "all" && !IsPlatform(target) {
+			return fmt.Errorf("when: unknown os %q", target)
+		}
+	}
+	if w.OSVersion != "" {
+		if _, err := versionMatches(w.OSVersion, "0"); err != nil {
+			return fmt.Errorf("when: %w", err)
+		}
+	}
+	for _, pat := range w.Hostname {
+		if _, err := path.Match(pat, ""); err != nil {
+			return fmt.Errorf("when: invalid hostname pattern %q", pat)
+		}
+	}
+	for name, pat := range w.Env {
+		if _, err := path.Match(pat, ""); err != nil {
+			return fmt.Errorf("when: invalid env pattern %q for %s", pat, name)
+		}
+	}
+	return nil
+}
+
+func mismatch(key, got string, want []string) error {
+	return fmt.Errorf("%s is %s, want %s", key, orUnknown(got), strings.Join(want, " or "))
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return s
+}
+
+func containsFold(list []string, s string) bool {
+	return slices.ContainsFunc(list, func(v string) bool { return strings.EqualFold(v, s) })
+}
+
+func matchAny(patterns []string, s string) bool {
+	for _, pat := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pat), s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+var versionCmpRe = regexp.MustCompile(`^(>=|<=|!=|==|=|>|<)?\s*([0-9]+(?:\.[0-9]+)*)$`)
+
+// versionMatches reports whether version satisfies every comparison in
+// constraint. A comparison without an operator means equality on the
+// components it gives, so "14" matches "14.5". An unknown version never
+// matches.
+func versionMatches(constraint, version string) (bool, error) {
+	ok := version != ""
+	for _, part := range strings.Split(constraint, ",") {
+		m := versionCmpRe.FindStringSubmatch(strings.TrimSpace(part))
+		if m == nil {
+			return false, fmt.Errorf("invalid os_version %q", strings.TrimSpace(part))
+		}
+		if !ok {
+			continue
+		}
+		op, want, have := m[1], m[2], version
+		if op == "" {
+			op, have = "=", prefix(version, want)
+		}
+		ok = compareOp(op, compareVersions(have, want))
+	}
+	return ok, nil
+}
+
+// prefix trims version to as many dot-separated components as want has.
+func prefix(version, want string) string {
+	parts := strings.Split(version, ".")
+	if n := strings.Count(want, ".") + 1; len(parts) > n {
+		parts = parts[:n]
+	}
+	return strings.Join(parts, ".")
+}
+
+func compareOp(op string, c int) bool {
+	switch op {
+	case ">=":
+		return c >= 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case "<":
+		return c < 0
+	case "!=":
+		return c != 0
+	}
+	return c == 0
+}
+
+// compareVersions compares dotted numeric versions; missing components
+// count as 0 and non-numeric ones as 0.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
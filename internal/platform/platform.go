@@ -0,0 +1,58 @@
+// Package platform names the platform day1 runs on. It is runtime.GOOS,
+// except under the Windows Subsystem for Linux where it is the virtual
+// platform "wsl": a Linux userland whose links open on the Windows host,
+// so both linux and windows content applies.
+package platform
+
+import (
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// WSL is the platform name used under the Windows Subsystem for Linux.
+const WSL = "wsl"
+
+// procVersion is the kernel version file read to detect WSL.
+var procVersion = "/proc/version"
+
+var (
+	once    sync.Once
+	current string
+)
+
+// Current returns the platform day1 is running on: runtime.GOOS, or WSL.
+func Current() string {
+	once.Do(func() { current = detect(runtime.GOOS, procVersion) })
+	return current
+}
+
+// IsWSL reports whether day1 runs under WSL.
+func IsWSL() bool { return Current() == WSL }
+
+// detect returns WSL when goos is linux and the kernel version at path
+// names Microsoft, as WSL kernels do.
+func detect(goos, path string) string {
+	if goos != "linux" {
+		return goos
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(strings.ToLower(string(data)), "microsoft") {
+		return goos
+	}
+	return WSL
+}
+
+// Matches reports whether content for target is shown on platform p.
+// An empty target or "all" matches everything, and WSL matches linux and
+// windows content as well as its own.
+func Matches(target, p string) bool {
+	switch {
+	case target == "" || target == "all" || target == p:
+		return true
+	case p == WSL:
+		return target == "linux" || target == "windows"
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package platform
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	wsl2 := write("wsl2", "Linux version 5.15.153.1-microsoft-standard-WSL2 (root@1c602f52c2e4) (gcc (GCC) 11.2.0) #1 SMP")
+	wsl1 := write("wsl1", "Linux version 4.4.0-19041-Microsoft (Microsoft@Microsoft.com) (gcc version 5.4.0 (GCC) ) #1237-Microsoft")
+	native := write("native", "Linux version 6.8.0-45-generic (buildd@lcy02-amd64-075) (x86_64-linux-gnu-gcc-13) #45-Ubuntu SMP")
+
+	tests := []struct {
+		name, goos, path, want string
+	}{
+		{"wsl2", "linux", wsl2, WSL},
+		{"wsl1", "linux", wsl1, WSL},
+		{"native linux", "linux", native, "linux"},
+		{"no proc", "linux", filepath.Join(dir, "missing"), "linux"},
+		{"windows ignores proc", "windows", wsl2, "windows"},
+		{"darwin", "darwin", native, "darwin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := detect(tt.goos, tt.path); got != tt.want {
+				t.Errorf("detect(%q, %s) = %q, want %q", tt.goos, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		target, platform string
+		want             bool
+	}{
+		{"all", "darwin", true},
+		{"", "wsl", true},
+		{"linux", "linux", true},
+		{"windows", "linux", false},
+		{"linux", "wsl", true},
+		{"windows", "wsl", true},
+		{"darwin", "wsl", false},
+		{"wsl", "wsl", true},
+		{"wsl", "linux", false},
+		{"wsl", "windows", false},
+	}
+	for _, tt := range tests {
+		if got := Matches(tt.target, tt.platform); got != tt.want {
+			t.Errorf("Matches(%q, %q) = %v, want %v", tt.target, tt.platform, got, tt.want)
+		}
+	}
+}
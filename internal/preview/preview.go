@@ -0,0 +1,302 @@
+// Package preview serves the wizard frontend over plain HTTP so content
+// authors can iterate on pages in a browser without a webview. A small JS
+// shim stands in for the Wails bindings and the page reloads whenever a
+// page or day1.yml changes on disk.
+package preview
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TsekNet/day1/internal/app"
+	"github.com/TsekNet/day1/internal/pages"
+	"github.com/TsekNet/day1/internal/urischeme"
+	"github.com/google/deck"
+)
+
+//go:embed shim.js
+var shimJS []byte
+
+const (
+	shimPath   = "/__preview/shim.js"
+	callPrefix = "/__preview/call/"
+	eventsPath = "/__preview/events"
+)
+
+// LoadFunc builds an App for the given platform from the pages directory.
+type LoadFunc func(platform string) (*app.App, error)
+
+// Server serves the frontend, the pages directory and the binding shim.
+type Server struct {
+	assets   fs.FS
+	pagesDir string
+	platform string
+	load     LoadFunc
+
+	mu   sync.Mutex
+	apps map[string]*app.App
+	subs map[chan struct{}]bool
+}
+
+// New returns a Server. assets must contain index.html at its root and
+// platform is the simulated platform a browser starts with.
+func New(assets fs.FS, pagesDir, platform string, load LoadFunc) *Server {
+	return &Server{
+		assets:   assets,
+		pagesDir: pagesDir,
+		platform: platform,
+		load:     load,
+		apps:     map[string]*app.App{},
+		subs:     map[chan struct{}]bool{},
+	}
+}
+
+// Handler returns the HTTP handler for the preview site.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.serveIndex)
+	mux.Handle("/pages/", http.StripPrefix("/pages/", http.FileServerFS(os.DirFS(s.pagesDir))))
+	mux.HandleFunc(shimPath, func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+		w.Write(shimJS)
+	})
+	mux.HandleFunc(callPrefix, s.serveCall)
+	mux.HandleFunc(eventsPath, s.serveEvents)
+	return mux
+}
+
+// serveIndex injects the shim ahead of main.js; other paths are served
+// straight from the frontend assets.
+func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" && r.URL.Path != "/index.html" {
+		http.FileServer(http.FS(s.assets)).ServeHTTP(w, r)
+		return
+	}
+	data, err := fs.ReadFile(s.assets, "index.html")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	tag := fmt.Sprintf(`<script src="%s" data-platform="%s"></script>`, shimPath, s.platform)
+	data = bytes.Replace(data, []byte(`<script src="main.js">`), []byte(tag+`<script src="main.js">`), 1)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(data)
+}
+
+// serveCall dispatches a binding call. Only read-only bindings are exposed;
+// state-changing ones are simulated by the shim so previews never touch the
+// author's real checklist or sentinel.
+func (s *Server) serveCall(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	platform := r.URL.Query().Get("platform")
+	if !pages.IsPlatform(platform) {
+		http.Error(w, fmt.Sprintf("unknown platform %q", platform), http.StatusBadRequest)
+		return
+	}
+	var args []json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&args); err != nil {
+		http.Error(w, "bad arguments: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a, err := s.app(platform)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	method := strings.TrimPrefix(r.URL.Path, callPrefix)
+	var result any
+	switch method {
+	case "GetPages":
+		result = a.GetPages()
+	case "GetPageHTML":
+		var index int
+		if len(args) != 1 || json.Unmarshal(args[0], &index) != nil {
+			http.Error(w, "GetPageHTML expects one integer", http.StatusBadRequest)
+			return
+		}
+		result = a.GetPageHTML(index)
+	case "GetFinalHTML":
+		result = a.GetFinalHTML()
+	case "GetHelpURL":
+		result = a.GetHelpURL()
+	case "GetAccentColor":
+		result = a.GetAccentColor()
+	case "GetBrand":
+		result = a.GetBrand()
+	case "GetTheme":
+		result = a.GetTheme()
+	case "GetLocale":
+		result = a.GetLocale()
+	case "GetDir":
+		result = a.GetDir()
+	case "GetMandatory":
+		result = a.GetMandatory()
+	case "CanSnooze":
+		result = a.CanSnooze()
+	case "GetStrings":
+		result = a.GetStrings()
+	case "GetWhatsNew":
+		result = a.GetWhatsNew()
+	case "CheckURL":
+		var u string
+		if len(args) != 1 || json.Unmarshal(args[0], &u) != nil {
+			http.Error(w, "CheckURL expects one string", http.StatusBadRequest)
+			return
+		}
+		result = s.links().AllowedOn(u, platform)
+	default:
+		http.Error(w, fmt.Sprintf("unknown binding %q", method), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(result)
+}
+
+// links returns the URI policy of the current day1.yml, or the built-in
+// one when the file is missing or its links section is invalid.
+func (s *Server) links() *urischeme.Policy {
+	cfg, err := pages.LoadConfig(os.DirFS(s.pagesDir))
+	if err != nil {
+		return urischeme.Default()
+	}
+	policy, err := cfg.LinkPolicy()
+	if err != nil {
+		deck.Warningf("preview: %v", err)
+		return urischeme.Default()
+	}
+	return policy
+}
+
+// serveEvents streams a "reload" server-sent event whenever content changes.
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	flusher.Flush()
+
+	ch := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.subs[ch] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subs, ch)
+		s.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ch:
+			fmt.Fprint(w, "event: reload\ndata: {}\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// app returns the cached App for platform, loading it on first use.
+func (s *Server) app(platform string) (*app.App, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a, ok := s.apps[platform]; ok {
+		return a, nil
+	}
+	a, err := s.load(platform)
+	if err != nil {
+		return nil, err
+	}
+	s.apps[platform] = a
+	return a, nil
+}
+
+// Reload drops cached apps and tells every connected browser to reload.
+func (s *Server) Reload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.apps = map[string]*app.App{}
+	for ch := range s.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Watch polls the pages directory every interval and calls Reload when a
+// .md file or day1.yml is added, removed or modified. Blocks until stop is
+// closed.
+func (s *Server) Watch(interval time.Duration, stop <-chan struct{}) {
+	last := snapshot(s.pagesDir)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+			cur := snapshot(s.pagesDir)
+			if !sameSnapshot(last, cur) {
+				deck.Info("pages changed, reloading preview")
+				last = cur
+				s.Reload()
+			}
+		}
+	}
+}
+
+// snapshot maps every watched file under dir to its size and mod time.
+func snapshot(dir string) map[string]string {
+	out := map[string]string{}
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !watched(d.Name()) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		out[path] = fmt.Sprintf("%d/%d", info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return out
+}
+
+// watched reports whether name is a page, day1.yml or a day1.<locale>.yml.
+func watched(name string) bool {
+	return strings.HasSuffix(name, ".md") || strings.HasPrefix(name, "day1.") && strings.HasSuffix(name, ".yml")
+}
+
+func sameSnapshot(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if b[k] != v {
+			return false
+		}
+	}
+	return true
+}
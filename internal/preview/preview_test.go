@@ -0,0 +1,150 @@
+package preview
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/TsekNet/day1/internal/app"
+	"github.com/TsekNet/day1/internal/pages"
+)
+
+func testServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	dir := t.TempDir()
+	assets := fstest.MapFS{
+		"index.html": {Data: []byte(`<html><body><script src="main.js"></script></body></html>`)},
+		"style.css":  {Data: []byte("body{}")},
+	}
+	load := func(platform string) (*app.App, error) {
+		loaded, err := pages.LoadForPlatform(os.DirFS(dir), platform)
+		if err != nil {
+			return nil, err
+		}
+		return app.New(loaded, app.Config{Theme: "light", SnoozeFor: time.Hour}), nil
+	}
+	return New(assets, dir, "linux", load), dir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIndexInjectsShim(t *testing.T) {
+	s, _ := testServer(t)
+	rec := httptest.NewRecorder()
+	s.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	body := rec.Body.String()
+	want := `<script src="/__preview/shim.js" data-platform="linux"></script><script src="main.js">`
+	if !strings.Contains(body, want) {
+		t.Errorf("index missing shim tag\ngot: %s", body)
+	}
+}
+
+func TestServeCall(t *testing.T) {
+	s, dir := testServer(t)
+	writeFile(t, filepath.Join(dir, "a.md"), "---\ntitle: All\n---\n# All")
+	writeFile(t, filepath.Join(dir, "b.md"), "---\ntitle: Mac\nplatform: darwin\n---\n# Mac")
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		wantCode int
+		want     string
+	}{
+		{"pages on linux", "/__preview/call/GetPages?platform=linux", "[]", 200, `[{"id":"a","title":"All","index":0,"dir":"ltr"}]`},
+		{"pages on darwin", "/__preview/call/GetPages?platform=darwin", "[]", 200, `"title":"Mac"`},
+		{"page html", "/__preview/call/GetPageHTML?platform=linux", "[0]", 200, `<h1>All`},
+		{"theme", "/__preview/call/GetTheme?platform=linux", "[]", 200, `"light"`},
+		{"strings", "/__preview/call/GetStrings?platform=linux", "[]", 200, `"next":"Next"`},
+		{"dir", "/__preview/call/GetDir?platform=linux", "[]", 200, `"ltr"`},
+		{"mandatory", "/__preview/call/GetMandatory?platform=linux", "[]", 200, "false"},
+		{"can snooze", "/__preview/call/CanSnooze?platform=linux", "[]", 200, "true"},
+		{"snooze rejected", "/__preview/call/Snooze?platform=linux", "[]", 404, ""},
+		{"check url allowed", "/__preview/call/CheckURL?platform=windows", `["ms-settings:display"]`, 200, "true"},
+		{"check url blocked", "/__preview/call/CheckURL?platform=linux", `["ms-settings:display"]`, 200, "false"},
+		{"state changing binding rejected", "/__preview/call/Complete?platform=linux", "[]", 404, ""},
+		{"unknown platform", "/__preview/call/GetPages?platform=beos", "[]", 400, ""},
+		{"bad arguments", "/__preview/call/GetPageHTML?platform=linux", `["x"]`, 400, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			s.Handler().ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (%s)", rec.Code, tt.wantCode, rec.Body.String())
+			}
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("body = %s, want it to contain %s", rec.Body.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWatchReloads(t *testing.T) {
+	s, dir := testServer(t)
+	writeFile(t, filepath.Join(dir, "a.md"), "# A")
+
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.Watch(10*time.Millisecond, stop)
+
+	resp, err := http.Get(ts.URL + eventsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// Wait for the subscription to register before changing content.
+	for i := 0; i < 100; i++ {
+		s.mu.Lock()
+		n := len(s.subs)
+		s.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	writeFile(t, filepath.Join(dir, "day1.yml"), "title: Changed\n")
+
+	buf := make([]byte, 64)
+	n, err := io.ReadAtLeast(resp.Body, buf, len("event: reload"))
+	if err != nil {
+		t.Fatalf("read event: %v", err)
+	}
+	if !strings.Contains(string(buf[:n]), "event: reload") {
+		t.Errorf("got %q, want reload event", buf[:n])
+	}
+}
+
+func TestSnapshotIgnoresOtherFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.md"), "# A")
+	before := snapshot(dir)
+
+	writeFile(t, filepath.Join(dir, "notes.txt"), "scratch")
+	if !sameSnapshot(before, snapshot(dir)) {
+		t.Error("non-page file should not change the snapshot")
+	}
+
+	writeFile(t, filepath.Join(dir, "b.md"), "# B")
+	if sameSnapshot(before, snapshot(dir)) {
+		t.Error("new page should change the snapshot")
+	}
+}
@@ -0,0 +1,170 @@
+// Stand-in for the Wails bindings when the wizard runs in a plain browser.
+// Read-only calls go to the preview server; checklist state, progress,
+// completion and dismissal are simulated here so previews never touch real
+// user state.
+(function() {
+  "use strict";
+
+  var PLATFORMS = ["windows", "darwin", "linux", "wsl"];
+  var params = new URLSearchParams(window.location.search);
+  var platform = params.get("platform") || document.currentScript.getAttribute("data-platform");
+  var checkState = {};
+
+  function call(method) {
+    var args = Array.prototype.slice.call(arguments, 1);
+    return fetch("/__preview/call/" + method + "?platform=" + encodeURIComponent(platform), {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify(args)
+    }).then(function(res) {
+      if (!res.ok) {
+        return res.text().then(function(text) { throw new Error(method + ": " + text); });
+      }
+      return res.json();
+    });
+  }
+
+  function notice(text) {
+    var el = document.createElement("div");
+    el.className = "preview-notice";
+    el.textContent = text;
+    document.body.appendChild(el);
+    setTimeout(function() { el.remove(); }, 2500);
+  }
+
+  // Minimal Wails event runtime so day1: links can move the wizard.
+  var listeners = {};
+  window.runtime = {
+    EventsOn: function(name, fn) { (listeners[name] = listeners[name] || []).push(fn); }
+  };
+  function emit(name, data) {
+    (listeners[name] || []).forEach(function(fn) { fn(data); });
+  }
+
+  // openInternal simulates day1:page and day1:final links. Checklist and
+  // action links only run in the wizard.
+  function openInternal(url) {
+    var target = url.slice("day1:".length).split("#")[0];
+    if (target === "final") {
+      emit("navigate", -1);
+      return Promise.resolve();
+    }
+    if (target.indexOf("page/") !== 0) {
+      notice("Runs in the wizard: " + url);
+      return Promise.resolve();
+    }
+    var id = decodeURIComponent(target.slice("page/".length));
+    return call("GetPages").then(function(pages) {
+      for (var i = 0; i < pages.length; i++) {
+        if (pages[i].id === id) {
+          emit("navigate", i);
+          return;
+        }
+      }
+      notice("No page " + id + " on " + platform);
+    });
+  }
+
+  function openURL(url) {
+    if (url.toLowerCase().indexOf("day1:") === 0) {
+      return openInternal(url);
+    }
+    return call("CheckURL", url).then(function(ok) {
+      if (ok) {
+        window.open(url, "_blank", "noopener");
+      } else {
+        notice("Blocked on " + platform + ": " + url);
+      }
+    });
+  }
+
+  window.go = {
+    app: {
+      App: {
+        GetPages: function() { return call("GetPages"); },
+        GetPageHTML: function(i) { return call("GetPageHTML", i); },
+        GetFinalHTML: function() { return call("GetFinalHTML"); },
+        GetHelpURL: function() { return call("GetHelpURL"); },
+        GetAccentColor: function() { return call("GetAccentColor"); },
+        GetBrand: function() { return call("GetBrand"); },
+        GetTheme: function() { return call("GetTheme"); },
+        GetLocale: function() { return call("GetLocale"); },
+        GetDir: function() { return call("GetDir"); },
+        GetMandatory: function() { return call("GetMandatory"); },
+        CanSnooze: function() { return call("CanSnooze"); },
+        GetStrings: function() { return call("GetStrings"); },
+        GetWhatsNew: function() { return call("GetWhatsNew"); },
+        GetCheckState: function() { return Promise.resolve(Object.assign({}, checkState)); },
+        ToggleCheckItem: function(key) {
+          checkState[key] = !checkState[key];
+          return Promise.resolve(checkState[key]);
+        },
+        GetProgress: function() { return Promise.resolve({ index: 0, page_id: "", visited: [] }); },
+        SetCurrentPage: function() { return Promise.resolve(); },
+        Ready: function() { return Promise.resolve(); },
+        Complete: function() {
+          notice("Completed (preview: no sentinel written)");
+          return Promise.resolve();
+        },
+        Dismiss: function() {
+          notice("Dismissed (preview)");
+          return Promise.resolve();
+        },
+        Snooze: function() {
+          notice("Snoozed (preview: nothing recorded)");
+          return Promise.resolve();
+        },
+        OpenHelp: function() {
+          return call("GetHelpURL").then(function(url) { if (url) return openURL(url); });
+        },
+        OpenURL: openURL
+      }
+    }
+  };
+
+  function addToolbar() {
+    var style = document.createElement("style");
+    style.textContent =
+      "html, body { overflow: auto; background: #334155; }" +
+      ".wizard { width: 900px; height: 600px; margin: 56px auto 24px; background: var(--bg);" +
+      "  border-radius: 8px; overflow: hidden; box-shadow: 0 10px 40px rgba(0,0,0,.4); }" +
+      ".preview-bar { position: fixed; top: 12px; left: 50%; transform: translateX(-50%);" +
+      "  display: flex; gap: 8px; align-items: center; font-size: 13px; color: #e2e8f0; }" +
+      ".preview-notice { position: fixed; bottom: 16px; left: 50%; transform: translateX(-50%);" +
+      "  padding: 8px 14px; border-radius: 6px; background: #0f172a; color: #e2e8f0; font-size: 13px; }";
+    document.head.appendChild(style);
+
+    var bar = document.createElement("div");
+    bar.className = "preview-bar";
+    var label = document.createElement("span");
+    label.textContent = "day1 preview — platform:";
+    bar.appendChild(label);
+
+    var select = document.createElement("select");
+    PLATFORMS.forEach(function(p) {
+      var opt = document.createElement("option");
+      opt.value = p;
+      opt.textContent = p;
+      opt.selected = p === platform;
+      select.appendChild(opt);
+    });
+    select.addEventListener("change", function() {
+      params.set("platform", select.value);
+      window.location.search = params.toString();
+    });
+    bar.appendChild(select);
+    document.body.appendChild(bar);
+  }
+
+  if (document.readyState === "loading") {
+    document.addEventListener("DOMContentLoaded", addToolbar);
+  } else {
+    addToolbar();
+  }
+
+  if (window.EventSource) {
+    new EventSource("/__preview/events").addEventListener("reload", function() {
+      window.location.reload();
+    });
+  }
+})();
@@ -0,0 +1,170 @@
+package translate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PO files carry the unit ID as msgctxt, since the same text can appear
+// in several places, and the file it's from as a reference comment.
+// Fuzzy units have the fuzzy flag; untranslated ones an empty msgstr.
+
+// WritePO writes c as a gettext PO file.
+func WritePO(w io.Writer, c Catalog) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "msgid \"\"\nmsgstr \"\"\n")
+	for _, h := range []string{
+		"Language: " + c.Locale,
+		"X-Source-Language: " + c.SourceLocale,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"Content-Transfer-Encoding: 8bit",
+	} {
+		fmt.Fprintf(bw, "%s\n", poQuote(h+"\n"))
+	}
+	for _, u := range c.Units {
+		fmt.Fprintf(bw, "\n#: %s\n", u.File())
+		if u.Fuzzy && u.Target != "" {
+			fmt.Fprintf(bw, "#, fuzzy\n")
+		}
+		fmt.Fprintf(bw, "msgctxt %s\n", poQuote(u.ID))
+		fmt.Fprintf(bw, "msgid %s\n", poString(u.Source))
+		fmt.Fprintf(bw, "msgstr %s\n", poString(u.Target))
+	}
+	return bw.Flush()
+}
+
+// poString quotes s, one line of the file per line of s.
+func poString(s string) string {
+	if !strings.Contains(strings.TrimSuffix(s, "\n"), "\n") {
+		return poQuote(s)
+	}
+	var b strings.Builder
+	b.WriteString(`""`)
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line != "" {
+			b.WriteString("\n" + poQuote(line))
+		}
+	}
+	return b.String()
+}
+
+func poQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func poUnquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("want a quoted string, got %s", s)
+	}
+	s = s[1 : len(s)-1]
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// ReadPO reads a gettext PO file written by WritePO and translated.
+// Obsolete entries, entries without msgctxt and plural forms are skipped.
+func ReadPO(r io.Reader) (Catalog, error) {
+	var (
+		c     Catalog
+		u     Unit
+		field *string
+		ctx   bool // the entry has a msgctxt
+		skip  bool // the entry has plural forms
+		done  bool // the entry's msgstr was read
+	)
+	flush := func() {
+		switch {
+		case skip:
+		case ctx:
+			c.Units = append(c.Units, u)
+		case u.Source == "":
+			c.Locale = poHeader(u.Target, "Language")
+			c.SourceLocale = poHeader(u.Target, "X-Source-Language")
+		}
+		u, field, ctx, skip, done = Unit{}, nil, false, false, false
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#~") {
+			continue
+		}
+		keyword, rest, _ := strings.Cut(line, " ")
+		if done && (line[0] == '#' || keyword == "msgctxt" || keyword == "msgid") {
+			flush()
+		}
+		switch {
+		case strings.HasPrefix(line, "#,"):
+			u.Fuzzy = u.Fuzzy || strings.Contains(line, "fuzzy")
+		case line[0] == '#':
+		case line[0] == '"':
+			if field == nil {
+				return c, fmt.Errorf("line %d: string outside an entry", n)
+			}
+			s, err := poUnquote(line)
+			if err != nil {
+				return c, fmt.Errorf("line %d: %w", n, err)
+			}
+			*field += s
+		default:
+			s, err := poUnquote(strings.TrimSpace(rest))
+			if err != nil {
+				return c, fmt.Errorf("line %d: %w", n, err)
+			}
+			switch {
+			case keyword == "msgctxt":
+				field, ctx = &u.ID, true
+			case keyword == "msgid":
+				field = &u.Source
+			case keyword == "msgstr", keyword == "msgstr[0]":
+				field, done = &u.Target, true
+			case keyword == "msgid_plural":
+				field, skip = new(string), true
+			case strings.HasPrefix(keyword, "msgstr["):
+				field = new(string)
+			default:
+				return c, fmt.Errorf("line %d: unknown keyword %s", n, keyword)
+			}
+			*field = s
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return c, err
+	}
+	if done {
+		flush()
+	}
+	return c, nil
+}
+
+// poHeader returns the value of key in a PO header entry.
+func poHeader(header, key string) string {
+	for _, line := range strings.Split(header, "\n") {
+		if k, v, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(k) == key {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,341 @@
+package translate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A page is split into units at the blocks a translator works on:
+// headings, paragraphs, list and checklist items, and table cells. The
+// markdown around them (list markers, "#", checklist boxes and {#id
+// required} anchors, table pipes, quote markers, code blocks, ::: blocks and
+// lines holding only template actions) is structure and is copied from
+// the original when a translation is rebuilt. Inline markdown such as
+// links, emphasis and {{ .User }} stays in the unit's text.
+//
+// Unit IDs are the file and an anchor: "welcome.md#title" for the page
+// title, the heading's slug for a heading, the {#id} of a checklist item,
+// and the heading's slug and a hash of the text for everything else, as
+// in "welcome.md#getting-started.5f1d9a3c". Adding, moving or editing a
+// unit leaves the IDs of the others alone.
+
+var (
+	fenceRe    = regexp.MustCompile("^[ \t]*(```+|~~~+)")
+	quoteRe    = regexp.MustCompile(`^[ \t]*(>[ \t]?)+`)
+	headingRe  = regexp.MustCompile(`^([ \t]*#{1,6}[ \t]+)(.*?)([ \t]*\{#[\w-]+\})?[ \t]*$`)
+	itemRe     = regexp.MustCompile(`^([ \t]*(?:[-*+]|\d{1,9}[.)])[ \t]+(?:\[[ xX]\][ \t]+)?)(.*)$`)
+	anchorRe   = regexp.MustCompile(`[ \t]*\{(?:#([\w-]+)(?:[ \t]+required)?|required)\}$`)
+	tableRe    = regexp.MustCompile(`^[ \t]*\|`)
+	delimRe    = regexp.MustCompile(`^[ \t]*\|?[ \t]*:?-+:?[ \t]*(\|[ \t]*:?-+:?[ \t]*)*\|?[ \t]*$`)
+	setextRe   = regexp.MustCompile(`^(=+|-+)$`)
+	refDefRe   = regexp.MustCompile(`^[ \t]*\[[^\]]+\]:[ \t]`)
+	actionRe   = regexp.MustCompile(`\{\{.*?\}\}`)
+	tagRe      = regexp.MustCompile(`<[^>]*>`)
+	slugDropRe = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+)
+
+// piece is a run of a file: structure copied as is, or the text of a
+// unit. cont is written before every line of a unit after the first.
+type piece struct {
+	text string
+	id   string
+	cont string
+}
+
+// doc is a markdown file split into units.
+type doc struct {
+	header string
+	// title is the page title, or "" for files that aren't pages.
+	title  string
+	pieces []piece
+}
+
+// units returns the IDs and texts of d's units in order, the title
+// first.
+func (d doc) units(file string) (ids, texts []string) {
+	if d.title != "" {
+		ids, texts = append(ids, titleID(file)), append(texts, d.title)
+	}
+	for _, p := range d.pieces {
+		if p.id != "" {
+			ids, texts = append(ids, p.id), append(texts, p.text)
+		}
+	}
+	return ids, texts
+}
+
+func titleID(file string) string { return file + "#title" }
+
+// hash identifies the source text a translation was made from.
+func hash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:6])
+}
+
+// segmenter splits the body of one file.
+type segmenter struct {
+	file    string
+	section string
+	used    map[string]bool
+	pieces  []piece
+}
+
+// segment splits raw, a page of file with the given title, or an included
+// file when title is "". body is raw without its frontmatter.
+func segment(file, raw, body, title string) doc {
+	s := &segmenter{file: file, used: map[string]bool{titleID(file): title != ""}}
+	lines := strings.SplitAfter(body, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var fence string
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		bare := strings.TrimRight(line, "\n")
+		if fence != "" {
+			if strings.HasPrefix(strings.TrimSpace(bare), fence) {
+				fence = ""
+			}
+			s.literal(line)
+			continue
+		}
+		if m := fenceRe.FindStringSubmatch(bare); m != nil {
+			fence = m[1]
+			s.literal(line)
+			continue
+		}
+		qp := quoteRe.FindString(bare)
+		rest := bare[len(qp):]
+		nl := line[len(bare):]
+		switch {
+		case strings.TrimSpace(rest) == "",
+			strings.HasPrefix(strings.TrimSpace(rest), ":::"),
+			refDefRe.MatchString(rest):
+			s.literal(line)
+		case strings.HasPrefix(strings.TrimSpace(rest), "<!--"):
+			for ; i < len(lines) && !strings.Contains(lines[i], "-->"); i++ {
+				s.literal(lines[i])
+			}
+			if i < len(lines) {
+				s.literal(lines[i])
+			}
+		case tableRe.MatchString(rest):
+			s.tableRow(qp, rest, nl)
+		case headingRe.MatchString(rest):
+			m := headingRe.FindStringSubmatch(rest)
+			id := s.file + "#" + slug(m[2])
+			s.unit(qp+m[1], m[2], m[3]+nl, id, "")
+			s.section = slug(m[2])
+		default:
+			lead := rest[:len(rest)-len(strings.TrimLeft(rest, " \t"))]
+			prefix, text := qp+lead, rest[len(lead):]
+			if m := itemRe.FindStringSubmatch(rest); m != nil {
+				prefix, text = qp+m[1], m[2]
+			}
+			var cont string
+			for i+1 < len(lines) && continues(lines[i+1], qp) {
+				i++
+				next := strings.TrimRight(lines[i], "\n")
+				if cont == "" {
+					cont = next[:len(next)-len(strings.TrimLeft(next[len(qp):], " \t"))]
+				}
+				text += "\n" + strings.TrimSpace(next[len(qp):])
+				nl = lines[i][len(next):]
+			}
+			var suffix, id string
+			if m := anchorRe.FindStringSubmatchIndex(text); m != nil && itemRe.MatchString(rest) {
+				suffix = text[m[0]:]
+				if m[2] >= 0 {
+					id = s.file + "#" + text[m[2]:m[3]]
+				}
+				text = text[:m[0]]
+			}
+			s.unit(prefix, text, suffix+nl, id, cont)
+		}
+	}
+	return doc{header: raw[:len(raw)-len(body)], title: title, pieces: s.pieces}
+}
+
+// continues reports whether line continues the paragraph or list item
+// before it, inside the quote prefix qp.
+func continues(line, qp string) bool {
+	bare := strings.TrimRight(line, "\n")
+	if quoteRe.FindString(bare) != qp {
+		return false
+	}
+	rest := strings.TrimSpace(bare[len(qp):])
+	return rest != "" && !setextRe.MatchString(rest) && !strings.HasPrefix(rest, ":::") && !strings.HasPrefix(rest, "<!--") &&
+		!fenceRe.MatchString(rest) && !tableRe.MatchString(rest) &&
+		!headingRe.MatchString(rest) && !itemRe.MatchString(rest) && !refDefRe.MatchString(rest)
+}
+
+// tableRow splits a table row into cells; the delimiter row is structure.
+func (s *segmenter) tableRow(qp, row, nl string) {
+	if delimRe.MatchString(row) {
+		s.literal(qp + row + nl)
+		return
+	}
+	s.literal(qp)
+	start := 0
+	for i := 0; i <= len(row); i++ {
+		if i < len(row) && (row[i] != '|' || (i > 0 && row[i-1] == '\\')) {
+			continue
+		}
+		cell := row[start:i]
+		lead := cell[:len(cell)-len(strings.TrimLeft(cell, " \t"))]
+		text := strings.TrimSpace(cell)
+		s.unit(lead, text, cell[len(lead)+len(text):], "", "")
+		if i < len(row) {
+			s.literal("|")
+		}
+		start = i + 1
+	}
+	s.literal(nl)
+}
+
+func (s *segmenter) literal(text string) {
+	if n := len(s.pieces); n > 0 && s.pieces[n-1].id == "" {
+		s.pieces[n-1].text += text
+		return
+	}
+	s.pieces = append(s.pieces, piece{text: text})
+}
+
+// unit adds text as a unit with the given ID, or an ID from the section
+// and text when id is "". Text without words is structure.
+func (s *segmenter) unit(prefix, text, suffix, id, cont string) {
+	s.literal(prefix)
+	if !translatable(text) {
+		s.literal(strings.ReplaceAll(text, "\n", "\n"+cont) + suffix)
+		return
+	}
+	if id == "" {
+		id = s.file + "#" + hash(text)[:8]
+		if s.section != "" {
+			id = s.file + "#" + s.section + "." + hash(text)[:8]
+		}
+	}
+	for n := 2; s.used[id]; n++ {
+		id = strings.TrimSuffix(id, "~"+strconv.Itoa(n-1)) + "~" + strconv.Itoa(n)
+	}
+	s.used[id] = true
+	s.pieces = append(s.pieces, piece{text: text, id: id, cont: cont})
+	s.literal(suffix)
+}
+
+// translatable reports whether text has words outside template actions
+// and HTML tags.
+func translatable(text string) bool {
+	text = tagRe.ReplaceAllString(actionRe.ReplaceAllString(text, ""), "")
+	return strings.IndexFunc(text, unicode.IsLetter) >= 0
+}
+
+// slug turns heading text into an ID: "Getting *Started*" gives
+// "getting-started".
+func slug(text string) string {
+	text = tagRe.ReplaceAllString(actionRe.ReplaceAllString(text, ""), "")
+	s := strings.Trim(slugDropRe.ReplaceAllString(strings.ToLower(text), "-"), "-")
+	if s == "" {
+		return "section"
+	}
+	return s
+}
+
+// align matches ids, the units of a file, with prev, the units of the
+// version of it that was last imported: units with the same ID, then the
+// units between two matches that replaced as many others, which were
+// probably reworded. It returns the index in prev of each unit's match,
+// or -1.
+func align(prev, ids []string) []int {
+	n, m := len(prev), len(ids)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if prev[i] == ids[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	match := make([]int, m)
+	for j := range match {
+		match[j] = -1
+	}
+	var gi, gj int
+	gap := func(i, j int) {
+		if i-gi == j-gj {
+			for k := range j - gj {
+				match[gj+k] = gi + k
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case prev[i] == ids[j]:
+			gap(i, j)
+			match[j] = i
+			i, j = i+1, j+1
+			gi, gj = i, j
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	gap(n, m)
+	return match
+}
+
+// build writes d with the units of targets translated; units without a
+// target keep their source text. title is the translated title, or "".
+func (d doc) build(title string, targets map[string]string) string {
+	var b strings.Builder
+	b.WriteString(withTitle(d.header, title))
+	for _, p := range d.pieces {
+		text := p.text
+		if t, ok := targets[p.id]; ok && p.id != "" && t != "" {
+			text = t
+		}
+		if p.id != "" {
+			text = strings.ReplaceAll(text, "\n", "\n"+p.cont)
+		}
+		b.WriteString(text)
+	}
+	return b.String()
+}
+
+// withTitle sets the title of the frontmatter header, adding frontmatter
+// when there is none. The rest of the header is kept as written.
+func withTitle(header, title string) string {
+	if title == "" {
+		return header
+	}
+	v, _ := yaml.Marshal(title)
+	line := "title: " + string(v)
+	if header == "" {
+		return "---\n" + line + "---\n"
+	}
+	lines := strings.SplitAfter(header, "\n")
+	for i := 1; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "title:") {
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && (strings.HasPrefix(lines[end], " ") || strings.HasPrefix(lines[end], "\t")) {
+			end++
+		}
+		return strings.Join(lines[:i], "") + line + strings.Join(lines[end:], "")
+	}
+	return lines[0] + line + strings.Join(lines[1:], "")
+}
@@ -0,0 +1,497 @@
+// Package translate moves the text of a pages directory to and from the
+// files translation vendors work in. Extract splits the pages, the files
+// they include, the final page and the strings of day1.yml into units
+// with stable IDs; Import writes translated pages and day1.<locale>.yml
+// back from the translated units, keeping the markdown structure, links
+// and frontmatter of the originals.
+//
+// Import records the hash of every unit's source text in
+// day1.<locale>.sum, next to day1.yml. The next Extract compares it with
+// the current text and flags units whose source changed as fuzzy, with
+// the old translation as their target.
+package translate
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/TsekNet/day1/internal/i18n"
+	"github.com/TsekNet/day1/internal/pages"
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the file the units of day1.yml belong to.
+const configFile = "day1.yml"
+
+// Unit is a piece of text to translate.
+type Unit struct {
+	// ID is the file the text is from and an anchor in it, such as
+	// "welcome.md#title" or "day1.yml#strings.next".
+	ID     string
+	Source string
+	// Target is the translation, or "" when there is none.
+	Target string
+	// Fuzzy marks a target translated from an older source text, which
+	// needs review.
+	Fuzzy bool
+}
+
+// File returns the file u is from.
+func (u Unit) File() string {
+	file, _, _ := strings.Cut(u.ID, "#")
+	return file
+}
+
+// Catalog is the units of a pages directory for one target language.
+type Catalog struct {
+	SourceLocale string
+	Locale       string
+	Units        []Unit
+	// Unaligned lists the translated files whose units couldn't be
+	// matched with the original's, because the translation was edited
+	// into a different shape. Their units have no target.
+	Unaligned []string
+}
+
+// Counts returns how many units are translated, fuzzy and untranslated.
+func (c Catalog) Counts() (translated, fuzzy, untranslated int) {
+	for _, u := range c.Units {
+		switch {
+		case u.Target == "":
+			untranslated++
+		case u.Fuzzy:
+			fuzzy++
+		default:
+			translated++
+		}
+	}
+	return translated, fuzzy, untranslated
+}
+
+// CheckLocale returns an error unless locale is a language the content
+// in fsys can be translated into.
+func CheckLocale(fsys fs.FS, locale string) error {
+	cfg, err := pages.LoadConfig(fsys)
+	if err != nil {
+		return err
+	}
+	if locale == "" {
+		return errors.New("no target language (use --locale)")
+	}
+	if locale == cfg.DefaultLocale() {
+		return fmt.Errorf("the pages are written in %s; translate them into another language", locale)
+	}
+	return nil
+}
+
+// Extract returns the units of the content in fsys for locale, with the
+// translations it already has.
+func Extract(fsys fs.FS, locale string) (Catalog, error) {
+	if err := CheckLocale(fsys, locale); err != nil {
+		return Catalog{}, err
+	}
+	cfg, _ := pages.LoadConfig(fsys)
+	sums, err := readSums(fsys, locale)
+	if err != nil {
+		return Catalog{}, err
+	}
+	cat := Catalog{SourceLocale: cfg.DefaultLocale(), Locale: locale}
+
+	overlay, err := readOverlay(fsys, locale)
+	if err != nil {
+		return Catalog{}, err
+	}
+	hashes := entryHashes(sums[configFile])
+	for _, u := range configUnits(cfg, locale) {
+		target := overlay.Title
+		if u.ID != titleID(configFile) {
+			target = overlay.Strings[strings.TrimPrefix(u.ID, configFile+"#strings.")]
+		}
+		cat.Units = append(cat.Units, withTarget(u, target, hashes))
+	}
+
+	srcs, err := pages.Sources(fsys)
+	if err != nil {
+		return Catalog{}, err
+	}
+	for _, src := range srcs {
+		d, err := readDoc(fsys, src.File, src.Title)
+		if err != nil {
+			return Catalog{}, err
+		}
+		ids, texts := d.units(src.File)
+		prev, ok, err := existing(fsys, src, locale, ids, sums[src.File])
+		if err != nil {
+			return Catalog{}, err
+		}
+		if !ok {
+			cat.Unaligned = append(cat.Unaligned, pages.TranslationFile(fsys, src.File, locale))
+		}
+		prevIDs := make([]string, len(prev))
+		for i, e := range prev {
+			prevIDs[i] = e.id
+		}
+		for i, j := range align(prevIDs, ids) {
+			u := Unit{ID: ids[i], Source: texts[i]}
+			if j >= 0 && prev[j].hash != "-" && prev[j].text != "" {
+				u.Target = prev[j].text
+				u.Fuzzy = prev[j].id != u.ID || prev[j].hash != hash(u.Source)
+			}
+			cat.Units = append(cat.Units, u)
+		}
+	}
+	return cat, nil
+}
+
+// withTarget sets u's target and flags it fuzzy unless hashes records
+// that it was translated from u's source text.
+func withTarget(u Unit, target string, hashes map[string]string) Unit {
+	if target == "" || hashes[u.ID] == "-" {
+		return u
+	}
+	u.Target = target
+	u.Fuzzy = hashes[u.ID] != hash(u.Source)
+	return u
+}
+
+// configUnits returns the units of day1.yml: the title, and the strings
+// the language has no built-in translation of or that day1.yml changes.
+func configUnits(cfg pages.Config, locale string) []Unit {
+	var out []Unit
+	if cfg.Title != "" {
+		out = append(out, Unit{ID: titleID(configFile), Source: cfg.Title})
+	}
+	source := i18n.Strings(cfg.DefaultLocale(), cfg.Strings)
+	all := len(i18n.Missing(locale, nil)) > 0
+	for _, k := range i18n.Keys() {
+		if _, ok := cfg.Strings[k]; ok || all {
+			out = append(out, Unit{ID: configFile + "#strings." + k, Source: source[k]})
+		}
+	}
+	return out
+}
+
+// prevUnit is a unit of the last import of a file: its ID and source
+// hash from the sum file, and its text in the translation.
+type prevUnit struct{ id, hash, text string }
+
+// existing returns the units of src's current translation into locale.
+// Without a sum file, they are taken to be the units ids of an unchanged
+// original, translated from an unknown source. ok is false when the
+// translation exists but its units can't be matched.
+func existing(fsys fs.FS, src pages.Source, locale string, ids []string, sums []sumEntry) (prev []prevUnit, ok bool, err error) {
+	file := pages.TranslationFile(fsys, src.File, locale)
+	if file == "" {
+		return nil, true, nil
+	}
+	raw, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return nil, false, fmt.Errorf("read %s: %w", file, err)
+	}
+	fm, body, err := pages.ParseFrontmatter(string(raw), file)
+	if err != nil {
+		return nil, false, err
+	}
+	_, texts := segment(file, string(raw), body, "").units(file)
+	if src.Title != "" {
+		// A translation without a title shows the original's.
+		texts = append([]string{fm.Title}, texts...)
+	}
+	if len(sums) == 0 {
+		sums = make([]sumEntry, len(ids))
+		for i, id := range ids {
+			sums[i] = sumEntry{id: id, hash: "?"}
+		}
+	}
+	if len(texts) != len(sums) {
+		return nil, false, nil
+	}
+	for i, e := range sums {
+		prev = append(prev, prevUnit{id: e.id, hash: e.hash, text: texts[i]})
+	}
+	return prev, true, nil
+}
+
+func readDoc(fsys fs.FS, file, title string) (doc, error) {
+	raw, err := fs.ReadFile(fsys, file)
+	if err != nil {
+		return doc{}, fmt.Errorf("read %s: %w", file, err)
+	}
+	_, body, err := pages.ParseFrontmatter(string(raw), file)
+	if err != nil {
+		return doc{}, err
+	}
+	return segment(file, string(raw), body, title), nil
+}
+
+// overlay is the part of day1.<locale>.yml that Import writes.
+type overlay struct {
+	Title   string            `yaml:"title"`
+	Strings map[string]string `yaml:"strings"`
+}
+
+func overlayName(locale string) string { return "day1." + locale + ".yml" }
+
+func readOverlay(fsys fs.FS, locale string) (overlay, error) {
+	var o overlay
+	data, err := fs.ReadFile(fsys, overlayName(locale))
+	if errors.Is(err, fs.ErrNotExist) {
+		return o, nil
+	}
+	if err != nil {
+		return o, err
+	}
+	if err := yaml.Unmarshal(data, &o); err != nil {
+		return o, fmt.Errorf("parse %s: %w", overlayName(locale), err)
+	}
+	return o, nil
+}
+
+// Import writes the translations of cat into the pages directory dir: a
+// translated copy of every file with a translated unit, title and
+// strings in day1.<locale>.yml, and the source hashes in
+// day1.<locale>.sum. Units without a target keep the original's text.
+// It returns the files written.
+func Import(dir string, cat Catalog) ([]string, error) {
+	fsys := os.DirFS(dir)
+	if err := CheckLocale(fsys, cat.Locale); err != nil {
+		return nil, err
+	}
+	cfg, _ := pages.LoadConfig(fsys)
+	old, err := readSums(fsys, cat.Locale)
+	if err != nil {
+		return nil, err
+	}
+	units := map[string]Unit{}
+	for _, u := range cat.Units {
+		units[u.ID] = u
+	}
+	sums := maps.Clone(old)
+	var written []string
+
+	var cu []Unit
+	for _, u := range configUnits(cfg, cat.Locale) {
+		if t, ok := translated(units, u); ok {
+			cu = append(cu, t)
+		}
+	}
+	if len(cu) > 0 {
+		if err := writeOverlay(dir, cat.Locale, cu); err != nil {
+			return nil, err
+		}
+		written = append(written, overlayName(cat.Locale))
+		sums[configFile] = mergeSums(old[configFile], cu)
+	}
+
+	srcs, err := pages.Sources(fsys)
+	if err != nil {
+		return nil, err
+	}
+	for _, src := range srcs {
+		d, err := readDoc(fsys, src.File, src.Title)
+		if err != nil {
+			return nil, err
+		}
+		ids, texts := d.units(src.File)
+		all := make([]Unit, len(ids))
+		targets := map[string]string{}
+		for i, id := range ids {
+			all[i] = Unit{ID: id, Source: texts[i]}
+			if t, ok := translated(units, all[i]); ok {
+				all[i] = t
+				targets[id] = t.Target
+			}
+		}
+		if len(targets) == 0 {
+			continue
+		}
+		file := pages.TranslationFile(fsys, src.File, cat.Locale)
+		if file == "" {
+			file = strings.TrimSuffix(src.File, ".md") + "." + cat.Locale + ".md"
+		}
+		title := targets[titleID(src.File)]
+		if title == "" {
+			title = src.Title
+		}
+		if err := writeFile(dir, file, d.build(title, targets)); err != nil {
+			return nil, err
+		}
+		written = append(written, file)
+		sums[src.File] = mergeSums(old[src.File], all)
+	}
+	if err := writeSums(dir, cat.Locale, sums); err != nil {
+		return nil, err
+	}
+	return written, nil
+}
+
+// translated returns the unit of units that translates u, with the
+// source text it was translated from; an older text than u's makes it
+// fuzzy. ok is false when it has no target.
+func translated(units map[string]Unit, u Unit) (Unit, bool) {
+	t, ok := units[u.ID]
+	if !ok || t.Target == "" {
+		return u, false
+	}
+	if t.Source == "" {
+		t.Source = u.Source
+	}
+	t.Fuzzy = t.Fuzzy || t.Source != u.Source
+	return t, true
+}
+
+// writeOverlay sets the title and strings of units in
+// day1.<locale>.yml, keeping the rest of the file as written.
+func writeOverlay(dir, locale string, units []Unit) error {
+	name := overlayName(locale)
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("parse %s: %w", name, err)
+	}
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+	}
+	root := doc.Content[0]
+	for _, u := range units {
+		if u.ID == titleID(configFile) {
+			setKey(root, "title", u.Target)
+			continue
+		}
+		strs := mapping(root, "strings")
+		setKey(strs, strings.TrimPrefix(u.ID, configFile+"#strings."), u.Target)
+	}
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return writeFile(dir, name, b.String())
+}
+
+// mapping returns the mapping under key in m, adding it if needed.
+func mapping(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key && m.Content[i+1].Kind == yaml.MappingNode {
+			return m.Content[i+1]
+		}
+	}
+	v := &yaml.Node{Kind: yaml.MappingNode}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, v)
+	return v
+}
+
+func setKey(m *yaml.Node, key, value string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+			return
+		}
+	}
+	m.Content = append(m.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: value})
+}
+
+func writeFile(dir, name, content string) error {
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, []byte(content), 0o644)
+}
+
+// A sum file lists the units of every translated file in the order they
+// were written, with the hash of the source text each was translated
+// from: "-" for units left in the original language, "?" for fuzzy units
+// imported without a known source.
+//
+//	welcome.md#title 5f1d9a3c27e4
+//	welcome.md#1 -
+
+type sumEntry struct{ id, hash string }
+
+func sumName(locale string) string { return "day1." + locale + ".sum" }
+
+// readSums returns the entries of day1.<locale>.sum by file.
+func readSums(fsys fs.FS, locale string) (map[string][]sumEntry, error) {
+	out := map[string][]sumEntry{}
+	f, err := fsys.Open(sumName(locale))
+	if errors.Is(err, fs.ErrNotExist) {
+		return out, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		id, h, ok := strings.Cut(line, " ")
+		if !ok || !strings.Contains(id, "#") {
+			return nil, fmt.Errorf("%s:%d: want a unit ID and a hash", sumName(locale), n)
+		}
+		u := Unit{ID: id}
+		out[u.File()] = append(out[u.File()], sumEntry{id: id, hash: strings.TrimSpace(h)})
+	}
+	return out, sc.Err()
+}
+
+func entryHashes(entries []sumEntry) map[string]string {
+	out := make(map[string]string, len(entries))
+	for _, e := range entries {
+		out[e.id] = e.hash
+	}
+	return out
+}
+
+// mergeSums returns the entries of units, whose Source is the text they
+// were translated from. A fuzzy unit keeps its old hash, so it stays
+// fuzzy until a translation of the current text arrives.
+func mergeSums(old []sumEntry, units []Unit) []sumEntry {
+	prev := entryHashes(old)
+	out := make([]sumEntry, 0, len(units))
+	for _, u := range units {
+		h := hash(u.Source)
+		switch {
+		case u.Target == "":
+			h = "-"
+		case u.Fuzzy && prev[u.ID] != "" && prev[u.ID] != "-":
+			h = prev[u.ID]
+		case u.Fuzzy:
+			h = "?"
+		}
+		out = append(out, sumEntry{id: u.ID, hash: h})
+	}
+	return out
+}
+
+func writeSums(dir, locale string, sums map[string][]sumEntry) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Written by day1 i18n import: the source text each %s translation was made from.\n", locale)
+	// day1.yml first, then the files by path.
+	files := slices.Sorted(maps.Keys(sums))
+	if i := slices.Index(files, configFile); i > 0 {
+		files = append([]string{configFile}, slices.Delete(files, i, i+1)...)
+	}
+	for _, file := range files {
+		for _, e := range sums[file] {
+			fmt.Fprintf(&b, "%s %s\n", e.id, e.hash)
+		}
+	}
+	return writeFile(dir, sumName(locale), b.String())
+}
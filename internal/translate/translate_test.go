@@ -0,0 +1,282 @@
+package translate
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/pages"
+)
+
+// id returns the ID of a unit of p.md without a heading or anchor.
+func id(section, text string) string {
+	if section == "" {
+		return "p.md#" + hash(text)[:8]
+	}
+	return "p.md#" + section + "." + hash(text)[:8]
+}
+
+func TestSegment(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		body    string
+		wantIDs []string
+		want    string // body rebuilt with every unit upper-cased
+	}{
+		{
+			name:    "headings and paragraphs",
+			body:    "Intro line one\nline two.\n\n## Getting *Started*\n\nRead the [guide](https://x.example.com).\n",
+			wantIDs: []string{id("", "Intro line one\nline two."), "p.md#getting-started", id("getting-started", "Read the [guide](https://x.example.com).")},
+			want:    "INTRO LINE ONE\nLINE TWO.\n\n## GETTING *STARTED*\n\nREAD THE [GUIDE](HTTPS://X.EXAMPLE.COM).\n",
+		},
+		{
+			name:    "checklist and list items",
+			body:    "- [ ] Enroll your laptop {#enroll}\n- [x] Set a\n  passphrase {#pass}\n1. Plain item\n",
+			wantIDs: []string{"p.md#enroll", "p.md#pass", id("", "Plain item")},
+			want:    "- [ ] ENROLL YOUR LAPTOP {#enroll}\n- [x] SET A\n  PASSPHRASE {#pass}\n1. PLAIN ITEM\n",
+		},
+		{
+			name:    "required checklist items",
+			body:    "- [ ] Take the training {#training required}\n- [ ] Sign the policy {required}\n",
+			wantIDs: []string{"p.md#training", id("", "Sign the policy")},
+			want:    "- [ ] TAKE THE TRAINING {#training required}\n- [ ] SIGN THE POLICY {required}\n",
+		},
+		{
+			name:    "table cells",
+			body:    "| 📅 | Milestone |\n|----|-----------|\n| **Today** | Meet the team |\n",
+			wantIDs: []string{id("", "Milestone"), id("", "**Today**"), id("", "Meet the team")},
+			want:    "| 📅 | MILESTONE |\n|----|-----------|\n| **TODAY** | MEET THE TEAM |\n",
+		},
+		{
+			name:    "structure is kept",
+			body:    "```sh\necho hi\n```\n\n::: platform linux\n{{ include \"snippets/a.md\" }}\n:::\n\n> Quoted\n> text\n\n---\n",
+			wantIDs: []string{id("", "Quoted\ntext")},
+			want:    "```sh\necho hi\n```\n\n::: platform linux\n{{ include \"snippets/a.md\" }}\n:::\n\n> QUOTED\n> TEXT\n\n---\n",
+		},
+		{
+			name:    "duplicate headings",
+			body:    "# Setup\n\n# Setup\n",
+			wantIDs: []string{"p.md#setup", "p.md#setup~2"},
+			want:    "# SETUP\n\n# SETUP\n",
+		},
+		{
+			name:    "repeated text",
+			body:    "Done.\n\nDone.\n",
+			wantIDs: []string{id("", "Done."), id("", "Done.") + "~2"},
+			want:    "DONE.\n\nDONE.\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d := segment("p.md", tt.body, tt.body, "")
+			ids, texts := d.units("p.md")
+			if strings.Join(ids, " ") != strings.Join(tt.wantIDs, " ") {
+				t.Errorf("ids = %q, want %q", ids, tt.wantIDs)
+			}
+			targets := map[string]string{}
+			for i, id := range ids {
+				targets[id] = strings.ToUpper(texts[i])
+			}
+			if got := d.build("", targets); got != tt.want {
+				t.Errorf("build = %q, want %q", got, tt.want)
+			}
+			if got := d.build("", nil); got != tt.body {
+				t.Errorf("build without targets = %q, want the original", got)
+			}
+		})
+	}
+}
+
+func TestWithTitle(t *testing.T) {
+	t.Parallel()
+	tests := []struct{ header, want string }{
+		{"", "---\ntitle: Hallo\n---\n"},
+		{"---\ntitle: Hi\nplatform: linux # only\n---\n", "---\ntitle: Hallo\nplatform: linux # only\n---\n"},
+		{"---\norder: 2\n---\n", "---\ntitle: Hallo\norder: 2\n---\n"},
+	}
+	for _, tt := range tests {
+		if got := withTitle(tt.header, "Hallo"); got != tt.want {
+			t.Errorf("withTitle(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestExtractImport(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"day1.yml":            "title: Welcome\nstrings: {next: Onward}\npages:\n  - welcome.md\n  - it.md\n",
+		"welcome.md":          "---\ntitle: Welcome\nplatform: linux\n---\n# Hi {{ .User }}\n\nRead the [policy](https://x.example.com).\n\n- [ ] Enroll {#enroll}\n\n{{ include \"snippets/contact.md\" }}\n",
+		"it.md":               "# IT\n\nCall us.\n",
+		"snippets/contact.md": "Call x100.\n",
+	})
+
+	cat, err := Extract(os.DirFS(dir), "de")
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	var ids []string
+	for _, u := range cat.Units {
+		ids = append(ids, u.ID)
+	}
+	policy := "welcome.md#hi." + hash("Read the [policy](https://x.example.com).")[:8]
+	want := "day1.yml#title day1.yml#strings.next welcome.md#title welcome.md#hi " + policy + " welcome.md#enroll " +
+		"it.md#title it.md#it it.md#it." + hash("Call us.")[:8] + " snippets/contact.md#" + hash("Call x100.")[:8]
+	if got := strings.Join(ids, " "); got != want {
+		t.Fatalf("units = %s\nwant %s", got, want)
+	}
+	if tr, fz, un := cat.Counts(); tr != 0 || fz != 0 || un != len(ids) {
+		t.Errorf("Counts = %d, %d, %d, want every unit untranslated", tr, fz, un)
+	}
+
+	// Translate everything but it.md through an XLIFF round trip.
+	for i, u := range cat.Units {
+		if u.File() != "it.md" {
+			cat.Units[i].Target = "DE " + u.Source
+		}
+	}
+	var buf bytes.Buffer
+	if err := WriteXLIFF(&buf, cat); err != nil {
+		t.Fatal(err)
+	}
+	back, err := ReadXLIFF(&buf)
+	if err != nil {
+		t.Fatalf("ReadXLIFF: %v", err)
+	}
+	written, err := Import(dir, back)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if got := strings.Join(written, " "); got != "day1.de.yml welcome.de.md snippets/contact.de.md" {
+		t.Errorf("written = %s", got)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "welcome.de.md"))
+	wantPage := "---\ntitle: DE Welcome\nplatform: linux\n---\n# DE Hi {{ .User }}\n\nDE Read the [policy](https://x.example.com).\n\n- [ ] DE Enroll {#enroll}\n\n{{ include \"snippets/contact.md\" }}\n"
+	if string(data) != wantPage {
+		t.Errorf("welcome.de.md = %q\nwant %q", data, wantPage)
+	}
+	cfg, _ := pages.LoadLocalizedConfig(os.DirFS(dir), "de")
+	if cfg.Title != "DE Welcome" || cfg.Strings["next"] != "DE Onward" {
+		t.Errorf("day1.de.yml gives title %q, strings %v", cfg.Title, cfg.Strings)
+	}
+	shown, _, err := pages.Select(os.DirFS(dir), facts.Facts{OS: "linux", User: "ada", Locale: "de"})
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if got := shown[0].Markdown; !strings.Contains(got, "DE Hi ada") || !strings.Contains(got, "DE Call x100.") {
+		t.Errorf("translated page = %q", got)
+	}
+	if problems := pages.Validate(dir); len(problems) > 0 {
+		t.Errorf("Validate: %v", problems)
+	}
+
+	// Reword a translated paragraph, add one and extract again.
+	writeFiles(t, dir, map[string]string{
+		"welcome.md": "---\ntitle: Welcome\nplatform: linux\n---\n# Hi {{ .User }}\n\nRead the [security policy](https://x.example.com).\n\n- [ ] Enroll {#enroll}\n\nNew paragraph.\n\n{{ include \"snippets/contact.md\" }}\n",
+	})
+	reworded := "welcome.md#hi." + hash("Read the [security policy](https://x.example.com).")[:8]
+	cat, err = Extract(os.DirFS(dir), "de")
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	got := map[string]Unit{}
+	for _, u := range cat.Units {
+		got[u.ID] = u
+	}
+	checks := []struct {
+		id     string
+		target string
+		fuzzy  bool
+	}{
+		{"welcome.md#title", "DE Welcome", false},
+		{"welcome.md#enroll", "DE Enroll", false},
+		{reworded, "DE Read the [policy](https://x.example.com).", true},
+		{"welcome.md#hi." + hash("New paragraph.")[:8], "", false},
+		{"it.md#it." + hash("Call us.")[:8], "", false},
+		{"snippets/contact.md#" + hash("Call x100.")[:8], "DE Call x100.", false},
+		{"day1.yml#strings.next", "DE Onward", false},
+	}
+	for _, c := range checks {
+		u := got[c.id]
+		if u.Target != c.target || u.Fuzzy != c.fuzzy {
+			t.Errorf("%s: target %q fuzzy %v, want %q %v", c.id, u.Target, u.Fuzzy, c.target, c.fuzzy)
+		}
+	}
+	if len(cat.Unaligned) > 0 {
+		t.Errorf("Unaligned = %v", cat.Unaligned)
+	}
+
+	// A fuzzy unit imported unchanged stays fuzzy.
+	if _, err := Import(dir, cat); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	cat, _ = Extract(os.DirFS(dir), "de")
+	for _, u := range cat.Units {
+		if u.ID == reworded && !u.Fuzzy {
+			t.Error("fuzzy unit lost its flag after an import")
+		}
+	}
+}
+
+func TestCheckLocale(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"day1.yml": "locale: de\n", "a.md": "# A\n"})
+	for _, locale := range []string{"", "de"} {
+		if _, err := Extract(os.DirFS(dir), locale); err == nil {
+			t.Errorf("Extract(%q): want an error", locale)
+		}
+	}
+}
+
+func TestPO(t *testing.T) {
+	t.Parallel()
+	cat := Catalog{SourceLocale: "en", Locale: "pt-BR", Units: []Unit{
+		{ID: "a.md#title", Source: "Welcome", Target: "Bem-vindo"},
+		{ID: "a.md#1", Source: "Line one\nline \"two\"\\", Target: "Linha um\nlinha \"dois\"\\", Fuzzy: true},
+		{ID: "a.md#2", Source: "Untranslated"},
+	}}
+	var buf bytes.Buffer
+	if err := WritePO(&buf, cat); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "#, fuzzy\nmsgctxt \"a.md#1\"\nmsgid \"\"\n\"Line one\\n\"\n") {
+		t.Errorf("PO output:\n%s", buf.String())
+	}
+	// Translators' tools add comments and obsolete entries.
+	po := buf.String() + "\n# translator comment\n#~ msgctxt \"gone.md#1\"\n#~ msgid \"Gone\"\n#~ msgstr \"Weg\"\n"
+	back, err := ReadPO(strings.NewReader(po))
+	if err != nil {
+		t.Fatalf("ReadPO: %v", err)
+	}
+	if back.Locale != "pt-BR" || back.SourceLocale != "en" || len(back.Units) != len(cat.Units) {
+		t.Fatalf("ReadPO = %+v", back)
+	}
+	for i, u := range back.Units {
+		if u != cat.Units[i] {
+			t.Errorf("unit %d = %+v, want %+v", i, u, cat.Units[i])
+		}
+	}
+	if _, err := ReadPO(strings.NewReader("msgid \"x\"\nbogus \"y\"\n")); err == nil {
+		t.Error("ReadPO accepted an unknown keyword")
+	}
+}
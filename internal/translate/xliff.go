@@ -0,0 +1,97 @@
+package translate
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// XLIFF 1.2, with a <file> per source file. Units without a translation
+// have no <target>; fuzzy ones have state="needs-review-translation".
+
+const xliffNS = "urn:oasis:names:tc:xliff:document:1.2"
+
+type xliffDoc struct {
+	XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:1.2 xliff"`
+	Version string      `xml:"version,attr"`
+	Files   []xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	Original       string      `xml:"original,attr"`
+	SourceLanguage string      `xml:"source-language,attr"`
+	TargetLanguage string      `xml:"target-language,attr"`
+	Datatype       string      `xml:"datatype,attr"`
+	Units          []xliffUnit `xml:"body>trans-unit"`
+}
+
+type xliffUnit struct {
+	ID     string       `xml:"id,attr"`
+	Source string       `xml:"source"`
+	Target *xliffTarget `xml:"target"`
+}
+
+type xliffTarget struct {
+	State string `xml:"state,attr,omitempty"`
+	Text  string `xml:",chardata"`
+}
+
+// WriteXLIFF writes c as an XLIFF 1.2 document.
+func WriteXLIFF(w io.Writer, c Catalog) error {
+	doc := xliffDoc{Version: "1.2"}
+	for _, u := range c.Units {
+		if n := len(doc.Files); n == 0 || doc.Files[n-1].Original != u.File() {
+			doc.Files = append(doc.Files, xliffFile{
+				Original:       u.File(),
+				SourceLanguage: c.SourceLocale,
+				TargetLanguage: c.Locale,
+				Datatype:       "x-markdown",
+			})
+		}
+		xu := xliffUnit{ID: u.ID, Source: u.Source}
+		if u.Target != "" {
+			xu.Target = &xliffTarget{State: "translated", Text: u.Target}
+			if u.Fuzzy {
+				xu.Target.State = "needs-review-translation"
+			}
+		}
+		f := &doc.Files[len(doc.Files)-1]
+		f.Units = append(f.Units, xu)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ReadXLIFF reads an XLIFF 1.2 document. States starting with "needs-"
+// mark fuzzy units.
+func ReadXLIFF(r io.Reader) (Catalog, error) {
+	var doc xliffDoc
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return Catalog{}, fmt.Errorf("parse XLIFF: %w", err)
+	}
+	if doc.XMLName.Space != xliffNS || !strings.HasPrefix(doc.Version, "1.") {
+		return Catalog{}, fmt.Errorf("parse XLIFF: want version 1.2, got %q", doc.Version)
+	}
+	var c Catalog
+	for _, f := range doc.Files {
+		c.SourceLocale, c.Locale = f.SourceLanguage, f.TargetLanguage
+		for _, xu := range f.Units {
+			u := Unit{ID: xu.ID, Source: xu.Source}
+			if xu.Target != nil {
+				u.Target = xu.Target.Text
+				u.Fuzzy = strings.HasPrefix(xu.Target.State, "needs-")
+			}
+			c.Units = append(c.Units, u)
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,300 @@
+package tui
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/TsekNet/day1/internal/pages"
+	"github.com/rivo/uniseg"
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+)
+
+// ANSI styles. They are only emitted when color is enabled.
+const (
+	styleReset  = "\x1b[0m"
+	styleBold   = "\x1b[1m"
+	styleDim    = "\x1b[2m"
+	styleItalic = "\x1b[3m"
+	styleUnder  = "\x1b[4m"
+	styleStrike = "\x1b[9m"
+	styleAccent = "\x1b[32m"
+	styleCode   = "\x1b[36m"
+)
+
+var ansiRe = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// width returns the number of terminal cells s occupies.
+func width(s string) int { return uniseg.StringWidth(ansiRe.ReplaceAllString(s, "")) }
+
+// Render converts page markdown to terminal text wrapped at cols columns.
+// checked reports the state of each task list item in document order;
+// items are numbered so the user can toggle them by number. opts select
+// the ::: directive blocks shown, as for pages.RenderHTML.
+func Render(markdown string, cols int, color bool, checked []bool, opts ...pages.RenderOption) string {
+	doc, source := pages.Parse(markdown, opts...)
+	r := &textRenderer{source: source, cols: cols, color: color, checked: checked}
+	r.blocks(doc, "", "")
+	return strings.TrimRight(r.out.String(), "\n") + "\n"
+}
+
+type textRenderer struct {
+	source  []byte
+	cols    int
+	color   bool
+	checked []bool
+	nchecks int
+	out     strings.Builder
+}
+
+func (r *textRenderer) style(s string, styles ...string) string {
+	if !r.color || s == "" {
+		return s
+	}
+	return strings.Join(styles, "") + s + styleReset
+}
+
+// blocks renders the block children of n. first prefixes the first line
+// and rest every following line, for list markers and quote bars.
+func (r *textRenderer) blocks(n ast.Node, first, rest string) {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		r.block(c, first, rest)
+		first = rest
+	}
+}
+
+func (r *textRenderer) block(n ast.Node, first, rest string) {
+	switch n := n.(type) {
+	case *ast.Heading:
+		styles := []string{styleBold}
+		if n.Level == 1 {
+			styles = append(styles, styleAccent)
+		}
+		r.wrap(r.words(n, styles...), first, rest)
+		if n.Level == 1 {
+			rule := strings.Repeat("─", min(width(r.plain(n)), r.cols-width(rest)))
+			r.out.WriteString(rest + r.style(rule, styleAccent) + "\n")
+		}
+		r.out.WriteString("\n")
+	case *ast.Paragraph, *ast.TextBlock:
+		r.wrap(r.words(n), first, rest)
+		if _, ok := n.(*ast.Paragraph); ok {
+			r.out.WriteString("\n")
+		}
+	case *ast.List:
+		num := n.Start
+		for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+			marker := "• "
+			if n.IsOrdered() {
+				marker = fmt.Sprintf("%d. ", num)
+				num++
+			}
+			if cb := taskCheckBox(item); cb != nil {
+				marker = r.checkMarker()
+			}
+			pad := strings.Repeat(" ", width(marker))
+			r.blocks(item, first+r.style(marker, styleAccent), rest+pad)
+			first = rest
+		}
+		if !n.IsTight {
+			return
+		}
+		r.out.WriteString("\n")
+	case *ast.Blockquote:
+		bar := r.style("│ ", styleAccent)
+		r.blocks(n, first+bar, rest+bar)
+	case *ast.FencedCodeBlock, *ast.CodeBlock:
+		lines := n.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			seg := lines.At(i)
+			line := strings.TrimRight(string(seg.Value(r.source)), "\n")
+			r.out.WriteString(first + "    " + r.style(line, styleCode) + "\n")
+			first = rest
+		}
+		r.out.WriteString("\n")
+	case *ast.ThematicBreak:
+		r.out.WriteString(first + r.style(strings.Repeat("─", max(r.cols-width(first), 1)), styleDim) + "\n\n")
+	case *extast.Table:
+		r.table(n, first, rest)
+	case *ast.HTMLBlock:
+		// Raw HTML has no terminal equivalent.
+	default:
+		r.blocks(n, first, rest)
+	}
+}
+
+// checkMarker returns the numbered checkbox for the next task list item.
+func (r *textRenderer) checkMarker() string {
+	i := r.nchecks
+	r.nchecks++
+	box := "[ ]"
+	if i < len(r.checked) && r.checked[i] {
+		box = "[x]"
+	}
+	return fmt.Sprintf("%s %d. ", box, i+1)
+}
+
+func taskCheckBox(item ast.Node) *extast.TaskCheckBox {
+	if tb := item.FirstChild(); tb != nil {
+		if cb, ok := tb.FirstChild().(*extast.TaskCheckBox); ok {
+			return cb
+		}
+	}
+	return nil
+}
+
+func (r *textRenderer) table(t *extast.Table, first, rest string) {
+	var rows [][]string
+	for row := t.FirstChild(); row != nil; row = row.NextSibling() {
+		var cells []string
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			cells = append(cells, strings.Join(r.words(cell), " "))
+		}
+		rows = append(rows, cells)
+	}
+	widths := map[int]int{}
+	for _, row := range rows {
+		for i, c := range row {
+			widths[i] = max(widths[i], width(c))
+		}
+	}
+	for i, row := range rows {
+		var b strings.Builder
+		for j, c := range row {
+			if j > 0 {
+				b.WriteString("  ")
+			}
+			if i == 0 {
+				c = r.style(ansiRe.ReplaceAllString(c, ""), styleBold)
+			}
+			b.WriteString(c + strings.Repeat(" ", widths[j]-width(c)))
+		}
+		r.out.WriteString(first + strings.TrimRight(b.String(), " ") + "\n")
+		first = rest
+	}
+	r.out.WriteString("\n")
+}
+
+// plain returns the text content of n without styling.
+func (r *textRenderer) plain(n ast.Node) string {
+	color := r.color
+	r.color = false
+	defer func() { r.color = color }()
+	return strings.Join(r.words(n), " ")
+}
+
+// words renders the inline content of n as styled words. A word of "\n"
+// forces a line break.
+func (r *textRenderer) words(n ast.Node, styles ...string) []string {
+	w := &wordBuf{r: r}
+	r.inline(w, n, styles)
+	w.flush()
+	return w.words
+}
+
+func (r *textRenderer) inline(w *wordBuf, n ast.Node, styles []string) {
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			w.write(string(c.Segment.Value(r.source)), styles)
+			switch {
+			case c.HardLineBreak():
+				w.flush()
+				w.words = append(w.words, "\n")
+			case c.SoftLineBreak():
+				w.flush()
+			}
+		case *ast.String:
+			// The typographer emits smart quotes and dashes as entities.
+			w.write(html.UnescapeString(string(c.Value)), styles)
+		case *ast.CodeSpan:
+			w.write(r.plain(c), append(styles, styleCode))
+		case *ast.Emphasis:
+			s := styleItalic
+			if c.Level > 1 {
+				s = styleBold
+			}
+			r.inline(w, c, append(styles, s))
+		case *extast.Strikethrough:
+			r.inline(w, c, append(styles, styleStrike))
+		case *ast.Link:
+			r.inline(w, c, append(styles, styleUnder))
+			if dest := string(c.Destination); dest != r.plain(c) {
+				w.write(" ("+dest+")", []string{styleDim})
+			}
+		case *ast.AutoLink:
+			w.write(string(c.URL(r.source)), append(styles, styleUnder))
+		case *ast.Image:
+			w.write("[image: "+r.plain(c)+"]", []string{styleDim})
+		case *extast.TaskCheckBox, *ast.RawHTML:
+			// Checkboxes are rendered as list markers; raw HTML is dropped.
+		default:
+			r.inline(w, c, styles)
+		}
+	}
+}
+
+// wrap writes words as lines of at most r.cols cells.
+func (r *textRenderer) wrap(words []string, first, rest string) {
+	line, lineWidth := first, width(first)
+	empty := true
+	for _, word := range words {
+		if word == "\n" {
+			r.out.WriteString(line + "\n")
+			line, lineWidth, empty = rest, width(rest), true
+			continue
+		}
+		ww := width(word)
+		if !empty && lineWidth+1+ww > r.cols {
+			r.out.WriteString(line + "\n")
+			line, lineWidth, empty = rest, width(rest), true
+		}
+		if !empty {
+			line += " "
+			lineWidth++
+		}
+		line += word
+		lineWidth += ww
+		empty = false
+	}
+	if !empty || line != rest {
+		r.out.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+}
+
+// wordBuf splits inline text into styled words, joining pieces that are
+// not separated by whitespace ("**bold**," is one word).
+type wordBuf struct {
+	r     *textRenderer
+	words []string
+	cur   strings.Builder
+}
+
+func (w *wordBuf) write(text string, styles []string) {
+	if text == "" {
+		return
+	}
+	if isSpace(text[0]) {
+		w.flush()
+	}
+	for i, f := range strings.Fields(text) {
+		if i > 0 {
+			w.flush()
+		}
+		w.cur.WriteString(w.r.style(f, styles...))
+	}
+	if isSpace(text[len(text)-1]) {
+		w.flush()
+	}
+}
+
+func (w *wordBuf) flush() {
+	if w.cur.Len() > 0 {
+		w.words = append(w.words, w.cur.String())
+		w.cur.Reset()
+	}
+}
+
+func isSpace(b byte) bool { return b == ' ' || b == '\t' || b == '\n' }
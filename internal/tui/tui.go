@@ -0,0 +1,225 @@
+// Package tui runs the onboarding wizard in a terminal, for Linux machines
+// without a graphical session such as servers reached over SSH. It shows
+// the same pages and saves the same checklist, progress and sentinel files
+// as the webview front end.
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/TsekNet/day1/internal/app"
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/pages"
+)
+
+const clearScreen = "\x1b[H\x1b[2J"
+
+// Options configures a terminal session.
+type Options struct {
+	// Title is shown above every page.
+	Title string
+	// Pages are the pages a was created with.
+	Pages []pages.Page
+	// Facts decide which ::: directive blocks of a page are shown, as in
+	// app.Config.
+	Facts facts.Facts
+	// FinalMD is the final page's markdown; a page built from the
+	// done_title and done_text_terminal strings is used when empty.
+	FinalMD string
+	// Width is the terminal width in columns. Defaults to 80.
+	Width int
+	// Color enables ANSI styles and clears the screen between pages.
+	Color bool
+}
+
+// Run shows the pages of a one at a time, reading commands from in line
+// by line. It resumes on the last viewed page and writes the sentinel
+// when the user finishes the final page. Quitting, snoozing or reaching
+// the end of in returns without writing the sentinel, keeping progress;
+// quitting and snoozing are recorded as dismissals. Like the window, it
+// doesn't move past a page with unchecked required items, and a
+// mandatory wizard can't be quit or snoozed.
+func Run(a *app.App, opts Options, in io.Reader, out io.Writer) error {
+	if opts.Width <= 0 {
+		opts.Width = 80
+	}
+	s := &session{a: a, opts: opts, in: bufio.NewScanner(in), out: out}
+	return s.run()
+}
+
+type session struct {
+	a    *app.App
+	opts Options
+	in   *bufio.Scanner
+	out  io.Writer
+	note string
+}
+
+func (s *session) run() error {
+	i := s.a.GetProgress().Index
+	if first := s.a.FirstIncomplete(); first >= 0 && first < i {
+		i = first
+	}
+	for {
+		final := i >= len(s.opts.Pages)
+		var items []pages.ChecklistItem
+		if final {
+			s.show(s.finalMD(), nil, s.str("done_title"), len(s.opts.Pages))
+		} else {
+			s.a.SetCurrentPage(i)
+			p := s.opts.Pages[i]
+			items = pages.Checklist(p.Markdown, pages.WithFacts(s.opts.Facts))
+			s.show(p.Markdown, s.checked(p, items), p.Frontmatter.Title, i)
+		}
+
+		cmd, ok := s.prompt(final, len(items))
+		if !ok {
+			return s.in.Err()
+		}
+		switch {
+		case cmd == "" || cmd == "n":
+			if first := s.a.FirstIncomplete(); first >= 0 && first <= i {
+				i = first
+				s.note = s.requiredNote(i)
+				break
+			}
+			if final {
+				if err := s.a.MarkComplete(); err != nil {
+					return err
+				}
+				fmt.Fprintln(s.out, s.str("completed"))
+				return nil
+			}
+			i++
+		case cmd == "b":
+			if i > 0 {
+				i--
+			}
+		case cmd == "q":
+			if err := s.a.MarkDismissed(); err != nil {
+				s.note = s.str("mandatory")
+				break
+			}
+			return nil
+		case cmd == "s" && s.a.CanSnooze():
+			until, err := s.a.MarkSnoozed()
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(s.out, s.str("snoozed", "time", until.Local().Format("2006-01-02 15:04")))
+			return nil
+		case cmd == "h":
+			if url := s.a.GetHelpURL(); url != "" {
+				s.note = s.str("help") + " " + url
+			} else {
+				s.note = s.str("no_help")
+			}
+		default:
+			n, err := strconv.Atoi(cmd)
+			if err != nil || final || n < 1 || n > len(items) {
+				s.note = s.str("unknown_command", "command", strconv.Quote(cmd))
+				break
+			}
+			s.a.ToggleCheckItem(app.CheckKey(s.opts.Pages[i].ID(), items[n-1].ID))
+		}
+	}
+}
+
+// show draws a page with a header naming the step.
+func (s *session) show(markdown string, checked []bool, title string, index int) {
+	if s.opts.Color {
+		fmt.Fprint(s.out, clearScreen)
+	}
+	total := len(s.opts.Pages)
+	header := fmt.Sprintf("%s — %s", s.opts.Title, title)
+	if index < total {
+		step := s.str("progress", "n", strconv.Itoa(index+1), "total", strconv.Itoa(total))
+		header += " (" + step + ")"
+	}
+	fmt.Fprintln(s.out, header)
+	fmt.Fprintln(s.out, strings.Repeat("─", min(width(header), s.opts.Width)))
+	fmt.Fprintln(s.out)
+	fmt.Fprint(s.out, Render(markdown, s.opts.Width, s.opts.Color, checked, pages.WithFacts(s.opts.Facts)))
+	fmt.Fprintln(s.out)
+	if s.note != "" {
+		fmt.Fprintln(s.out, s.note)
+		s.note = ""
+	}
+}
+
+// prompt lists the available commands and reads one. ok is false at the
+// end of input.
+func (s *session) prompt(final bool, nitems int) (cmd string, ok bool) {
+	var opts []string
+	if final {
+		opts = append(opts, "[Enter] "+s.str("finish"))
+	} else {
+		opts = append(opts, "[Enter] "+s.str("next"))
+	}
+	opts = append(opts, "[b] "+s.str("back"))
+	if nitems > 0 {
+		opts = append(opts, fmt.Sprintf("[1-%d] %s", nitems, s.str("toggle")))
+	}
+	if s.a.GetHelpURL() != "" {
+		opts = append(opts, "[h] "+s.str("help"))
+	}
+	if s.a.CanSnooze() {
+		opts = append(opts, "[s] "+s.str("snooze"))
+	}
+	if !s.a.GetMandatory() {
+		opts = append(opts, "[q] "+s.str("close"))
+	}
+	fmt.Fprintf(s.out, "%s > ", strings.Join(opts, "  "))
+	if !s.in.Scan() {
+		fmt.Fprintln(s.out)
+		return "", false
+	}
+	return strings.ToLower(strings.TrimSpace(s.in.Text())), true
+}
+
+// requiredNote asks for the unchecked required items of page i, by
+// number.
+func (s *session) requiredNote(i int) string {
+	p := s.opts.Pages[i]
+	required := s.a.GetPages()[i].Required
+	state := s.a.GetCheckState()
+	var nums []string
+	for n, item := range pages.Checklist(p.Markdown, pages.WithFacts(s.opts.Facts)) {
+		if key := app.CheckKey(p.ID(), item.ID); slices.Contains(required, key) && !state[key] {
+			nums = append(nums, strconv.Itoa(n+1))
+		}
+	}
+	return fmt.Sprintf("%s (%s)", s.str("required_left"), strings.Join(nums, ", "))
+}
+
+// checked returns the saved state of items in page order.
+func (s *session) checked(p pages.Page, items []pages.ChecklistItem) []bool {
+	state := s.a.GetCheckState()
+	out := make([]bool, len(items))
+	for k, item := range items {
+		out[k] = state[app.CheckKey(p.ID(), item.ID)]
+	}
+	return out
+}
+
+func (s *session) finalMD() string {
+	if s.opts.FinalMD != "" {
+		return s.opts.FinalMD
+	}
+	return "# " + s.str("done_title") + "\n\n" + s.str("done_text_terminal")
+}
+
+// str returns the wizard string key, the same the window shows, with
+// each {name} of the name, value pairs in vars filled in.
+func (s *session) str(key string, vars ...string) string {
+	text := s.a.GetStrings()[key]
+	for i := 0; i+1 < len(vars); i += 2 {
+		text = strings.ReplaceAll(text, "{"+vars[i]+"}", vars[i+1])
+	}
+	return text
+}
@@ -0,0 +1,166 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/TsekNet/day1/internal/app"
+	"github.com/TsekNet/day1/internal/facts"
+	"github.com/TsekNet/day1/internal/marker"
+	"github.com/TsekNet/day1/internal/pages"
+)
+
+func TestRender(t *testing.T) {
+	t.Parallel()
+	md := "# Welcome\n\nSet up **your** laptop, see [the wiki](https://wiki.example.com).\n\n" +
+		"- [ ] Enroll device {#enroll}\n- [ ] Join chat\n\n> Ask for help.\n\n```\nsudo apt update\n```\n\n" +
+		"| Tool | Use |\n|---|---|\n| Slack | Chat |\n"
+	got := Render(md, 80, false, []bool{true, false})
+
+	for _, want := range []string{
+		"Welcome\n───────",
+		"Set up your laptop, see the wiki (https://wiki.example.com).",
+		"[x] 1. Enroll device\n",
+		"[ ] 2. Join chat\n",
+		"│ Ask for help.",
+		"    sudo apt update",
+		"Tool   Use\nSlack  Chat",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Render() missing %q in:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "\x1b[") || strings.Contains(got, "{#enroll}") {
+		t.Errorf("Render() leaked escapes or ID markers:\n%s", got)
+	}
+	if got := Render("**Bold**, done.", 80, true, nil); !strings.Contains(got, styleBold+"Bold"+styleReset+",") {
+		t.Errorf("Render(color) = %q, want styled bold word joined with comma", got)
+	}
+	md = "::: platform windows\n- [ ] Enable BitLocker\n:::\n\n::: platform !windows\n- [ ] Enable disk encryption\n:::\n"
+	got = Render(md, 80, false, nil, pages.WithFacts(facts.Facts{OS: "darwin"}))
+	if want := "[ ] 1. Enable disk encryption\n"; got != want {
+		t.Errorf("Render(darwin) = %q, want %q", got, want)
+	}
+}
+
+func TestRenderWraps(t *testing.T) {
+	t.Parallel()
+	got := Render("- "+strings.Repeat("word ", 12), 20, false, nil)
+	for _, line := range strings.Split(strings.TrimRight(got, "\n"), "\n") {
+		if width(line) > 20 {
+			t.Errorf("line %q is wider than 20 columns", line)
+		}
+		if !strings.HasPrefix(line, "• ") && !strings.HasPrefix(line, "  word") {
+			t.Errorf("line %q is not indented under the bullet", line)
+		}
+	}
+}
+
+func testPages() []pages.Page {
+	return []pages.Page{
+		{Frontmatter: pages.Frontmatter{Title: "Welcome"}, Markdown: "# Welcome\n", SourceFile: "welcome.md"},
+		{Frontmatter: pages.Frontmatter{Title: "Setup"}, Markdown: "- [ ] Enroll {#enroll}\n- [ ] Join chat {#chat}\n", SourceFile: "setup.md"},
+	}
+}
+
+func TestRunCompletes(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	pp := testPages()
+	a := app.New(pp, app.Config{ContentVersion: "v1"})
+
+	var out strings.Builder
+	// Next, toggle item 2, an unknown command, next to the final page, finish.
+	if err := Run(a, Options{Title: "Day 1", Pages: pp}, strings.NewReader("\n2\nx\nn\n\n"), &out); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+	for _, want := range []string{"Day 1 — Setup (2 of 2)", "[x] 2. Join chat", `Unknown command "x"`, "all set!", "Onboarding complete."} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+	st, done, err := marker.Read()
+	if err != nil || !done || st.Version != "v1" {
+		t.Errorf("marker.Read() = %+v, %v, %v; want completed v1", st, done, err)
+	}
+	state := app.New(pp, app.Config{}).GetCheckState()
+	if !state["setup:chat"] || state["setup:enroll"] {
+		t.Errorf("check state = %v, want only setup:chat", state)
+	}
+}
+
+func TestRunTranslated(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	pp := testPages()
+	a := app.New(pp, app.Config{Locale: "de"})
+
+	var out strings.Builder
+	if err := Run(a, Options{Title: "Day 1", Pages: pp}, strings.NewReader("\nx\n\n\n"), &out); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+	for _, want := range []string{
+		"Day 1 — Welcome (1 von 2)",
+		"[Enter] Weiter  [b] Zurück",
+		"[1-2] Abhaken oder zurücksetzen",
+		"[q] Schließen",
+		`Unbekannter Befehl "x".`,
+		"Day 1 — Alles erledigt!",
+		"Drücken Sie die Eingabetaste",
+		"Onboarding abgeschlossen.",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunQuitKeepsProgress(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	pp := testPages()
+
+	var out strings.Builder
+	if err := Run(app.New(pp, app.Config{}), Options{Pages: pp}, strings.NewReader("\nq\n"), &out); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+	if done, _ := marker.Exists(); done {
+		t.Error("quitting wrote the sentinel")
+	}
+
+	// A new session resumes on the second page; EOF also quits.
+	out.Reset()
+	if err := Run(app.New(pp, app.Config{}), Options{Title: "Day 1", Pages: pp}, strings.NewReader("b\n"), &out); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+	if first := strings.SplitN(out.String(), "\n", 2)[0]; first != "Day 1 — Setup (2 of 2)" {
+		t.Errorf("resumed on %q, want the setup page", first)
+	}
+	if !strings.Contains(out.String(), "Day 1 — Welcome (1 of 2)") {
+		t.Error("back did not return to the first page")
+	}
+	if done, _ := marker.Exists(); done {
+		t.Error("end of input wrote the sentinel")
+	}
+}
+
+func TestRunMandatory(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	pp := testPages()
+	pp[1].Markdown = "- [ ] Enroll {#enroll required}\n- [ ] Join chat {#chat}\n"
+	a := app.New(pp, app.Config{Mandatory: true})
+
+	var out strings.Builder
+	// Quit is refused; Enter stays on the setup page until item 1 is checked.
+	if err := Run(a, Options{Title: "Day 1", Pages: pp}, strings.NewReader("q\n\n\n1\n\n\n"), &out); err != nil {
+		t.Fatalf("Run() = %v", err)
+	}
+	for _, want := range []string{"can't be closed", "Check the required items to continue. (1)", "Onboarding complete."} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q", want)
+		}
+	}
+	if strings.Contains(out.String(), "[q]") {
+		t.Error("mandatory prompt offers quit")
+	}
+	if done, _ := marker.Exists(); !done {
+		t.Error("completing did not write the sentinel")
+	}
+}
@@ -0,0 +1,83 @@
+package urischeme
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// InternalPrefix is the scheme of links the wizard handles itself, such as
+// "day1:page/security" or "day1:check/all". They never leave the app.
+const InternalPrefix = "day1:"
+
+// Operations of a day1: link.
+const (
+	OpPage   = "page"   // day1:page/<id> shows a page
+	OpFinal  = "final"  // day1:final shows the final page
+	OpCheck  = "check"  // day1:check/[<page>/]<item> toggles an item; "all" checks every item
+	OpAction = "action" // day1:action/<name> runs an action from day1.yml
+)
+
+// CheckAll is the item of a day1:check link that checks every item of the
+// page.
+const CheckAll = "all"
+
+var nameRe = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Internal is a parsed day1: link.
+type Internal struct {
+	Op string
+	// Page is the page ID of page links, and of check links that name a
+	// page. Empty means the current page.
+	Page string
+	// Item is the checklist item ID of check links, or CheckAll.
+	Item string
+	// Name is the action name of action links.
+	Name string
+}
+
+// ParseInternal parses a day1: link. A #fragment is ignored.
+func ParseInternal(rawURL string) (Internal, error) {
+	if !strings.HasPrefix(strings.ToLower(rawURL), InternalPrefix) {
+		return Internal{}, fmt.Errorf("not a %s link", strings.TrimSuffix(InternalPrefix, ":"))
+	}
+	rest, _, _ := strings.Cut(rawURL[len(InternalPrefix):], "#")
+	rest, err := url.PathUnescape(rest)
+	if err != nil {
+		return Internal{}, fmt.Errorf("invalid escape in %q", rawURL)
+	}
+	op, arg, _ := strings.Cut(rest, "/")
+	switch op {
+	case OpPage:
+		if arg == "" {
+			return Internal{}, fmt.Errorf("%s link needs a page ID", op)
+		}
+		return Internal{Op: op, Page: arg}, nil
+	case OpFinal:
+		if arg != "" {
+			return Internal{}, fmt.Errorf("%s link takes no argument", op)
+		}
+		return Internal{Op: op}, nil
+	case OpCheck:
+		page, item := "", arg
+		if i := strings.LastIndex(arg, "/"); i >= 0 {
+			page, item = arg[:i], arg[i+1:]
+		}
+		if !nameRe.MatchString(item) {
+			return Internal{}, fmt.Errorf("%s link needs an item ID or %q", op, CheckAll)
+		}
+		return Internal{Op: op, Page: page, Item: item}, nil
+	case OpAction:
+		if !nameRe.MatchString(arg) {
+			return Internal{}, fmt.Errorf("%s link needs an action name", op)
+		}
+		return Internal{Op: op, Name: arg}, nil
+	}
+	return Internal{}, fmt.Errorf("unknown day1 link %q (want page, final, check or action)", op)
+}
+
+// PageURL returns the day1: link that shows the page with the given ID.
+func PageURL(id string) string {
+	return InternalPrefix + OpPage + "/" + id
+}
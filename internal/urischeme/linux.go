@@ -0,0 +1,134 @@
+package urischeme
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LinuxSettingsPrefix is the scheme of Linux settings links such as
+// "x-linux-settings:network". Linux has no standard settings URI, so day1
+// maps the panel to the settings app of the running desktop.
+const LinuxSettingsPrefix = "x-linux-settings:"
+
+// Desktops with a known settings app, as named in XDG_CURRENT_DESKTOP.
+const (
+	DesktopGNOME = "gnome"
+	DesktopKDE   = "kde"
+	DesktopXFCE  = "xfce"
+)
+
+// settingsApps is the settings launcher of each desktop.
+var settingsApps = map[string]string{
+	DesktopGNOME: "gnome-control-center",
+	DesktopKDE:   "systemsettings",
+	DesktopXFCE:  "xfce4-settings-manager",
+}
+
+// linuxPanels maps a panel name to the launcher arguments that open it on
+// each desktop. A desktop without an entry opens the settings app's main
+// window instead.
+var linuxPanels = map[string]map[string][]string{
+	"network":   {DesktopGNOME: {"network"}, DesktopKDE: {"kcm_networkmanagement"}},
+	"wifi":      {DesktopGNOME: {"wifi"}, DesktopKDE: {"kcm_networkmanagement"}},
+	"bluetooth": {DesktopGNOME: {"bluetooth"}, DesktopKDE: {"kcm_bluetooth"}},
+	"display":   {DesktopGNOME: {"display"}, DesktopKDE: {"kcm_kscreen"}, DesktopXFCE: {"--dialog=xfce-display-settings"}},
+	"sound":     {DesktopGNOME: {"sound"}, DesktopKDE: {"kcm_pulseaudio"}},
+	"power":     {DesktopGNOME: {"power"}, DesktopKDE: {"kcm_powerdevilprofilesconfig"}},
+	"privacy":   {DesktopGNOME: {"privacy"}},
+	"keyboard":  {DesktopGNOME: {"keyboard"}, DesktopKDE: {"kcm_keyboard"}, DesktopXFCE: {"--dialog=xfce-keyboard-settings"}},
+	"mouse":     {DesktopGNOME: {"mouse"}, DesktopKDE: {"kcm_mouse"}, DesktopXFCE: {"--dialog=xfce-mouse-settings"}},
+	"printers":  {DesktopGNOME: {"printers"}, DesktopKDE: {"kcm_printer_manager"}},
+	"users":     {DesktopGNOME: {"users"}, DesktopKDE: {"kcm_users"}},
+}
+
+// LinuxPanels returns the panel names x-linux-settings: links may use,
+// sorted.
+func LinuxPanels() []string {
+	out := make([]string, 0, len(linuxPanels))
+	for name := range linuxPanels {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// UnknownDesktopError is returned by LinuxSettingsCommand when the desktop
+// has no known settings app. Its message tells the user where to go
+// instead, in English; front ends show a translated one built from
+// PanelTitle.
+type UnknownDesktopError struct {
+	Desktop string
+	Panel   string
+}
+
+func (e *UnknownDesktopError) Error() string {
+	if e.Panel == "" {
+		return "Open your system settings."
+	}
+	return fmt.Sprintf("Open your system settings and go to %s.", e.PanelTitle())
+}
+
+// PanelTitle returns the name the user sees for the panel, or "" when
+// the link opens the settings app itself.
+func (e *UnknownDesktopError) PanelTitle() string {
+	switch e.Panel {
+	case "":
+		return ""
+	case "wifi":
+		return "Wi-Fi"
+	}
+	return strings.ToUpper(e.Panel[:1]) + e.Panel[1:]
+}
+
+// linuxPanel returns the panel named by an x-linux-settings: URL. An
+// empty panel opens the settings app itself.
+func linuxPanel(rawURL string) (string, error) {
+	if !strings.HasPrefix(strings.ToLower(rawURL), LinuxSettingsPrefix) {
+		return "", fmt.Errorf("not a %s link", strings.TrimSuffix(LinuxSettingsPrefix, ":"))
+	}
+	panel := strings.ToLower(strings.Trim(rawURL[len(LinuxSettingsPrefix):], "/"))
+	if panel == "" {
+		return "", nil
+	}
+	if _, ok := linuxPanels[panel]; !ok {
+		return "", fmt.Errorf("unknown settings panel %q (want one of %s)", panel, strings.Join(LinuxPanels(), ", "))
+	}
+	return panel, nil
+}
+
+// Desktop returns the desktop named by an XDG_CURRENT_DESKTOP value such
+// as "ubuntu:GNOME", or "" when none is known.
+func Desktop(xdgCurrentDesktop string) string {
+	for _, name := range strings.Split(xdgCurrentDesktop, ":") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "gnome", "gnome-classic", "unity", "budgie":
+			return DesktopGNOME
+		case "kde", "plasma":
+			return DesktopKDE
+		case "xfce":
+			return DesktopXFCE
+		}
+	}
+	return ""
+}
+
+// LinuxSettingsCommand returns the argv that opens the panel of an
+// x-linux-settings: URL on the desktop named by xdgCurrentDesktop. The
+// first element is the program; run it directly, never through a shell.
+// When the desktop is unknown the error is an *UnknownDesktopError.
+func LinuxSettingsCommand(rawURL, xdgCurrentDesktop string) ([]string, error) {
+	panel, err := linuxPanel(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	desktop := Desktop(xdgCurrentDesktop)
+	if desktop == "" {
+		return nil, &UnknownDesktopError{Desktop: xdgCurrentDesktop, Panel: panel}
+	}
+	argv := []string{settingsApps[desktop]}
+	if panel != "" {
+		argv = append(argv, linuxPanels[panel][desktop]...)
+	}
+	return argv, nil
+}
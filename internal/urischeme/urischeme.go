@@ -1,13 +1,23 @@
 // Package urischeme validates and classifies URIs that day1 is allowed to
 // open. Each platform has its own settings URI scheme (ms-settings: on Windows,
-// x-apple.systempreferences: on macOS) plus the universal http/https. The
+// x-apple.systempreferences: on macOS, x-linux-settings: on Linux) plus the
+// universal http/https. day1: links are handled inside the wizard. The
 // package centralises the allow-list so callers don't scatter platform checks.
+//
+// A Policy extends the built-in schemes with site-specific ones and can
+// restrict web links to allowed hosts. The package-level functions use the
+// built-in policy.
 package urischeme
 
 import (
+	"errors"
+	"fmt"
 	"net/url"
-	"runtime"
+	"path"
+	"regexp"
 	"strings"
+
+	"github.com/TsekNet/day1/internal/platform"
 )
 
 // Kind describes what category a URI falls into.
@@ -17,13 +27,37 @@ const (
 	KindUnknown  Kind = iota
 	KindWeb           // http, https
 	KindSettings      // platform settings panel
+	KindApp           // app handler such as slack:, zoommtg: or mailto:
+	KindInternal      // day1: link handled by the wizard itself
 )
 
+var kindNames = map[Kind]string{KindWeb: "web", KindSettings: "settings", KindApp: "app"}
+
+func (k Kind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	if k == KindInternal {
+		return "internal"
+	}
+	return "unknown"
+}
+
+// ParseKind returns the Kind named s ("web", "settings" or "app").
+func ParseKind(s string) (Kind, error) {
+	for k, name := range kindNames {
+		if name == s {
+			return k, nil
+		}
+	}
+	return KindUnknown, fmt.Errorf("unknown kind %q (want web, settings or app)", s)
+}
+
 // Scheme is a registered URI scheme with its kind and platform constraint.
 type Scheme struct {
 	Prefix   string // scheme prefix including ":" (e.g. "ms-settings:")
 	Kind     Kind
-	Platform string // GOOS value, or "" for all platforms
+	Platform string // GOOS value, or "" for all platforms; see platform.Matches
 	Example  string // documentation example
 }
 
@@ -33,87 +67,212 @@ var registry = []Scheme{
 	{Prefix: "http:", Kind: KindWeb, Example: "http://intranet.corp/kb"},
 	{Prefix: "ms-settings:", Kind: KindSettings, Platform: "windows", Example: "ms-settings:windowsupdate"},
 	{Prefix: "x-apple.systempreferences:", Kind: KindSettings, Platform: "darwin", Example: "x-apple.systempreferences:com.apple.preference.security"},
+	{Prefix: LinuxSettingsPrefix, Kind: KindSettings, Platform: "linux", Example: "x-linux-settings:network"},
+	{Prefix: InternalPrefix, Kind: KindInternal, Example: "day1:page/security"},
 }
 
-// findScheme returns the first registry entry matching rawURL on goos, or nil.
-func findScheme(rawURL, goos string) *Scheme {
-	lower := strings.ToLower(rawURL)
-	for i := range registry {
-		s := &registry[i]
-		if !strings.HasPrefix(lower, s.Prefix) {
-			continue
+// Policy is an allow-list of schemes plus optional host rules for web
+// links. The zero Policy allows nothing; use Default or NewPolicy.
+type Policy struct {
+	schemes    []Scheme
+	allowHosts []string
+	denyHosts  []string
+}
+
+var defaultPolicy = &Policy{schemes: registry}
+
+// Default returns the built-in policy: the registry schemes and any host.
+func Default() *Policy { return defaultPolicy }
+
+var (
+	schemeNameRe = regexp.MustCompile(`^[a-z][a-z0-9+.-]*$`)
+
+	// neverAllowed are schemes that run code or read local files inside
+	// or outside the webview and can't be added to a policy.
+	neverAllowed = map[string]bool{
+		"javascript": true, "vbscript": true, "data": true,
+		"file": true, "blob": true, "about": true,
+	}
+)
+
+// NewPolicy returns the built-in policy extended with extra schemes. When
+// allowHosts is non-empty, web links must match one of its patterns;
+// links matching denyHosts are always blocked. Patterns are host names
+// with path.Match wildcards, e.g. "*.example.com".
+func NewPolicy(extra []Scheme, allowHosts, denyHosts []string) (*Policy, error) {
+	p := &Policy{schemes: append([]Scheme(nil), registry...)}
+	for _, s := range extra {
+		name := strings.ToLower(strings.TrimSuffix(s.Prefix, ":"))
+		switch {
+		case !schemeNameRe.MatchString(name):
+			return nil, fmt.Errorf("invalid scheme %q", s.Prefix)
+		case neverAllowed[name]:
+			return nil, fmt.Errorf("scheme %q can't be allowed", name)
+		case p.find(name+":") != nil:
+			return nil, fmt.Errorf("scheme %q is already allowed", name)
+		case s.Kind == KindUnknown:
+			return nil, fmt.Errorf("scheme %q needs a kind", name)
 		}
-		if s.Platform != "" && s.Platform != goos {
-			return nil
+		s.Prefix = name + ":"
+		p.schemes = append(p.schemes, s)
+	}
+	var err error
+	if p.allowHosts, err = hostPatterns(allowHosts); err != nil {
+		return nil, err
+	}
+	if p.denyHosts, err = hostPatterns(denyHosts); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func hostPatterns(patterns []string) ([]string, error) {
+	out := make([]string, 0, len(patterns))
+	for _, pat := range patterns {
+		pat = strings.ToLower(strings.TrimSpace(pat))
+		if _, err := path.Match(pat, ""); err != nil || pat == "" || strings.Contains(pat, "/") {
+			return nil, fmt.Errorf("invalid host pattern %q", pat)
 		}
-		return s
+		out = append(out, pat)
 	}
-	return nil
+	return out, nil
 }
 
-// HasControlChars reports whether s contains ASCII control characters.
-func HasControlChars(s string) bool {
-	for i := 0; i < len(s); i++ {
-		if s[i] <= 0x1f || s[i] == 0x7f {
+func matchHost(patterns []string, host string) bool {
+	for _, pat := range patterns {
+		if ok, _ := path.Match(pat, host); ok {
 			return true
 		}
 	}
 	return false
 }
 
-// Allowed reports whether rawURL is permitted on the current OS.
-func Allowed(rawURL string) bool {
-	return AllowedOn(rawURL, runtime.GOOS)
+// find returns the scheme whose prefix matches rawURL on any platform.
+func (p *Policy) find(rawURL string) *Scheme {
+	lower := strings.ToLower(rawURL)
+	for i := range p.schemes {
+		if strings.HasPrefix(lower, p.schemes[i].Prefix) {
+			return &p.schemes[i]
+		}
+	}
+	return nil
 }
 
-// AllowedOn reports whether rawURL is permitted on the given OS.
-// Exported for testing without build tags.
-func AllowedOn(rawURL, goos string) bool {
+// findScheme returns the scheme matching rawURL on goos, or nil.
+func (p *Policy) findScheme(rawURL, goos string) *Scheme {
+	s := p.find(rawURL)
+	if s == nil || !platform.Matches(s.Platform, goos) {
+		return nil
+	}
+	return s
+}
+
+// Check returns nil if rawURL may be opened on goos, or an error saying
+// why it is blocked.
+func (p *Policy) Check(rawURL, goos string) error {
 	if HasControlChars(rawURL) {
-		return false
+		return errors.New("contains control characters")
 	}
-	s := findScheme(rawURL, goos)
+	s := p.find(rawURL)
 	if s == nil {
-		return false
+		return errors.New("scheme is not allowed")
+	}
+	if !platform.Matches(s.Platform, goos) {
+		return fmt.Errorf("scheme %s is only allowed on %s", strings.TrimSuffix(s.Prefix, ":"), s.Platform)
 	}
 	u, err := url.Parse(rawURL)
 	if err != nil {
-		return false
+		return errors.New("not a valid URL")
 	}
-	if s.Kind == KindWeb && u.Host == "" {
-		return false
+	switch s.Prefix {
+	case LinuxSettingsPrefix:
+		_, err := linuxPanel(rawURL)
+		return err
+	case InternalPrefix:
+		_, err := ParseInternal(rawURL)
+		return err
 	}
-	return true
+	if s.Kind != KindWeb {
+		return nil
+	}
+	host := strings.ToLower(u.Hostname())
+	switch {
+	case host == "":
+		return errors.New("web link has no host")
+	case matchHost(p.denyHosts, host):
+		return fmt.Errorf("host %s is denied", host)
+	case len(p.allowHosts) > 0 && !matchHost(p.allowHosts, host):
+		return fmt.Errorf("host %s is not in the allowed hosts", host)
+	}
+	return nil
 }
 
-// Classify returns the Kind of a URI on the current OS, or KindUnknown.
-func Classify(rawURL string) Kind {
-	return ClassifyOn(rawURL, runtime.GOOS)
+// Allowed reports whether rawURL is permitted on the current platform.
+func (p *Policy) Allowed(rawURL string) bool {
+	return p.AllowedOn(rawURL, platform.Current())
 }
 
-// ClassifyOn returns the Kind of a URI on the given OS.
-func ClassifyOn(rawURL, goos string) Kind {
-	if s := findScheme(rawURL, goos); s != nil {
+// AllowedOn reports whether rawURL is permitted on the given OS.
+func (p *Policy) AllowedOn(rawURL, goos string) bool {
+	return p.Check(rawURL, goos) == nil
+}
+
+// ClassifyOn returns the Kind of a URI on the given OS, or KindUnknown.
+// Host rules don't affect the kind.
+func (p *Policy) ClassifyOn(rawURL, goos string) Kind {
+	if s := p.findScheme(rawURL, goos); s != nil {
 		return s.Kind
 	}
 	return KindUnknown
 }
 
-// schemesForPlatform returns all registry entries available on goos.
-func schemesForPlatform(goos string) []Scheme {
+// AllSchemes returns every scheme of the policy available on goos.
+func (p *Policy) AllSchemes(goos string) []Scheme {
 	var out []Scheme
-	for _, s := range registry {
-		if s.Platform == "" || s.Platform == goos {
+	for _, s := range p.schemes {
+		if platform.Matches(s.Platform, goos) {
 			out = append(out, s)
 		}
 	}
 	return out
 }
 
-// SettingsSchemes returns the settings URI schemes available on the given OS.
-func SettingsSchemes(goos string) []Scheme {
+// HasControlChars reports whether s contains ASCII control characters.
+func HasControlChars(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] <= 0x1f || s[i] == 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// Allowed reports whether rawURL is permitted on the current platform.
+func Allowed(rawURL string) bool {
+	return defaultPolicy.Allowed(rawURL)
+}
+
+// AllowedOn reports whether rawURL is permitted on the given OS.
+// Exported for testing without build tags.
+func AllowedOn(rawURL, goos string) bool {
+	return defaultPolicy.AllowedOn(rawURL, goos)
+}
+
+// Classify returns the Kind of a URI on the current platform, or KindUnknown.
+func Classify(rawURL string) Kind {
+	return ClassifyOn(rawURL, platform.Current())
+}
+
+// ClassifyOn returns the Kind of a URI on the given OS.
+func ClassifyOn(rawURL, goos string) Kind {
+	return defaultPolicy.ClassifyOn(rawURL, goos)
+}
+
+// SettingsSchemes returns the settings URI schemes of the policy available
+// on goos.
+func (p *Policy) SettingsSchemes(goos string) []Scheme {
 	var out []Scheme
-	for _, s := range schemesForPlatform(goos) {
+	for _, s := range p.AllSchemes(goos) {
 		if s.Kind == KindSettings {
 			out = append(out, s)
 		}
@@ -121,7 +280,12 @@ func SettingsSchemes(goos string) []Scheme {
 	return out
 }
 
+// SettingsSchemes returns the settings URI schemes available on the given OS.
+func SettingsSchemes(goos string) []Scheme {
+	return defaultPolicy.SettingsSchemes(goos)
+}
+
 // AllSchemes returns every registered scheme available on the given OS.
 func AllSchemes(goos string) []Scheme {
-	return schemesForPlatform(goos)
+	return defaultPolicy.AllSchemes(goos)
 }
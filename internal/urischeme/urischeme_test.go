@@ -1,6 +1,10 @@
 package urischeme
 
-import "testing"
+import (
+	"errors"
+	"strings"
+	"testing"
+)
 
 func TestAllowedOn(t *testing.T) {
 	t.Parallel()
@@ -21,6 +25,17 @@ func TestAllowedOn(t *testing.T) {
 		{"apple prefs on mac", "x-apple.systempreferences:com.apple.preference.security", "darwin", true},
 		{"apple prefs on windows blocked", "x-apple.systempreferences:com.apple.preference.security", "windows", false},
 		{"apple prefs on linux blocked", "x-apple.systempreferences:com.apple.preference.security", "linux", false},
+		{"ms-settings on wsl", "ms-settings:windowsupdate", "wsl", true},
+		{"apple prefs on wsl blocked", "x-apple.systempreferences:com.apple.preference.security", "wsl", false},
+
+		{"linux settings on linux", "x-linux-settings:network", "linux", true},
+		{"linux settings main window", "x-linux-settings:", "linux", true},
+		{"linux settings on windows blocked", "x-linux-settings:network", "windows", false},
+		{"linux settings unknown panel", "x-linux-settings:nope", "linux", false},
+		{"linux settings on wsl", "x-linux-settings:display", "wsl", true},
+
+		{"day1 page link", "day1:page/security", "darwin", true},
+		{"day1 unknown op", "day1:launch/x", "linux", false},
 
 		{"apple prefs with anchor", "x-apple.systempreferences:com.apple.preference.security?Privacy_AllFiles", "darwin", true},
 		{"ms-settings with path", "ms-settings:windowsupdate-action", "windows", true},
@@ -64,7 +79,9 @@ func TestClassifyOn(t *testing.T) {
 		{"http is web", "http://example.com", "windows", KindWeb},
 		{"ms-settings is settings", "ms-settings:windowsupdate", "windows", KindSettings},
 		{"apple prefs is settings", "x-apple.systempreferences:com.apple.preference.security", "darwin", KindSettings},
+		{"linux settings is settings", "x-linux-settings:network", "linux", KindSettings},
 		{"ms-settings wrong platform", "ms-settings:windowsupdate", "darwin", KindUnknown},
+		{"day1 is internal", "day1:final", "windows", KindInternal},
 		{"unknown scheme", "ftp://x.com", "linux", KindUnknown},
 	}
 
@@ -88,7 +105,7 @@ func TestSettingsSchemes(t *testing.T) {
 	}{
 		{"windows", 1, "ms-settings:"},
 		{"darwin", 1, "x-apple.systempreferences:"},
-		{"linux", 0, ""},
+		{"linux", 1, "x-linux-settings:"},
 	}
 
 	for _, tt := range tests {
@@ -112,13 +129,208 @@ func TestAllSchemes(t *testing.T) {
 	mac := AllSchemes("darwin")
 	lin := AllSchemes("linux")
 
-	if len(win) != 3 {
-		t.Errorf("windows schemes = %d, want 3 (https, http, ms-settings)", len(win))
+	if len(win) != 4 {
+		t.Errorf("windows schemes = %d, want 4 (https, http, ms-settings, day1)", len(win))
+	}
+	if len(mac) != 4 {
+		t.Errorf("darwin schemes = %d, want 4 (https, http, x-apple.systempreferences, day1)", len(mac))
+	}
+	if len(lin) != 4 {
+		t.Errorf("linux schemes = %d, want 4 (https, http, x-linux-settings, day1)", len(lin))
+	}
+	if wsl := AllSchemes("wsl"); len(wsl) != 5 {
+		t.Errorf("wsl schemes = %d, want 5 (https, http, ms-settings, x-linux-settings, day1)", len(wsl))
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+	p, err := NewPolicy(
+		[]Scheme{
+			{Prefix: "slack", Kind: KindApp},
+			{Prefix: "ZoomMtg:", Kind: KindApp, Platform: "darwin"},
+		},
+		[]string{"*.corp.example", "corp.example"},
+		[]string{"secret.corp.example"},
+	)
+	if err != nil {
+		t.Fatalf("NewPolicy() = %v", err)
 	}
-	if len(mac) != 3 {
-		t.Errorf("darwin schemes = %d, want 3 (https, http, x-apple.systempreferences)", len(mac))
+
+	tests := []struct {
+		name string
+		url  string
+		goos string
+		want bool
+	}{
+		{"extra scheme", "slack://channel?id=1", "linux", true},
+		{"extra scheme case insensitive", "SLACK://channel", "windows", true},
+		{"platform scheme", "zoommtg://zoom.us/join", "darwin", true},
+		{"platform scheme elsewhere", "zoommtg://zoom.us/join", "windows", false},
+		{"built-ins kept", "ms-settings:windowsupdate", "windows", true},
+		{"allowed host", "https://wiki.corp.example/kb", "linux", true},
+		{"allowed apex", "https://corp.example", "linux", true},
+		{"allowed host with port", "https://wiki.corp.example:8443/kb", "linux", true},
+		{"host not allowed", "https://example.com", "linux", false},
+		{"suffix trick", "https://corp.example.evil.com", "linux", false},
+		{"denied host", "https://secret.corp.example", "linux", false},
+		{"denied host upper case", "https://SECRET.corp.example", "linux", false},
+		{"unknown scheme", "ftp://corp.example", "linux", false},
 	}
-	if len(lin) != 2 {
-		t.Errorf("linux schemes = %d, want 2 (https, http)", len(lin))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if got := p.AllowedOn(tt.url, tt.goos); got != tt.want {
+				t.Errorf("AllowedOn(%q, %q) = %v, want %v (%v)", tt.url, tt.goos, got, tt.want, p.Check(tt.url, tt.goos))
+			}
+		})
+	}
+
+	if got := p.ClassifyOn("slack://x", "linux"); got != KindApp {
+		t.Errorf("ClassifyOn(slack) = %v, want app", got)
+	}
+	if got := len(p.AllSchemes("darwin")); got != 6 {
+		t.Errorf("darwin schemes = %d, want 6", got)
+	}
+	if len(AllSchemes("darwin")) != 4 {
+		t.Error("NewPolicy changed the default policy")
+	}
+}
+
+func TestNewPolicyErrors(t *testing.T) {
+	t.Parallel()
+	tests := []struct {
+		name  string
+		extra []Scheme
+		allow []string
+	}{
+		{"javascript", []Scheme{{Prefix: "javascript:", Kind: KindApp}}, nil},
+		{"file", []Scheme{{Prefix: "file", Kind: KindApp}}, nil},
+		{"invalid name", []Scheme{{Prefix: "1x", Kind: KindApp}}, nil},
+		{"built-in", []Scheme{{Prefix: "https", Kind: KindWeb}}, nil},
+		{"duplicate", []Scheme{{Prefix: "slack", Kind: KindApp}, {Prefix: "slack", Kind: KindApp}}, nil},
+		{"no kind", []Scheme{{Prefix: "slack"}}, nil},
+		{"bad pattern", nil, []string{"[corp"}},
+		{"url as pattern", nil, []string{"https://corp.example/"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			if _, err := NewPolicy(tt.extra, tt.allow, nil); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestParseKind(t *testing.T) {
+	t.Parallel()
+	for _, k := range []Kind{KindWeb, KindSettings, KindApp} {
+		if got, err := ParseKind(k.String()); err != nil || got != k {
+			t.Errorf("ParseKind(%q) = %v, %v", k.String(), got, err)
+		}
+	}
+	if _, err := ParseKind("program"); err == nil {
+		t.Error("ParseKind(program): expected error")
+	}
+}
+
+func TestDesktop(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		env  string
+		want string
+	}{
+		{"GNOME", DesktopGNOME},
+		{"ubuntu:GNOME", DesktopGNOME},
+		{"Unity", DesktopGNOME},
+		{"KDE", DesktopKDE},
+		{"XFCE", DesktopXFCE},
+		{"X-Cinnamon", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Desktop(tt.env); got != tt.want {
+			t.Errorf("Desktop(%q) = %q, want %q", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestLinuxSettingsCommand(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		url     string
+		desktop string
+		want    []string
+		wantErr bool
+	}{
+		{"gnome network", "x-linux-settings:network", "ubuntu:GNOME", []string{"gnome-control-center", "network"}, false},
+		{"kde display", "x-linux-settings:display", "KDE", []string{"systemsettings", "kcm_kscreen"}, false},
+		{"xfce display", "x-linux-settings:display", "XFCE", []string{"xfce4-settings-manager", "--dialog=xfce-display-settings"}, false},
+		{"xfce panel without dialog", "x-linux-settings:privacy", "XFCE", []string{"xfce4-settings-manager"}, false},
+		{"main window", "x-linux-settings:", "GNOME", []string{"gnome-control-center"}, false},
+		{"case and slashes", "X-Linux-Settings://Sound", "GNOME", []string{"gnome-control-center", "sound"}, false},
+		{"unknown panel", "x-linux-settings:network;rm -rf /", "GNOME", nil, true},
+		{"other scheme", "https://example.com", "GNOME", nil, true},
+		{"unknown desktop", "x-linux-settings:network", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := LinuxSettingsCommand(tt.url, tt.desktop)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+				t.Errorf("argv = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	_, err := LinuxSettingsCommand("x-linux-settings:wifi", "X-Cinnamon")
+	var unknown *UnknownDesktopError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("err = %v, want *UnknownDesktopError", err)
+	}
+	if want := "Open your system settings and go to Wi-Fi."; err.Error() != want {
+		t.Errorf("message = %q, want %q", err.Error(), want)
+	}
+}
+
+func TestParseInternal(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		url     string
+		want    Internal
+		wantErr bool
+	}{
+		{"day1:page/security", Internal{Op: OpPage, Page: "security"}, false},
+		{"day1:page/guides/vpn#setup", Internal{Op: OpPage, Page: "guides/vpn"}, false},
+		{"DAY1:page/a%20b", Internal{Op: OpPage, Page: "a b"}, false},
+		{"day1:final", Internal{Op: OpFinal}, false},
+		{"day1:check/all", Internal{Op: OpCheck, Item: CheckAll}, false},
+		{"day1:check/mfa", Internal{Op: OpCheck, Item: "mfa"}, false},
+		{"day1:check/guides/vpn/install", Internal{Op: OpCheck, Page: "guides/vpn", Item: "install"}, false},
+		{"day1:action/copy-wifi", Internal{Op: OpAction, Name: "copy-wifi"}, false},
+		{"day1:page/", Internal{}, true},
+		{"day1:final/x", Internal{}, true},
+		{"day1:check/", Internal{}, true},
+		{"day1:action/a b", Internal{}, true},
+		{"day1:run/x", Internal{}, true},
+		{"https://example.com", Internal{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseInternal(tt.url)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseInternal(%q) err = %v, wantErr %v", tt.url, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseInternal(%q) = %+v, want %+v", tt.url, got, tt.want)
+		}
 	}
 }
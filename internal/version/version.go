@@ -4,4 +4,7 @@ var (
 	Version = "dev"
 	Commit  = "none"
 	Date    = "unknown"
+	// TrustedKey is an optional base64 ed25519 public key. When set, day1
+	// only shows content signed with the matching private key.
+	TrustedKey = ""
 )